@@ -0,0 +1,246 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"os"
+	"time"
+)
+
+// backupRecord - строка файла резервной копии (JSON lines): первой идет конфигурация стрима, затем сообщения
+type backupRecord struct {
+	Type    string                  `json:"type"`
+	Config  *jetstream.StreamConfig `json:"config,omitempty"`
+	Seq     uint64                  `json:"seq,omitempty"`
+	Subject string                  `json:"subject,omitempty"`
+	Time    time.Time               `json:"time,omitempty"`
+	Headers nats.Header             `json:"headers,omitempty"`
+	Data    []byte                  `json:"data,omitempty"`
+}
+
+const (
+	recordStream  = "stream"
+	recordMessage = "msg"
+)
+
+func runBackup(args []string) int {
+	fs := newFlagSet("backup", "backup --stream <name> --file <path> [flags]")
+	cl := addClientFlags(fs)
+	streamName := fs.String("stream", "EVENTS", "stream to back up")
+	file := fs.String("file", "", "output file (JSON lines)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	count, err := backupStream(js, *streamName, *file, cl.timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup failed: %v\n", err)
+		return exitFailure
+	}
+
+	fmt.Printf("stream %s: %d messages written to %s\n", *streamName, count, *file)
+	return exitOK
+}
+
+func backupStream(js jetstream.JetStream, name, path string, timeout time.Duration) (count int, err error) {
+	traceCtx, span := tracing.Tracer().Start(context.Background(), "backup "+name,
+		trace.WithAttributes(attribute.String("stream", name), attribute.String("file", path)))
+	defer func() {
+		span.SetAttributes(attribute.Int("messages", count))
+		tracing.End(span, err)
+	}()
+
+	ctx, cancel := context.WithTimeout(traceCtx, timeout)
+	stream, err := js.Stream(ctx, name)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream %s: %w", name, err)
+	}
+	info := stream.CachedInfo()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(backupRecord{Type: recordStream, Config: &info.Config}); err != nil {
+		return 0, err
+	}
+
+	if info.State.Msgs > 0 {
+		consumer, err := stream.OrderedConsumer(traceCtx, jetstream.OrderedConsumerConfig{
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create ordered consumer: %w", err)
+		}
+
+		msgs, err := consumer.Messages()
+		if err != nil {
+			return 0, fmt.Errorf("failed to consume stream: %w", err)
+		}
+		defer msgs.Stop()
+
+		// читаем до последнего сообщения на момент начала резервного копирования
+		for {
+			msg, err := msgs.Next()
+			if err != nil {
+				return count, fmt.Errorf("failed to read message: %w", err)
+			}
+			meta, err := msg.Metadata()
+			if err != nil {
+				return count, fmt.Errorf("failed to read message metadata: %w", err)
+			}
+
+			record := backupRecord{
+				Type:    recordMessage,
+				Seq:     meta.Sequence.Stream,
+				Subject: msg.Subject(),
+				Time:    meta.Timestamp,
+				Headers: msg.Headers(),
+				Data:    msg.Data(),
+			}
+			if err := enc.Encode(record); err != nil {
+				return count, err
+			}
+			count++
+
+			if meta.Sequence.Stream >= info.State.LastSeq || meta.NumPending == 0 {
+				break
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return count, err
+	}
+	return count, out.Sync()
+}
+
+func runRestore(args []string) int {
+	fs := newFlagSet("restore", "restore --file <path> [--stream <name>] [flags]")
+	cl := addClientFlags(fs)
+	file := fs.String("file", "", "backup file created by the backup command")
+	streamName := fs.String("stream", "", "restore into a stream with a different name")
+	appendExisting := fs.Bool("append", false, "append messages to an existing stream")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	name, count, err := restoreStream(js, *file, *streamName, *appendExisting, cl.timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore failed: %v\n", err)
+		return exitFailure
+	}
+
+	fmt.Printf("stream %s: %d messages restored from %s\n", name, count, *file)
+	return exitOK
+}
+
+func restoreStream(js jetstream.JetStream, path, rename string, appendExisting bool, timeout time.Duration) (name string, count int, err error) {
+	traceCtx, span := tracing.Tracer().Start(context.Background(), "restore",
+		trace.WithAttributes(attribute.String("file", path)))
+	defer func() {
+		span.SetAttributes(attribute.String("stream", name), attribute.Int("messages", count))
+		tracing.End(span, err)
+	}()
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer in.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(in))
+
+	var header backupRecord
+	if err := dec.Decode(&header); err != nil || header.Type != recordStream || header.Config == nil {
+		return "", 0, fmt.Errorf("backup file does not start with stream configuration")
+	}
+
+	cfg := *header.Config
+	if rename != "" {
+		cfg.Name = rename
+	}
+
+	ctx, cancel := context.WithTimeout(traceCtx, timeout)
+	_, err = js.Stream(ctx, cfg.Name)
+	cancel()
+	switch {
+	case err == nil && !appendExisting:
+		return cfg.Name, 0, fmt.Errorf("stream %s already exists, use --append to add messages", cfg.Name)
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		ctx, cancel := context.WithTimeout(traceCtx, timeout)
+		_, err = js.CreateStream(ctx, cfg)
+		cancel()
+		if err != nil {
+			return cfg.Name, 0, fmt.Errorf("failed to create stream: %w", err)
+		}
+	case err != nil:
+		return cfg.Name, 0, fmt.Errorf("failed to get stream: %w", err)
+	}
+
+	for {
+		var record backupRecord
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return cfg.Name, count, fmt.Errorf("failed to read record %d: %w", count+1, err)
+		}
+		if record.Type != recordMessage {
+			continue
+		}
+
+		msg := &nats.Msg{Subject: record.Subject, Header: record.Headers, Data: record.Data}
+		if err := publishRestored(traceCtx, js, msg, cfg.Name, timeout); err != nil {
+			return cfg.Name, count, fmt.Errorf("failed to publish message seq %d: %w", record.Seq, err)
+		}
+		count++
+	}
+
+	return cfg.Name, count, nil
+}
+
+// publishRestored публикует сообщение из копии. Если оно несло traceparent, спан продолжает исходную трассу.
+func publishRestored(ctx context.Context, js jetstream.JetStream, msg *nats.Msg, stream string, timeout time.Duration) error {
+	ctx, span := tracing.StartPublish(ctx, msg)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := js.PublishMsg(ctx, msg, jetstream.WithExpectStream(stream))
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/bench"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go/jetstream"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runBench(args []string) int {
+	fs := newFlagSet("bench", "bench [flags]")
+	cl := addClientFlags(fs)
+	stream := fs.String("stream", "BENCH", "stream for benchmark subjects, created if missing")
+	prefix := fs.String("prefix", "bench", "subject prefix: <prefix>.bundle.match, <prefix>.match.monitoring, <prefix>.fork.found")
+	mix := fs.String("mix", "bundle:1,monitoring:8,fork:1", "event kinds and their weights")
+	rate := fs.Int("rate", 0, "target messages per second across all publishers, 0 for max throughput")
+	duration := fs.Duration("duration", 10*time.Second, "benchmark duration, 0 to stop after -msgs")
+	msgs := fs.Int("msgs", 0, "number of messages to publish, 0 to run for -duration")
+	pubs := fs.Int("pubs", 1, "concurrent publishers")
+	subs := fs.Int("subs", 0, "concurrent subscribers measuring end-to-end latency")
+	async := fs.Bool("async", false, "publish asynchronously instead of waiting for each ack")
+	window := fs.Int("window", 256, "max outstanding async publishes per publisher")
+	memory := fs.Bool("memory", false, "create the stream with memory storage")
+	keep := fs.Bool("keep", false, "keep the created stream after the benchmark")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	m, err := bench.ParseMix(*mix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -mix: %v\n", err)
+		return exitUsage
+	}
+	switch {
+	case *duration <= 0 && *msgs <= 0:
+		err = errors.New("either -duration or -msgs must be positive")
+	case *pubs < 1:
+		err = errors.New("-pubs must be at least 1")
+	case *subs < 0 || *rate < 0 || *window < 1:
+		err = errors.New("-subs, -rate and -window must not be negative")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	created, err := ensureBenchStream(js, *stream, *prefix, *memory, cl.timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	if created && !*keep {
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cl.timeout)
+			defer cancel()
+			if err := js.DeleteStream(ctx, *stream); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to delete stream %s: %v\n", *stream, err)
+			}
+		}()
+	}
+
+	// Ctrl+C останавливает публикацию, отчет печатается по уже отправленным сообщениям
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := bench.Run(ctx, cl.dial, bench.Options{
+		Prefix:      *prefix,
+		Mix:         m,
+		Rate:        *rate,
+		Duration:    *duration,
+		Messages:    *msgs,
+		Publishers:  *pubs,
+		Subscribers: *subs,
+		Async:       *async,
+		AsyncWindow: *window,
+		Source:      programName() + "-bench",
+		Timeout:     cl.timeout,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "benchmark failed: %v\n", err)
+		return exitFailure
+	}
+
+	if *asJSON {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+			return exitFailure
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if report.AckFailures > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// ensureBenchStream создает стрим под subject'ы бенчмарка, если его нет. Возвращает true, если стрим создан.
+func ensureBenchStream(js jetstream.JetStream, name, prefix string, memory bool, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err := js.Stream(ctx, name)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return false, fmt.Errorf("failed to get stream %s: %w", name, err)
+	}
+
+	storage := jetstream.FileStorage
+	if memory {
+		storage = jetstream.MemoryStorage
+	}
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{prefix + ".>"},
+		Storage:  storage,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create stream %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	// exitUsage - неверные аргументы или ошибка конфигурации
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands заполняется в init, чтобы help мог ссылаться на список команд
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "run the NATS server and HTTP health endpoints (default)", runServe},
+		{"config", "validate or print the effective configuration", runConfig},
+		{"version", "print version information", runVersion},
+		{"streams", "list streams or show stream details", runStreams},
+		{"consumers", "list, inspect or delete stream consumers", runConsumers},
+		{"backup", "export a stream configuration and its messages to a file", runBackup},
+		{"restore", "import a stream from a backup file", runRestore},
+		{"record", "record live event traffic to a file", runRecord},
+		{"replay", "replay a recorded traffic file with original or accelerated timing", runReplay},
+		{"publish", "publish events from fixture files", runPublish},
+		{"subscribe", "print events of all types as they arrive, for debugging", runSubscribe},
+		{"bench", "benchmark JetStream publishing with realistic event payloads", runBench},
+		{"help", "show help for a command", runHelp},
+	}
+}
+
+func dispatch(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runServe(args)
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage()
+	return exitUsage
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", programName())
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s help <command>' for command flags.\n", programName())
+}
+
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] && c.name != "help" {
+			return c.run([]string{"-h"})
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+	return exitUsage
+}
+
+func programName() string {
+	return "nats-service"
+}
+
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n\nFlags:\n", programName(), usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags возвращает код выхода, если команду выполнять не нужно (-h или ошибка разбора)
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// configFlags - общие флаги источников конфигурации и переопределения каждого параметра
+type configFlags struct {
+	envFile    string
+	configFile string
+	overrides  map[string]string
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	cf := &configFlags{overrides: make(map[string]string)}
+
+	fs.StringVar(&cf.envFile, "env-file", ".env", "path to .env file")
+	fs.StringVar(&cf.configFile, "config", "", "path to YAML configuration file (or CONFIG_FILE)")
+
+	for _, p := range config.Parameters() {
+		key, name := p.Key, flagName(p.Key)
+		usage := fmt.Sprintf("override %s (%s)", key, p.Path)
+		if p.Secret {
+			// секреты не передаются в аргументах, только путь к файлу
+			key, name = key+"_FILE", name+"-file"
+			usage = fmt.Sprintf("read %s from file", p.Key)
+		}
+
+		fs.Func(name, usage, func(value string) error {
+			cf.overrides[key] = value
+			return nil
+		})
+	}
+
+	return cf
+}
+
+func (cf *configFlags) load() (*config.Config, int, bool) {
+	cfg, err := config.Load(config.LoadOptions{
+		EnvFile:    cf.envFile,
+		ConfigFile: cf.configFile,
+		Overrides:  cf.overrides,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+		return nil, exitUsage, false
+	}
+	return cfg, exitOK, true
+}
+
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"os"
+	"strings"
+	"time"
+)
+
+// clientFlags - подключение к уже запущенному серверу для административных команд
+type clientFlags struct {
+	cfg          *configFlags
+	server       string
+	user         string
+	passwordFile string
+	password     string
+	timeout      time.Duration
+
+	config          *config.Config
+	shutdownTracing func(context.Context) error
+}
+
+func addClientFlags(fs *flag.FlagSet) *clientFlags {
+	cl := &clientFlags{cfg: addConfigFlags(fs)}
+	fs.StringVar(&cl.server, "server", "", "NATS server URL (default from NATS_PORT)")
+	fs.StringVar(&cl.user, "user", "", "NATS username (default NATS_USERNAME)")
+	// пароль только из файла, как и -nats-password-file: аргументы видны в ps и истории shell
+	fs.StringVar(&cl.passwordFile, "password-file", "", "read NATS password from file (default NATS_PASSWORD unless it is a bcrypt hash)")
+	fs.DurationVar(&cl.timeout, "timeout", 10*time.Second, "request timeout")
+	return cl
+}
+
+func (cl *clientFlags) connect() (*nats.Conn, jetstream.JetStream, int, bool) {
+	cfg, code, ok := cl.cfg.load()
+	if !ok {
+		return nil, nil, code, false
+	}
+	cl.config = cfg
+
+	if cl.passwordFile != "" {
+		data, err := os.ReadFile(cl.passwordFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read password file: %v\n", err)
+			return nil, nil, exitUsage, false
+		}
+		cl.password = strings.TrimRight(string(data), "\r\n")
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up tracing: %v\n", err)
+		return nil, nil, exitFailure, false
+	}
+	cl.shutdownTracing = shutdown
+
+	nc, err := nats.Connect(cl.url(cfg), cl.options(cfg)...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect to NATS: %v\n", err)
+		if cfg.NATSNoListen {
+			fmt.Fprintln(os.Stderr, "NATS_NO_LISTEN is set: the service accepts only in-process connections")
+		}
+		return nil, nil, exitFailure, false
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		cl.close(nc)
+		fmt.Fprintf(os.Stderr, "failed to create JetStream context: %v\n", err)
+		return nil, nil, exitFailure, false
+	}
+
+	return nc, js, exitOK, true
+}
+
+// dial открывает дополнительное подключение с теми же параметрами, что и connect
+func (cl *clientFlags) dial(name string) (*nats.Conn, error) {
+	opts := append(cl.options(cl.config), nats.Name(programName()+"-"+name))
+	nc, err := nats.Connect(cl.url(cl.config), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect %s: %w", name, err)
+	}
+	return nc, nil
+}
+
+// close закрывает подключение и сбрасывает накопленные спаны
+func (cl *clientFlags) close(nc *nats.Conn) {
+	nc.Close()
+	if cl.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cl.timeout)
+		defer cancel()
+		if err := cl.shutdownTracing(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to flush traces: %v\n", err)
+		}
+	}
+}
+
+func (cl *clientFlags) url(cfg *config.Config) string {
+	if cl.server != "" {
+		return cl.server
+	}
+	return cfg.GetNATSURL()
+}
+
+func (cl *clientFlags) options(cfg *config.Config) []nats.Option {
+	opts := []nats.Option{
+		nats.Name(programName() + "-cli"),
+		nats.Timeout(cl.timeout),
+	}
+
+	user, password := cl.user, cl.password
+	if user == "" {
+		user = cfg.NATSUsername
+	}
+	if password == "" && !cfg.NATSPassword.IsBcrypt() {
+		password = cfg.NATSPassword.Value()
+	}
+	if user != "" {
+		opts = append(opts, nats.UserInfo(user, password))
+	}
+
+	return opts
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintf(os.Stderr, "Usage: %s config <validate|print> [flags]\n", programName())
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	switch args[0] {
+	case "print":
+		return runConfigPrint(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown config command %q\n", args[0])
+	return exitUsage
+}
+
+func runConfigPrint(args []string) int {
+	fs := newFlagSet("config print", "config print [flags]")
+	cf := addConfigFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	cfg, code, ok := cf.load()
+	if !ok {
+		return code
+	}
+
+	if cfg.ConfigFile() != "" {
+		fmt.Printf("# config file: %s\n", cfg.ConfigFile())
+	}
+	fmt.Println("# precedence: default < file < .env < env < flag")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tENV\tVALUE\tSOURCE")
+	for _, e := range cfg.Effective() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Path, e.Key, e.Value, e.Source)
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print configuration: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// runConfigValidate предназначен для CI: печатает все проблемы конфигурации и завершается с кодом exitUsage
+func runConfigValidate(args []string) int {
+	fs := newFlagSet("config validate", "config validate [flags]")
+	cf := addConfigFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	_, err := config.Load(config.LoadOptions{
+		EnvFile:    cf.envFile,
+		ConfigFile: cf.configFile,
+		Overrides:  cf.overrides,
+	})
+	if err == nil {
+		fmt.Println("configuration is valid")
+		return exitOK
+	}
+
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		for _, problem := range verr.Problems {
+			fmt.Fprintf(os.Stderr, "error: %s\n", problem)
+		}
+		fmt.Fprintf(os.Stderr, "%d problems found\n", len(verr.Problems))
+	} else {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+	return exitUsage
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/fixtures"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runPublish(args []string) int {
+	fs := newFlagSet("publish", "publish [flags] <fixture.yaml|json|jsonl>...")
+	cl := addClientFlags(fs)
+	repeat := fs.Int("repeat", 1, "passes over the fixtures, each with new correlation IDs, 0 to loop until Ctrl+C")
+	start := fs.Int64("correlation-start", 1, "first correlation ID to assign")
+	interval := fs.Duration("interval", 0, "pause between events without their own delay")
+	seed := fs.Uint64("seed", 0, "seed for randomized template values, 0 for a random seed")
+	source := fs.String("source", programName()+"-fixtures", "event_header.source for events without their own source")
+	core := fs.Bool("core", false, "publish with core NATS instead of waiting for JetStream acks")
+	dryRun := fs.Bool("dry-run", false, "print the events instead of publishing them")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 || *repeat < 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var entries []fixtures.Entry
+	for _, path := range fs.Args() {
+		loaded, err := fixtures.Load(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		entries = append(entries, loaded...)
+	}
+
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+	}
+	builder := fixtures.NewBuilder(*source, *start, *seed)
+
+	// проверяем фикстуры до подключения, чтобы ошибка не оставила половину событий в стриме
+	first, err := builder.Build(entries, 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	var publish func(ctx context.Context, msg fixtures.Message, data []byte) error
+	if *dryRun {
+		publish = func(_ context.Context, msg fixtures.Message, data []byte) error {
+			fmt.Printf("%s %s\n", msg.Subject, data)
+			return nil
+		}
+	} else {
+		nc, js, code, ok := cl.connect()
+		if !ok {
+			return code
+		}
+		defer cl.close(nc)
+
+		publish = func(ctx context.Context, msg fixtures.Message, data []byte) (err error) {
+			out := &nats.Msg{Subject: msg.Subject, Data: data}
+			ctx, span := tracing.StartPublish(ctx, out)
+			defer func() { tracing.End(span, err) }()
+
+			if *core {
+				fmt.Printf("%-24s correlation_id=%d\n", msg.Subject, msg.CorrelationID)
+				return nc.PublishMsg(out)
+			}
+			pubCtx, cancel := context.WithTimeout(ctx, cl.timeout)
+			defer cancel()
+			ack, err := js.PublishMsg(pubCtx, out)
+			if err == nil {
+				fmt.Printf("%-24s correlation_id=%-6d stream=%s seq=%d\n", msg.Subject, msg.CorrelationID, ack.Stream, ack.Sequence)
+			}
+			return err
+		}
+		if *core {
+			defer nc.Flush()
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	published := 0
+	msgs := first
+	for pass := 0; *repeat == 0 || pass < *repeat; pass++ {
+		if pass > 0 {
+			if msgs, err = builder.Build(entries, pass); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitFailure
+			}
+		}
+
+		for _, msg := range msgs {
+			data, err := msg.Encode(time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to encode %s: %v\n", msg.Subject, err)
+				return exitFailure
+			}
+			if err := publish(ctx, msg, data); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to publish %s (correlation_id %d): %v\n", msg.Subject, msg.CorrelationID, err)
+				return exitFailure
+			}
+			published++
+
+			pause := msg.Delay
+			if pause == 0 {
+				pause = *interval
+			}
+			if *dryRun {
+				pause = 0
+			}
+			select {
+			case <-ctx.Done():
+				fmt.Fprintf(os.Stderr, "interrupted after %d events\n", published)
+				return exitOK
+			case <-time.After(pause):
+			}
+		}
+	}
+
+	if !*dryRun {
+		fmt.Printf("published %d events (seed %d)\n", published, *seed)
+	}
+	return exitOK
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/replay"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runRecord(args []string) int {
+	fs := newFlagSet("record", "record --file <path> [flags]")
+	cl := addClientFlags(fs)
+	file := fs.String("file", "", "output file (JSON lines, gzip-compressed if it ends with .gz)")
+	subject := fs.String("subject", "events.>", "subjects to record")
+	duration := fs.Duration("duration", 0, "stop after this duration, 0 to record until Ctrl+C")
+	msgs := fs.Int("msgs", 0, "stop after this many messages, 0 for no limit")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	nc, _, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	w, err := replay.Create(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	// Ctrl+C завершает запись, файл при этом остается целым
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "recording %s to %s, press Ctrl+C to stop\n", *subject, *file)
+	stats, err := replay.Capture(ctx, nc, w, replay.CaptureOptions{Filter: *subject, Duration: *duration, Messages: *msgs})
+	if closeErr := w.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write recording file: %w", closeErr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "record failed after %d messages: %v\n", stats.Messages, err)
+		return exitFailure
+	}
+
+	fmt.Printf("recorded %d messages (%d bytes) in %s to %s\n", stats.Messages, stats.Bytes, stats.Elapsed.Round(time.Millisecond), *file)
+	if stats.Dropped > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d messages were dropped by the client\n", stats.Dropped)
+	}
+	return exitOK
+}
+
+func runReplay(args []string) int {
+	fs := newFlagSet("replay", "replay --file <path> [flags]")
+	cl := addClientFlags(fs)
+	file := fs.String("file", "", "recording file created by the record command")
+	speed := fs.Float64("speed", 1, "replay speed factor: 1 keeps original timing, 10 is ten times faster")
+	asFast := fs.Bool("max", false, "replay as fast as possible, ignoring recorded timing")
+	rewriteIDs := fs.Bool("rewrite-ids", false, "generate new event_id (and Nats-Msg-Id) for every event")
+	rewriteTimes := fs.Bool("rewrite-time", false, "shift event timestamps to the replay time")
+	core := fs.Bool("core", false, "publish with core NATS instead of waiting for JetStream acks")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitUsage
+	}
+	if *speed <= 0 && !*asFast {
+		fmt.Fprintln(os.Stderr, "-speed must be positive, use -max to replay without delays")
+		return exitUsage
+	}
+
+	r, err := replay.Open(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	defer r.Close()
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	// записанный traceparent остается родителем, спан воспроизведения встает между ним и подписчиками
+	publish := func(ctx context.Context, msg *nats.Msg) (err error) {
+		ctx, span := tracing.StartPublish(ctx, msg)
+		defer func() { tracing.End(span, err) }()
+
+		if *core {
+			return nc.PublishMsg(msg)
+		}
+		pubCtx, cancel := context.WithTimeout(ctx, cl.timeout)
+		defer cancel()
+		_, err = js.PublishMsg(pubCtx, msg)
+		return err
+	}
+
+	opts := replay.ReplayOptions{Speed: *speed, RewriteIDs: *rewriteIDs, RewriteTimes: *rewriteTimes}
+	if *asFast {
+		opts.Speed = 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "replaying %s recorded at %s (%s)\n", *file, r.Header.Started.Format(time.RFC3339), r.Header.Filter)
+	stats, err := replay.Replay(ctx, r, publish, opts)
+	if *core {
+		if flushErr := nc.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "replay failed after %d messages: %v\n", stats.Messages, err)
+		return exitFailure
+	}
+
+	fmt.Printf("replayed %d messages in %s, %d failed, max lag %s\n",
+		stats.Messages, stats.Elapsed.Round(time.Millisecond), stats.Failed, stats.Lag.Round(time.Millisecond))
+	if stats.Failed > 0 {
+		fmt.Fprintf(os.Stderr, "last publish error: %v\n", stats.LastError)
+		return exitFailure
+	}
+	return exitOK
+}
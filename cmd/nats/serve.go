@@ -0,0 +1,239 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/alerts"
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/consumers"
+	"NATS_TIRE_SERVICE/internal/forks"
+	"NATS_TIRE_SERVICE/internal/journey"
+	"NATS_TIRE_SERVICE/internal/lifecycle"
+	"NATS_TIRE_SERVICE/internal/nats"
+	"NATS_TIRE_SERVICE/internal/normalize"
+	"NATS_TIRE_SERVICE/internal/oddsfilter"
+	"NATS_TIRE_SERVICE/internal/server"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runServe(args []string) int {
+	fs := newFlagSet("serve", "serve [flags]")
+	cf := addConfigFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	cfg, code, ok := cf.load()
+	if !ok {
+		return code
+	}
+
+	// логгер не компонент менеджера: менеджер сам пишет в него, а результат остановки
+	// логируется уже после Stop, поэтому логгер создается раньше и закрывается последним
+	logger, err := utils.NewLogger(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		return exitUsage
+	}
+	defer func() {
+		if err := logger.Close(); err != nil {
+			if !cfg.IsDevelopment() {
+				log.Printf("Failed to close logger: %s", err)
+			}
+		}
+	}()
+
+	logger.Info("Starting NATS Service",
+		zap.String("app", cfg.AppName),
+		zap.String("version", cfg.Version),
+		zap.String("env", cfg.Env),
+	)
+
+	natsServer, err := nats.NewServer(cfg, logger)
+	if err != nil {
+		logger.Error("failed to create NATS server", zap.Error(err))
+		return exitFailure
+	}
+
+	httpServer := server.NewHTTPServer(cfg.HTTPPort, logger, natsServer, cfg)
+
+	manager := lifecycle.NewManager(logger)
+	components := []lifecycle.Component{
+		{
+			Name:     "config",
+			Critical: true,
+			Start: func(context.Context) error {
+				return cfg.EnsureDirs()
+			},
+		},
+		tracingComponent(cfg),
+		{
+			Name:      "http",
+			DependsOn: []string{"config"},
+			Critical:  true,
+			Start: func(context.Context) error {
+				return httpServer.Start()
+			},
+			Stop: func(context.Context) error {
+				return httpServer.Stop()
+			},
+		},
+		{
+			Name:         "nats",
+			DependsOn:    []string{"config"},
+			Critical:     true,
+			StartTimeout: 40 * time.Second,
+			StopTimeout:  cfg.ShutdownDrainDelay + cfg.LameDuckDuration + 15*time.Second,
+			Start: func(context.Context) error {
+				return natsServer.Start()
+			},
+			Stop: func(context.Context) error {
+				return natsServer.Stop()
+			},
+		},
+		logSignals(logger),
+	}
+
+	// внутренние компоненты стартуют после стримов из файла конфигурации, если они заданы
+	natsDeps := []string{"nats"}
+	if len(cfg.Streams) > 0 {
+		components = append(components, lifecycle.Component{
+			Name:      "streams",
+			DependsOn: []string{"nats"},
+			Critical:  true,
+			Start:     natsServer.EnsureStreams,
+		})
+		natsDeps = []string{"streams"}
+	}
+
+	if cfg.JourneyEnabled {
+		journeys := journey.NewService(cfg, logger, natsServer.ClientConn)
+		httpServer.SetJourneyIndex(journeys.Index())
+		components = append(components, lifecycle.Component{
+			Name:      "journey",
+			DependsOn: natsDeps,
+			Start:     journeys.Start,
+			Stop:      journeys.Stop,
+		})
+	}
+
+	if cfg.LagMonitorEnabled || cfg.StaleConsumersEnabled {
+		monitor := consumers.NewMonitor(cfg, logger, natsServer.ClientConn, newAlertManager(cfg, logger))
+		httpServer.SetConsumerMonitor(monitor)
+		components = append(components, lifecycle.Component{
+			Name:      "consumer-monitor",
+			DependsOn: natsDeps,
+			Start:     monitor.Start,
+			Stop:      monitor.Stop,
+		})
+	}
+
+	if cfg.NormalizeEnabled {
+		normalizer := normalize.NewNormalizer(cfg, logger, natsServer.ClientConn)
+		httpServer.SetNormalizer(normalizer)
+		components = append(components, lifecycle.Component{
+			Name:      "normalizer",
+			DependsOn: natsDeps,
+			Start:     normalizer.Start,
+			Stop:      normalizer.Stop,
+		})
+	}
+
+	if cfg.OddsFilterEnabled {
+		stage := oddsfilter.NewStage(cfg, logger, natsServer.ClientConn)
+		httpServer.SetOddsFilter(stage)
+		components = append(components, lifecycle.Component{
+			Name:      "odds-filter",
+			DependsOn: natsDeps,
+			Start:     stage.Start,
+			Stop:      stage.Stop,
+		})
+	}
+
+	if cfg.ForkDetectorEnabled {
+		detector := forks.NewDetector(cfg, logger, natsServer.ClientConn)
+		httpServer.SetForkDetector(detector)
+		components = append(components, lifecycle.Component{
+			Name:      "fork-detector",
+			DependsOn: natsDeps,
+			Start:     detector.Start,
+			Stop:      detector.Stop,
+		})
+	}
+
+	for _, c := range components {
+		if err := manager.Register(c); err != nil {
+			logger.Error("failed to register component", zap.String("component", c.Name), zap.Error(err))
+			return exitFailure
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(),
+		syscall.SIGINT,  // Ctrl+C
+		syscall.SIGTERM, // Docker/Kubernetes stop
+		syscall.SIGQUIT, // Graceful shutdown
+	)
+	defer stop()
+
+	if err := manager.Start(ctx); err != nil {
+		logger.Error("Service failed to start", zap.Error(err))
+		return exitFailure
+	}
+
+	logger.Info("✅ Service started successfully!",
+		zap.String("nats_url", cfg.GetNATSURL()),
+		zap.String("monitoring_url", cfg.GetMonitoringURL()),
+		zap.String("health_url", cfg.GetHealthURL()),
+	)
+	logger.Info(" Press Ctrl+C to stop")
+
+	<-ctx.Done()
+	logger.Info("Shutdown initiated")
+
+	if err := manager.Stop(); err != nil {
+		logger.Error("Service stopped with errors", zap.Error(err))
+		return exitFailure
+	}
+
+	logger.Info("Service stopped gracefully")
+	return exitOK
+}
+
+// tracingComponent настраивает OpenTelemetry. Регистрируется до http и nats, чтобы при остановке
+// сбросить спаны последним. Ошибка экспортера не мешает работе сервиса.
+func tracingComponent(cfg *config.Config) lifecycle.Component {
+	var shutdown func(context.Context) error
+
+	return lifecycle.Component{
+		Name:      "tracing",
+		DependsOn: []string{"config"},
+		Start: func(ctx context.Context) error {
+			var err error
+			shutdown, err = tracing.Setup(ctx, cfg)
+			return err
+		},
+		Stop: func(ctx context.Context) error {
+			return shutdown(ctx)
+		},
+	}
+}
+
+// newAlertManager - без ALERT_WEBHOOK_URLS алерты только пишутся в лог
+func newAlertManager(cfg *config.Config, logger *utils.Logger) *alerts.Manager {
+	var notifier alerts.Notifier
+	if len(cfg.AlertWebhookURLs) > 0 {
+		notifier = alerts.NewWebhook(cfg.AlertWebhookURLs, cfg.AlertWebhookTimeout)
+	}
+	return alerts.NewManager(cfg.AppName, notifier, cfg.AlertRepeatInterval, logger.WithFields(zap.String("component", "alerts")))
+}
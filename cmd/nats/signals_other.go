@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/lifecycle"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+)
+
+// logSignals - на платформах без SIGUSR1/SIGUSR2/SIGHUP уровень меняется только через admin API
+func logSignals(*utils.Logger) lifecycle.Component {
+	return lifecycle.Component{
+		Name:  "log-signals",
+		Start: func(context.Context) error { return nil },
+	}
+}
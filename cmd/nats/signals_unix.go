@@ -0,0 +1,60 @@
+//go:build unix
+
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/lifecycle"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// logSignals управляет логированием по сигналам: SIGUSR1 - подробнее, SIGUSR2 - тише,
+// SIGHUP - заново открыть файл логов после внешнего logrotate
+func logSignals(logger *utils.Logger) lifecycle.Component {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	return lifecycle.Component{
+		Name: "log-signals",
+		Start: func(context.Context) error {
+			signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
+			go func() {
+				for {
+					select {
+					case sig := <-signals:
+						if sig == syscall.SIGHUP {
+							if err := logger.ReopenFile(); err != nil {
+								logger.Error("failed to reopen log file", zap.Error(err))
+								continue
+							}
+							logger.Info("log file reopened by signal", zap.String("signal", sig.String()))
+							continue
+						}
+
+						delta := 1
+						if sig == syscall.SIGUSR1 {
+							delta = -1
+						}
+						// Notice: сообщение видно и тогда, когда уровень стал error
+						logger.Notice("log level changed by signal",
+							zap.String("signal", sig.String()),
+							zap.String("level", logger.StepLevel(delta)),
+						)
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			signal.Stop(signals)
+			close(done)
+			return nil
+		},
+	}
+}
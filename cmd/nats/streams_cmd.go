@@ -0,0 +1,176 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go/jetstream"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func runStreams(args []string) int {
+	fs := newFlagSet("streams", "streams [list | info <stream>] [flags]")
+	cl := addClientFlags(fs)
+	asJSON := fs.Bool("json", false, "print raw JSON")
+
+	action, rest := splitAction(args, "list")
+	if code, ok := parseFlags(fs, rest); !ok {
+		return code
+	}
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cl.timeout)
+	defer cancel()
+
+	switch action {
+	case "list", "ls":
+		var infos []*jetstream.StreamInfo
+		lister := js.ListStreams(ctx)
+		for info := range lister.Info() {
+			infos = append(infos, info)
+		}
+		if err := lister.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list streams: %v\n", err)
+			return exitFailure
+		}
+		if *asJSON {
+			return printJSON(infos)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSUBJECTS\tMESSAGES\tSIZE\tCONSUMERS\tLAST")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\n",
+				info.Config.Name,
+				strings.Join(info.Config.Subjects, ","),
+				info.State.Msgs,
+				domain.ByteSize(info.State.Bytes),
+				info.State.Consumers,
+				info.State.LastTime.Format("2006-01-02 15:04:05"),
+			)
+		}
+		w.Flush()
+		return exitOK
+
+	case "info":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return exitUsage
+		}
+		stream, err := js.Stream(ctx, fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get stream %s: %v\n", fs.Arg(0), err)
+			return exitFailure
+		}
+		return printJSON(stream.CachedInfo())
+	}
+
+	fs.Usage()
+	return exitUsage
+}
+
+func runConsumers(args []string) int {
+	fs := newFlagSet("consumers", "consumers [list <stream> | info <stream> <consumer> | rm <stream> <consumer>] [flags]")
+	cl := addClientFlags(fs)
+	asJSON := fs.Bool("json", false, "print raw JSON")
+
+	action, rest := splitAction(args, "list")
+	if code, ok := parseFlags(fs, rest); !ok {
+		return code
+	}
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cl.timeout)
+	defer cancel()
+
+	switch {
+	case (action == "list" || action == "ls") && fs.NArg() == 1:
+		stream, err := js.Stream(ctx, fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get stream %s: %v\n", fs.Arg(0), err)
+			return exitFailure
+		}
+
+		var infos []*jetstream.ConsumerInfo
+		lister := stream.ListConsumers(ctx)
+		for info := range lister.Info() {
+			infos = append(infos, info)
+		}
+		if err := lister.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list consumers: %v\n", err)
+			return exitFailure
+		}
+		if *asJSON {
+			return printJSON(infos)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tFILTER\tPENDING\tACK PENDING\tREDELIVERED\tLAST DELIVERED")
+		for _, info := range infos {
+			last := "-"
+			if info.Delivered.Last != nil {
+				last = info.Delivered.Last.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n",
+				info.Name,
+				info.Config.FilterSubject,
+				info.NumPending,
+				info.NumAckPending,
+				info.NumRedelivered,
+				last,
+			)
+		}
+		w.Flush()
+		return exitOK
+
+	case action == "info" && fs.NArg() == 2:
+		consumer, err := js.Consumer(ctx, fs.Arg(0), fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get consumer %s: %v\n", fs.Arg(1), err)
+			return exitFailure
+		}
+		return printJSON(consumer.CachedInfo())
+
+	case (action == "rm" || action == "delete") && fs.NArg() == 2:
+		if err := js.DeleteConsumer(ctx, fs.Arg(0), fs.Arg(1)); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to delete consumer %s: %v\n", fs.Arg(1), err)
+			return exitFailure
+		}
+		fmt.Printf("consumer %s deleted from %s\n", fs.Arg(1), fs.Arg(0))
+		return exitOK
+	}
+
+	fs.Usage()
+	return exitUsage
+}
+
+// splitAction отделяет действие (list, info, ...) от флагов и аргументов
+func splitAction(args []string, def string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return def, args
+}
+
+func printJSON(v interface{}) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode JSON: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
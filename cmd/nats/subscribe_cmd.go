@@ -0,0 +1,195 @@
+package main
+
+import (
+	"NATS_TIRE_SERVICE/internal/watch"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runSubscribe(args []string) int {
+	fs := newFlagSet("subscribe", "subscribe [flags]")
+	cl := addClientFlags(fs)
+	subject := fs.String("subject", "events.>", "subject filter")
+	streamName := fs.String("stream", "", "stream to read from (default: the stream bound to -subject)")
+	durable := fs.String("durable", "", "use (or create) a named durable consumer and ack messages instead of an ephemeral one")
+	all := fs.Bool("all", false, "start from the beginning of the stream instead of new messages")
+	core := fs.Bool("core", false, "plain NATS subscription without JetStream")
+	asJSON := fs.Bool("json", false, "print JSON lines instead of the readable format")
+	count := fs.Int("count", 0, "exit after this many messages, 0 to run until Ctrl+C")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *core && (*durable != "" || *all || *streamName != "") {
+		fmt.Fprintln(os.Stderr, "-core can not be combined with -durable, -all or -stream")
+		return exitUsage
+	}
+
+	nc, js, code, ok := cl.connect()
+	if !ok {
+		return code
+	}
+	defer cl.close(nc)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	printer := watch.Pretty
+	if *asJSON {
+		printer = watch.JSONLine
+	}
+	started := time.Now()
+	stats := watch.NewStats(started)
+
+	// статистика идет в stderr, чтобы не мешать разбору JSON lines
+	defer func() { stats.Write(os.Stderr, time.Now()) }()
+
+	received := 0
+	handle := func(ev watch.Event) bool {
+		malformed, err := printer(os.Stdout, ev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write output: %v\n", err)
+			return false
+		}
+		stats.Add(ev, malformed)
+		received++
+		return *count == 0 || received < *count
+	}
+
+	var err error
+	if *core {
+		err = subscribeCore(ctx, nc, *subject, handle)
+	} else {
+		err = subscribeStream(ctx, js, streamOptions{
+			stream:  *streamName,
+			subject: *subject,
+			durable: *durable,
+			all:     *all,
+			timeout: cl.timeout,
+		}, handle)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "subscribe failed: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func subscribeCore(ctx context.Context, nc *nats.Conn, subject string, handle func(watch.Event) bool) error {
+	sub, err := nc.SubscribeSync(subject)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	defer sub.Unsubscribe()
+
+	fmt.Fprintf(os.Stderr, "subscribed to %s, press Ctrl+C to stop\n", subject)
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if !handle(watch.Event{Subject: msg.Subject, Received: time.Now(), Data: msg.Data}) {
+			return nil
+		}
+	}
+}
+
+type streamOptions struct {
+	stream  string
+	subject string
+	durable string
+	all     bool
+	timeout time.Duration
+}
+
+// subscribeStream читает стрим ordered consumer'ом или durable consumer'ом с подтверждением сообщений
+func subscribeStream(ctx context.Context, js jetstream.JetStream, opts streamOptions, handle func(watch.Event) bool) error {
+	reqCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	name := opts.stream
+	if name == "" {
+		var err error
+		if name, err = js.StreamNameBySubject(reqCtx, opts.subject); err != nil {
+			return fmt.Errorf("no stream for subject %s (use -core for a plain subscription): %w", opts.subject, err)
+		}
+	}
+	stream, err := js.Stream(reqCtx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get stream %s: %w", name, err)
+	}
+
+	deliver := jetstream.DeliverNewPolicy
+	if opts.all {
+		deliver = jetstream.DeliverAllPolicy
+	}
+
+	var consumer jetstream.Consumer
+	if opts.durable == "" {
+		consumer, err = stream.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{
+			FilterSubjects: []string{opts.subject},
+			DeliverPolicy:  deliver,
+		})
+	} else {
+		consumer, err = stream.Consumer(reqCtx, opts.durable)
+		if errors.Is(err, jetstream.ErrConsumerNotFound) {
+			consumer, err = stream.CreateConsumer(reqCtx, jetstream.ConsumerConfig{
+				Durable:       opts.durable,
+				FilterSubject: opts.subject,
+				DeliverPolicy: deliver,
+				AckPolicy:     jetstream.AckExplicitPolicy,
+			})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	msgs, err := consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to consume stream %s: %w", name, err)
+	}
+	defer msgs.Stop()
+	go func() {
+		<-ctx.Done()
+		msgs.Stop()
+	}()
+
+	info := consumer.CachedInfo()
+	fmt.Fprintf(os.Stderr, "reading %s from stream %s via consumer %s, press Ctrl+C to stop\n", opts.subject, name, info.Name)
+
+	for {
+		msg, err := msgs.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return nil
+			}
+			return err
+		}
+
+		ev := watch.Event{Subject: msg.Subject(), Received: time.Now(), Data: msg.Data()}
+		if meta, err := msg.Metadata(); err == nil {
+			ev.Seq = meta.Sequence.Stream
+		}
+		more := handle(ev)
+
+		// некорректные сообщения тоже подтверждаются: отладочный подписчик не должен застревать на них
+		if opts.durable != "" {
+			if err := msg.Ack(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to ack message %d: %v\n", ev.Seq, err)
+			}
+		}
+		if !more {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// version задается при сборке: go build -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
+func runVersion(args []string) int {
+	fs := newFlagSet("version", "version")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	fmt.Printf("%s %s\n", programName(), version)
+	fmt.Printf("  go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				fmt.Printf("  %-9s %s\n", setting.Key[len("vcs."):]+":", setting.Value)
+			}
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/nats-io/nats-server/v2" {
+				fmt.Printf("  server:   %s\n", dep.Version)
+			}
+		}
+	}
+
+	return exitOK
+}
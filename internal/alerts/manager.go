@@ -0,0 +1,150 @@
+package alerts
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+type activeAlert struct {
+	alert    domain.Alert
+	notified bool
+	lastSent time.Time
+}
+
+// Manager хранит активные алерты: новое нарушение отправляется один раз (и повторно раз в repeat),
+// пропавшее - закрывается уведомлением со статусом resolved
+type Manager struct {
+	service  string
+	notifier Notifier
+	repeat   time.Duration
+	logger   *utils.Logger
+
+	mu     sync.Mutex
+	active map[string]*activeAlert
+}
+
+// NewManager - notifier может быть nil, тогда алерты только пишутся в лог
+func NewManager(service string, notifier Notifier, repeat time.Duration, logger *utils.Logger) *Manager {
+	return &Manager{
+		service:  service,
+		notifier: notifier,
+		repeat:   repeat,
+		logger:   logger,
+		active:   make(map[string]*activeAlert),
+	}
+}
+
+// outgoing - уведомление, собранное под блокировкой и отправляемое после нее.
+// Для firing active указывает на алерт, которому после доставки отмечается отправка.
+type outgoing struct {
+	alert  domain.Alert
+	active *activeAlert
+}
+
+// Evaluate сверяет текущие нарушения источника с активными алертами. current - полный список
+// нарушений source на момент now: все, чего в нем нет, считается исправленным.
+// Вебхуки отправляются без блокировки, поэтому медленный получатель не задерживает Active
+// и проверки других источников. Для одного источника Evaluate вызывается последовательно.
+func (m *Manager) Evaluate(ctx context.Context, source string, current []domain.Alert, now time.Time) {
+	pending := m.apply(source, current, now)
+
+	delivered := make([]bool, len(pending))
+	for i, n := range pending {
+		delivered[i] = m.notify(ctx, n.alert)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, n := range pending {
+		// не доставленный алерт повторяется на следующей проверке
+		if n.active != nil && delivered[i] {
+			n.active.notified, n.active.lastSent = true, now
+		}
+	}
+}
+
+// apply обновляет активные алерты и возвращает уведомления, которые нужно отправить
+func (m *Manager) apply(source string, current []domain.Alert, now time.Time) []outgoing {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []outgoing
+	seen := make(map[string]bool, len(current))
+	for _, alert := range current {
+		alert.Source, alert.Service, alert.Status = source, m.service, domain.AlertFiring
+		seen[alert.Fingerprint] = true
+
+		a, ok := m.active[alert.Fingerprint]
+		if !ok {
+			alert.StartsAt = now
+			a = &activeAlert{alert: alert}
+			m.active[alert.Fingerprint] = a
+			m.logger.Warn("alert firing",
+				zap.String("fingerprint", alert.Fingerprint),
+				zap.String("summary", alert.Summary),
+			)
+		} else {
+			alert.StartsAt = a.alert.StartsAt
+			a.alert = alert
+		}
+
+		if !a.notified || (m.repeat > 0 && now.Sub(a.lastSent) >= m.repeat) {
+			pending = append(pending, outgoing{alert: a.alert, active: a})
+		}
+	}
+
+	for fingerprint, a := range m.active {
+		if a.alert.Source != source || seen[fingerprint] {
+			continue
+		}
+		delete(m.active, fingerprint)
+
+		resolved := a.alert
+		resolved.Status = domain.AlertResolved
+		resolved.EndsAt = &now
+		m.logger.Info("alert resolved",
+			zap.String("fingerprint", fingerprint),
+			zap.Duration("duration", now.Sub(resolved.StartsAt)),
+		)
+		// о нарушении, которое так и не дошло до получателя, не сообщаем и о закрытии
+		if a.notified {
+			pending = append(pending, outgoing{alert: resolved})
+		}
+	}
+	return pending
+}
+
+func (m *Manager) notify(ctx context.Context, alert domain.Alert) bool {
+	if m.notifier == nil {
+		return true
+	}
+	if err := m.notifier.Notify(ctx, alert); err != nil {
+		m.logger.Error("failed to deliver alert",
+			zap.String("fingerprint", alert.Fingerprint),
+			zap.String("status", alert.Status),
+			zap.Error(err),
+		)
+		return false
+	}
+	return true
+}
+
+// Active возвращает активные алерты, отсортированные по fingerprint
+func (m *Manager) Active() []domain.Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]domain.Alert, 0, len(m.active))
+	for _, a := range m.active {
+		result = append(result, a.alert)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Fingerprint < result[j].Fingerprint
+	})
+	return result
+}
@@ -0,0 +1,175 @@
+package alerts
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookSink - локальный получатель вебхуков вместо внешней системы алертов
+type webhookSink struct {
+	mu       sync.Mutex
+	received []domain.Alert
+	status   int
+}
+
+func (s *webhookSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var alert domain.Alert
+	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	s.received = append(s.received, alert)
+}
+
+func (s *webhookSink) statuses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []string
+	for _, a := range s.received {
+		result = append(result, a.Status)
+	}
+	return result
+}
+
+func newTestManager(t *testing.T, repeat time.Duration) (*Manager, *webhookSink) {
+	sink := &webhookSink{}
+	srv := httptest.NewServer(sink)
+	t.Cleanup(srv.Close)
+
+	logger := &utils.Logger{Logger: zap.NewNop()}
+	return NewManager("test", NewWebhook([]string{srv.URL}, time.Second), repeat, logger), sink
+}
+
+func lagAlert(value float64) domain.Alert {
+	return domain.Alert{Fingerprint: "EVENTS/monitoring/num_pending", Value: value, Threshold: 1000}
+}
+
+func TestManagerDeduplicatesAndResolves(t *testing.T) {
+	m, sink := newTestManager(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	m.Evaluate(ctx, domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(1500)}, now)
+	m.Evaluate(ctx, domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(2500)}, now.Add(time.Minute))
+
+	if got := sink.statuses(); len(got) != 1 || got[0] != domain.AlertFiring {
+		t.Fatalf("after repeated breach: %v, want one firing", got)
+	}
+	if active := m.Active(); len(active) != 1 || active[0].Value != 2500 || !active[0].StartsAt.Equal(now) {
+		t.Fatalf("active = %+v", active)
+	}
+
+	m.Evaluate(ctx, domain.AlertSourceConsumerLag, nil, now.Add(2*time.Minute))
+
+	got := sink.statuses()
+	if len(got) != 2 || got[1] != domain.AlertResolved {
+		t.Fatalf("after recovery: %v, want firing then resolved", got)
+	}
+	if resolved := sink.received[1]; resolved.EndsAt == nil || resolved.Source != domain.AlertSourceConsumerLag || resolved.Service != "test" {
+		t.Fatalf("resolved alert = %+v", resolved)
+	}
+	if len(m.Active()) != 0 {
+		t.Fatal("resolved alert is still active")
+	}
+}
+
+func TestManagerRepeatsFiringAlerts(t *testing.T) {
+	m, sink := newTestManager(t, time.Hour)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, offset := range []time.Duration{0, 30 * time.Minute, time.Hour, 90 * time.Minute} {
+		m.Evaluate(ctx, domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(1500)}, now.Add(offset))
+	}
+
+	if got := sink.statuses(); len(got) != 2 {
+		t.Fatalf("notifications = %v, want initial and one repeat", got)
+	}
+}
+
+func TestManagerRetriesUndelivered(t *testing.T) {
+	m, sink := newTestManager(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	sink.status = http.StatusInternalServerError
+	m.Evaluate(ctx, domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(1500)}, now)
+
+	sink.status = 0
+	m.Evaluate(ctx, domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(1500)}, now.Add(time.Minute))
+
+	if got := sink.statuses(); len(got) != 1 || got[0] != domain.AlertFiring {
+		t.Fatalf("notifications = %v, want firing delivered on retry", got)
+	}
+}
+
+func TestManagerKeepsOtherSources(t *testing.T) {
+	m, sink := newTestManager(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	m.Evaluate(ctx, domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(1500)}, now)
+	m.Evaluate(ctx, "other", nil, now)
+
+	if got := sink.statuses(); len(got) != 1 || len(m.Active()) != 1 {
+		t.Fatalf("alert of another source was resolved: %v", got)
+	}
+}
+
+// blockingNotifier держит отправку, пока тест не закроет release
+type blockingNotifier struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, alert domain.Alert) error {
+	n.started <- struct{}{}
+	<-n.release
+	return nil
+}
+
+func TestManagerNotifiesWithoutLock(t *testing.T) {
+	notifier := &blockingNotifier{started: make(chan struct{}, 1), release: make(chan struct{})}
+	m := NewManager("test", notifier, 0, &utils.Logger{Logger: zap.NewNop()})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Evaluate(context.Background(), domain.AlertSourceConsumerLag, []domain.Alert{lagAlert(1500)}, time.Now())
+	}()
+	<-notifier.started
+
+	// пока вебхук не ответил, состояние доступно
+	active := make(chan []domain.Alert)
+	go func() { active <- m.Active() }()
+	select {
+	case got := <-active:
+		if len(got) != 1 {
+			t.Errorf("active = %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Active is blocked by a pending notification")
+	}
+
+	close(notifier.release)
+	<-done
+	if a := m.active[lagAlert(0).Fingerprint]; !a.notified {
+		t.Error("delivered alert is not marked as notified")
+	}
+}
@@ -0,0 +1,71 @@
+package alerts
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Notifier доставляет алерт во внешнюю систему
+type Notifier interface {
+	Notify(ctx context.Context, alert domain.Alert) error
+}
+
+// Webhook отправляет алерт JSON POST запросом на каждый адрес
+type Webhook struct {
+	urls   []string
+	client *http.Client
+}
+
+func NewWebhook(urls []string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		urls:   urls,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify считает алерт доставленным, если его принял хотя бы один адрес,
+// иначе возвращает ошибки всех адресов
+func (w *Webhook) Notify(ctx context.Context, alert domain.Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	var errs []error
+	for _, url := range w.urls {
+		if err := w.post(ctx, url, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(w.urls) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+// Package bench - нагрузочный тест публикации событий в JetStream
+package bench
+
+import (
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	KindBundle     = "bundle"
+	KindMonitoring = "monitoring"
+	KindFork       = "fork"
+
+	// HeaderSent - время отправки в наносекундах, по нему подписчики считают задержку доставки
+	HeaderSent = "Bench-Sent"
+
+	// диапазон гистограмм: от 1µs до 1 минуты, 3 значащие цифры
+	histogramMax = int64(time.Minute / time.Microsecond)
+)
+
+// Mix - доли видов событий, например bundle:1,monitoring:8,fork:1
+type Mix []MixEntry
+
+type MixEntry struct {
+	Kind   string
+	Weight int
+}
+
+func ParseMix(s string) (Mix, error) {
+	var mix Mix
+	for _, part := range strings.Split(s, ",") {
+		kind, weight, found := strings.Cut(strings.TrimSpace(part), ":")
+		w := 1
+		if found {
+			var err error
+			if w, err = strconv.Atoi(weight); err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight %q for %s", weight, kind)
+			}
+		}
+		switch kind {
+		case KindBundle, KindMonitoring, KindFork:
+		default:
+			return nil, fmt.Errorf("unknown event kind %q, expected %s/%s/%s", kind, KindBundle, KindMonitoring, KindFork)
+		}
+		if w > 0 {
+			mix = append(mix, MixEntry{Kind: kind, Weight: w})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, errors.New("event mix is empty")
+	}
+	return mix, nil
+}
+
+// sequence раскладывает доли в цикл, который издатели проходят по кругу: точные пропорции без случайности
+func (m Mix) sequence() []string {
+	var seq []string
+	for _, e := range m {
+		for i := 0; i < e.Weight; i++ {
+			seq = append(seq, e.Kind)
+		}
+	}
+	return seq
+}
+
+type Options struct {
+	Prefix      string        // префикс subject: <prefix>.bundle.match, <prefix>.match.monitoring, <prefix>.fork.found
+	Mix         Mix           //
+	Rate        int           // сообщений в секунду на всех издателей, 0 - максимальная скорость
+	Duration    time.Duration // 0 - пока не отправлено Messages
+	Messages    int           // 0 - пока не истечет Duration
+	Publishers  int           //
+	Subscribers int           // подписчики на <prefix>.> для задержки доставки
+	Async       bool          // асинхронная публикация с окном AsyncWindow неподтвержденных сообщений
+	AsyncWindow int           //
+	Source      string        // event_header.source
+	Timeout     time.Duration // ожидание подтверждения
+}
+
+// Connect открывает подключение для издателя или подписчика
+type Connect func(name string) (*nats.Conn, error)
+
+// Run публикует события и собирает статистику. Каждый издатель и подписчик работает на своем подключении.
+func Run(ctx context.Context, connect Connect, opts Options) (*Report, error) {
+	if opts.Publishers < 1 {
+		return nil, errors.New("at least one publisher is required")
+	}
+
+	subs := make([]*subscriber, opts.Subscribers)
+	for i := range subs {
+		s, err := newSubscriber(connect, fmt.Sprintf("bench-sub-%d", i+1), opts.Prefix+".>")
+		if err != nil {
+			closeSubscribers(subs)
+			return nil, err
+		}
+		subs[i] = s
+	}
+	defer closeSubscribers(subs)
+
+	pubs := make([]*publisher, opts.Publishers)
+	for i := range pubs {
+		p, err := newPublisher(connect, i, opts)
+		if err != nil {
+			for _, p := range pubs[:i] {
+				p.nc.Close()
+			}
+			return nil, err
+		}
+		pubs[i] = p
+	}
+
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+	if opts.Duration > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, opts.Duration)
+	}
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, p := range pubs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.run(runCtx, start)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := newReport(opts, elapsed, pubs)
+	if len(subs) > 0 {
+		waitDelivered(ctx, subs, report.Acked)
+		report.addSubscribers(subs)
+	}
+	for _, p := range pubs {
+		p.nc.Close()
+	}
+	return report, nil
+}
+
+type publisher struct {
+	id      int
+	opts    Options
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	gen     *Generator
+	seq     []string
+	quota   int
+	latency *hdrhistogram.Histogram
+
+	published   uint64
+	bytes       uint64
+	acked       atomic.Uint64
+	failed      atomic.Uint64
+	byKind      map[string]uint64
+	mu          sync.Mutex
+	errorCounts map[string]uint64
+}
+
+func newPublisher(connect Connect, id int, opts Options) (*publisher, error) {
+	nc, err := connect(fmt.Sprintf("bench-pub-%d", id+1))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc, jetstream.WithPublishAsyncMaxPending(max(opts.AsyncWindow, 1)))
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	// сообщения делятся между издателями поровну, остаток достается первым
+	quota := 0
+	if opts.Messages > 0 {
+		quota = opts.Messages / opts.Publishers
+		if id < opts.Messages%opts.Publishers {
+			quota++
+		}
+	}
+
+	seq := opts.Mix.sequence()
+	// издатели начинают цикл с разных мест, чтобы виды событий не шли пачками одновременно
+	offset := id * len(seq) / opts.Publishers
+	seq = append(seq[offset:], seq[:offset]...)
+
+	return &publisher{
+		id:          id,
+		opts:        opts,
+		nc:          nc,
+		js:          js,
+		gen:         NewGenerator(uint64(id+1), opts.Source, int64(id+1), int64(opts.Publishers)),
+		seq:         seq,
+		quota:       quota,
+		latency:     hdrhistogram.New(1, histogramMax, 3),
+		byKind:      make(map[string]uint64),
+		errorCounts: make(map[string]uint64),
+	}, nil
+}
+
+type inflight struct {
+	future jetstream.PubAckFuture
+	sent   time.Time
+	span   trace.Span
+}
+
+func (p *publisher) run(ctx context.Context, start time.Time) {
+	// интервал между отправками одного издателя при заданной скорости
+	var interval time.Duration
+	if p.opts.Rate > 0 {
+		interval = time.Duration(int64(time.Second) * int64(p.opts.Publishers) / int64(p.opts.Rate))
+	}
+
+	var pending chan inflight
+	var collected sync.WaitGroup
+	if p.opts.Async {
+		pending = make(chan inflight, max(p.opts.AsyncWindow, 1))
+		collected.Add(1)
+		go func() {
+			defer collected.Done()
+			p.collect(pending, interval)
+		}()
+	}
+
+	for n := 0; p.quota == 0 || n < p.quota; n++ {
+		if interval > 0 {
+			if wait := time.Until(start.Add(time.Duration(n) * interval)); wait > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		kind := p.seq[n%len(p.seq)]
+		subject, data, err := p.gen.Next(kind)
+		if err != nil {
+			p.fail(err)
+			continue
+		}
+
+		sent := time.Now()
+		msg := &nats.Msg{
+			Subject: p.opts.Prefix + "." + subject,
+			Data:    data,
+			Header:  nats.Header{HeaderSent: []string{strconv.FormatInt(sent.UnixNano(), 10)}},
+		}
+		p.published++
+		p.bytes += uint64(len(data))
+		p.byKind[kind]++
+		spanCtx, span := tracing.StartPublish(context.Background(), msg)
+
+		if p.opts.Async {
+			future, err := p.js.PublishMsgAsync(msg)
+			if err != nil {
+				tracing.End(span, err)
+				p.fail(err)
+				continue
+			}
+			pending <- inflight{future: future, sent: sent, span: span}
+			continue
+		}
+
+		pubCtx, cancel := context.WithTimeout(spanCtx, p.opts.Timeout)
+		_, err = p.js.PublishMsg(pubCtx, msg)
+		cancel()
+		tracing.End(span, err)
+		if err != nil {
+			p.fail(err)
+			continue
+		}
+		p.acked.Add(1)
+		p.record(time.Since(sent), interval)
+	}
+
+	if p.opts.Async {
+		select {
+		case <-p.js.PublishAsyncComplete():
+		case <-time.After(p.opts.Timeout):
+		}
+		close(pending)
+		collected.Wait()
+	}
+}
+
+// collect ждет подтверждения асинхронных публикаций по порядку отправки
+func (p *publisher) collect(pending <-chan inflight, interval time.Duration) {
+	for f := range pending {
+		select {
+		case <-f.future.Ok():
+			tracing.End(f.span, nil)
+			p.acked.Add(1)
+			p.record(time.Since(f.sent), interval)
+		case err := <-f.future.Err():
+			tracing.End(f.span, err)
+			p.fail(err)
+		case <-time.After(p.opts.Timeout):
+			tracing.End(f.span, context.DeadlineExceeded)
+			p.fail(context.DeadlineExceeded)
+		}
+	}
+}
+
+// record учитывает задержку подтверждения. При заданной скорости добавляются значения,
+// которые не были измерены из-за задержки (coordinated omission)
+func (p *publisher) record(d, interval time.Duration) {
+	us := max(d.Microseconds(), 1)
+	if interval > 0 {
+		_ = p.latency.RecordCorrectedValue(us, max(interval.Microseconds(), 1))
+		return
+	}
+	_ = p.latency.RecordValue(us)
+}
+
+func (p *publisher) fail(err error) {
+	p.failed.Add(1)
+	p.mu.Lock()
+	p.errorCounts[err.Error()]++
+	p.mu.Unlock()
+}
+
+type subscriber struct {
+	nc       *nats.Conn
+	sub      *nats.Subscription
+	received atomic.Uint64
+	mu       sync.Mutex
+	latency  *hdrhistogram.Histogram
+}
+
+func newSubscriber(connect Connect, name, subject string) (*subscriber, error) {
+	nc, err := connect(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &subscriber{nc: nc, latency: hdrhistogram.New(1, histogramMax, 3)}
+	s.sub, err = nc.Subscribe(subject, s.handle)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	// подписчик не должен терять сообщения на пиковой скорости
+	if err := s.sub.SetPendingLimits(-1, -1); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to set pending limits: %w", err)
+	}
+	if err := nc.Flush(); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to flush subscription: %w", err)
+	}
+	return s, nil
+}
+
+func (s *subscriber) handle(msg *nats.Msg) {
+	s.received.Add(1)
+
+	sent, err := strconv.ParseInt(msg.Header.Get(HeaderSent), 10, 64)
+	if err != nil {
+		return
+	}
+	us := max(time.Since(time.Unix(0, sent)).Microseconds(), 1)
+
+	s.mu.Lock()
+	_ = s.latency.RecordValue(us)
+	s.mu.Unlock()
+}
+
+func closeSubscribers(subs []*subscriber) {
+	for _, s := range subs {
+		if s != nil {
+			s.nc.Close()
+		}
+	}
+}
+
+// waitDelivered ждет, пока каждый подписчик получит все подтвержденные сообщения,
+// или пока поток не остановится на секунду
+func waitDelivered(ctx context.Context, subs []*subscriber, expected uint64) {
+	var last uint64
+	idleSince := time.Now()
+
+	for ctx.Err() == nil {
+		var total uint64
+		done := true
+		for _, s := range subs {
+			n := s.received.Load()
+			total += n
+			if n < expected {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		if total != last {
+			last, idleSince = total, time.Now()
+		} else if time.Since(idleSince) > time.Second {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
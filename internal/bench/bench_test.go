@@ -0,0 +1,62 @@
+package bench_test
+
+import (
+	"NATS_TIRE_SERVICE/internal/bench"
+	"NATS_TIRE_SERVICE/internal/natstest"
+	"context"
+	"github.com/nats-io/nats.go/jetstream"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := bench.ParseMix("bundle:2, monitoring, fork:0")
+	if err != nil {
+		t.Fatalf("ParseMix: %v", err)
+	}
+	if len(mix) != 2 || mix[0] != (bench.MixEntry{Kind: bench.KindBundle, Weight: 2}) || mix[1].Weight != 1 {
+		t.Errorf("mix = %+v", mix)
+	}
+
+	for _, bad := range []string{"odds:1", "bundle:-1", "fork:0"} {
+		if _, err := bench.ParseMix(bad); err == nil {
+			t.Errorf("ParseMix(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	h := natstest.Start(t, natstest.WithStreams(jetstream.StreamConfig{
+		Name:     "BENCH",
+		Subjects: []string{"bench.>"},
+		Storage:  jetstream.MemoryStorage,
+	}))
+	mix, _ := bench.ParseMix("bundle:1,monitoring:2,fork:1")
+
+	for _, async := range []bool{false, true} {
+		report, err := bench.Run(context.Background(), h.Server.ClientConn, bench.Options{
+			Prefix:      "bench",
+			Mix:         mix,
+			Messages:    200,
+			Publishers:  2,
+			Subscribers: 2,
+			Async:       async,
+			AsyncWindow: 16,
+			Source:      "bench-test",
+			Timeout:     5 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Run(async=%v): %v", async, err)
+		}
+
+		if report.Published != 200 || report.Acked != 200 || report.AckFailures != 0 {
+			t.Errorf("async=%v: published %d, acked %d, failures %d", async, report.Published, report.Acked, report.AckFailures)
+		}
+		if report.ByKind[bench.KindMonitoring] != 100 || report.ByKind[bench.KindBundle] != 50 {
+			t.Errorf("async=%v: by kind = %v", async, report.ByKind)
+		}
+		if report.AckLatency.Count != 200 || report.E2ELatency == nil || report.Received != 400 {
+			t.Errorf("async=%v: ack samples %d, received %d", async, report.AckLatency.Count, report.Received)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	teams = [][]string{
+		{"NaVi", "G2"}, {"FaZe", "Vitality"}, {"Spirit", "MOUZ"}, {"Astralis", "Heroic"},
+		{"Liquid", "Complexity"}, {"Cloud9", "Virtus.pro"}, {"ENCE", "FURIA"}, {"BIG", "Eternal Fire"},
+	}
+	bookmakers = []string{"fonbet", "parivision", "winline", "betboom", "marathon", "ligastavok"}
+	markets    = []string{"match-winner", "map-1-winner", "total-maps", "handicap-maps"}
+)
+
+// Generator строит события, похожие на реальные: бандл матча, обновления коэффициентов
+// по нескольким букмекерам и найденные вилки. Не потокобезопасен - по одному на издателя.
+type Generator struct {
+	rnd    *rand.Rand
+	source string
+	nextID int64
+	step   int64
+}
+
+// NewGenerator - издатели получают непересекающиеся correlation id: first, first+step, ...
+func NewGenerator(seed uint64, source string, first, step int64) *Generator {
+	return &Generator{
+		rnd:    rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		source: source,
+		nextID: first,
+		step:   step,
+	}
+}
+
+// Next возвращает subject (без префикса) и тело события выбранного вида
+func (g *Generator) Next(kind string) (string, []byte, error) {
+	id := g.nextID
+	g.nextID += g.step
+
+	pair := teams[g.rnd.IntN(len(teams))]
+	bms := g.bookmakers()
+
+	var event domain.Event
+	var subject string
+	switch kind {
+	case KindBundle:
+		subject = "bundle.match"
+		event = domain.NewEvent(domain.EventTypeMatchBundle, g.source, id, domain.MatchBundle{
+			CorrelationID:   id,
+			TeamNames:       pair,
+			BookmakerBundle: bundleLinks(bms, id),
+		})
+	case KindMonitoring:
+		subject = "match.monitoring"
+		bets := make(map[string][]domain.MonitoringBet, len(bms))
+		for _, bm := range bms {
+			for _, market := range markets[:1+g.rnd.IntN(len(markets))] {
+				bets[bm] = append(bets[bm], domain.MonitoringBet{
+					BetMarket: market,
+					TargetBet: pair[g.rnd.IntN(2)],
+					Less:      g.odds(),
+					More:      g.odds(),
+				})
+			}
+		}
+		event = domain.NewEvent(domain.EventTypeMatchMonitoring, g.source, id, domain.MatchMonitoring{
+			CorrelationID:   id,
+			SportType:       "counter-strike",
+			TeamNames:       pair,
+			BookmakerBundle: bundleLinks(bms, id),
+			Bets:            bets,
+			Timestamp:       time.Now().UTC(),
+		})
+	case KindFork:
+		subject = "fork.found"
+		a, b := g.odds()+0.3, g.odds()+0.3
+		margin := 1/a + 1/b
+		event = domain.NewEvent(domain.EventTypeForkFound, g.source, id, domain.ForkFound{
+			CorrelationID: id,
+			TeamNames:     pair,
+			BetMarket:     markets[0],
+			Profit:        (1/margin - 1) * 100,
+			Legs: []domain.ForkLeg{
+				{Bookmaker: bms[0], TargetBet: pair[0], Odds: a, Stake: (1 / a) / margin},
+				{Bookmaker: bms[len(bms)-1], TargetBet: pair[1], Odds: b, Stake: (1 / b) / margin},
+			},
+			Timestamp: time.Now().UTC(),
+		})
+	default:
+		return "", nil, fmt.Errorf("unknown event kind %q", kind)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode %s event: %w", kind, err)
+	}
+	return subject, data, nil
+}
+
+// bookmakers - от двух до всех букмекеров в случайном порядке
+func (g *Generator) bookmakers() []string {
+	n := 2 + g.rnd.IntN(len(bookmakers)-1)
+	perm := g.rnd.Perm(len(bookmakers))
+	result := make([]string, n)
+	for i := range result {
+		result[i] = bookmakers[perm[i]]
+	}
+	return result
+}
+
+// odds - коэффициент от 1.30 до 3.30 с точностью до сотых
+func (g *Generator) odds() float64 {
+	return float64(130+g.rnd.IntN(200)) / 100
+}
+
+func bundleLinks(bms []string, id int64) map[string]string {
+	links := make(map[string]string, len(bms))
+	for _, bm := range bms {
+		links[bm] = fmt.Sprintf("https://%s.ru/match/%d", bm, id)
+	}
+	return links
+}
@@ -0,0 +1,167 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report - итог прогона. Задержки в микросекундах.
+type Report struct {
+	Mode        string            `json:"mode"`
+	Publishers  int               `json:"publishers"`
+	Subscribers int               `json:"subscribers"`
+	TargetRate  int               `json:"target_rate"`
+	Elapsed     time.Duration     `json:"elapsed_ns"`
+	Published   uint64            `json:"published"`
+	Acked       uint64            `json:"acked"`
+	AckFailures uint64            `json:"ack_failures"`
+	Errors      map[string]uint64 `json:"errors,omitempty"`
+	ByKind      map[string]uint64 `json:"by_kind"`
+	Bytes       uint64            `json:"bytes"`
+	MsgsPerSec  float64           `json:"msgs_per_sec"`
+	BytesPerSec float64           `json:"bytes_per_sec"`
+	AckLatency  Latency           `json:"ack_latency_us"`
+	Received    uint64            `json:"received,omitempty"`
+	E2ELatency  *Latency          `json:"e2e_latency_us,omitempty"`
+}
+
+type Latency struct {
+	Count int64   `json:"count"`
+	Min   int64   `json:"min"`
+	Mean  float64 `json:"mean"`
+	P50   int64   `json:"p50"`
+	P90   int64   `json:"p90"`
+	P99   int64   `json:"p99"`
+	P999  int64   `json:"p99_9"`
+	Max   int64   `json:"max"`
+}
+
+func newLatency(h *hdrhistogram.Histogram) Latency {
+	return Latency{
+		Count: h.TotalCount(),
+		Min:   h.Min(),
+		Mean:  h.Mean(),
+		P50:   h.ValueAtQuantile(50),
+		P90:   h.ValueAtQuantile(90),
+		P99:   h.ValueAtQuantile(99),
+		P999:  h.ValueAtQuantile(99.9),
+		Max:   h.Max(),
+	}
+}
+
+func newReport(opts Options, elapsed time.Duration, pubs []*publisher) *Report {
+	r := &Report{
+		Mode:        "sync",
+		Publishers:  opts.Publishers,
+		Subscribers: opts.Subscribers,
+		TargetRate:  opts.Rate,
+		Elapsed:     elapsed,
+		ByKind:      make(map[string]uint64),
+		Errors:      make(map[string]uint64),
+	}
+	if opts.Async {
+		r.Mode = "async"
+	}
+
+	merged := hdrhistogram.New(1, histogramMax, 3)
+	for _, p := range pubs {
+		r.Published += p.published
+		r.Bytes += p.bytes
+		r.Acked += p.acked.Load()
+		r.AckFailures += p.failed.Load()
+		for kind, n := range p.byKind {
+			r.ByKind[kind] += n
+		}
+		for msg, n := range p.errorCounts {
+			r.Errors[msg] += n
+		}
+		merged.Merge(p.latency)
+	}
+	r.AckLatency = newLatency(merged)
+
+	if secs := elapsed.Seconds(); secs > 0 {
+		r.MsgsPerSec = float64(r.Acked) / secs
+		r.BytesPerSec = float64(r.Bytes) / secs
+	}
+	return r
+}
+
+func (r *Report) addSubscribers(subs []*subscriber) {
+	merged := hdrhistogram.New(1, histogramMax, 3)
+	for _, s := range subs {
+		r.Received += s.received.Load()
+		s.mu.Lock()
+		merged.Merge(s.latency)
+		s.mu.Unlock()
+	}
+	latency := newLatency(merged)
+	r.E2ELatency = &latency
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Report) WriteText(w io.Writer) {
+	rate := "max"
+	if r.TargetRate > 0 {
+		rate = fmt.Sprintf("%d msgs/s", r.TargetRate)
+	}
+	fmt.Fprintf(w, "%s publish, %d publishers, %d subscribers, target rate %s, elapsed %s\n",
+		r.Mode, r.Publishers, r.Subscribers, rate, r.Elapsed.Round(time.Millisecond))
+
+	kinds := make([]string, 0, len(r.ByKind))
+	for kind, n := range r.ByKind {
+		kinds = append(kinds, fmt.Sprintf("%s %d", kind, n))
+	}
+	sort.Strings(kinds)
+	fmt.Fprintf(w, "published    %d msgs (%s), %s\n", r.Published, strings.Join(kinds, ", "), formatBytes(float64(r.Bytes)))
+	fmt.Fprintf(w, "acked        %d, ack failures %d\n", r.Acked, r.AckFailures)
+	fmt.Fprintf(w, "throughput   %.0f msgs/s, %s/s\n", r.MsgsPerSec, formatBytes(r.BytesPerSec))
+	fmt.Fprintf(w, "ack latency  %s\n", r.AckLatency)
+	if r.E2ELatency != nil {
+		fmt.Fprintf(w, "received     %d\n", r.Received)
+		fmt.Fprintf(w, "e2e latency  %s\n", *r.E2ELatency)
+	}
+
+	if len(r.Errors) > 0 {
+		msgs := make([]string, 0, len(r.Errors))
+		for msg := range r.Errors {
+			msgs = append(msgs, msg)
+		}
+		sort.Slice(msgs, func(i, j int) bool { return r.Errors[msgs[i]] > r.Errors[msgs[j]] })
+		fmt.Fprintln(w, "errors:")
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "  %8d  %s\n", r.Errors[msg], msg)
+		}
+	}
+}
+
+func (l Latency) String() string {
+	if l.Count == 0 {
+		return "no samples"
+	}
+	return fmt.Sprintf("min %s  p50 %s  p90 %s  p99 %s  p99.9 %s  max %s",
+		us(l.Min), us(l.P50), us(l.P90), us(l.P99), us(l.P999), us(l.Max))
+}
+
+func us(v int64) time.Duration {
+	return time.Duration(v) * time.Microsecond
+}
+
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f%s", b, units[i])
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package config
+
+import (
+	"os"
+)
+
+// checkDirAccess на платформах без access(2) смотрит только на атрибут "только чтение"
+func checkDirAccess(dir string, info os.FileInfo) error {
+	if info.Mode().Perm()&0200 == 0 {
+		return &os.PathError{Op: "access", Path: dir, Err: os.ErrPermission}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package config
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// checkDirAccess проверяет права текущего процесса на создание файлов в каталоге
+func checkDirAccess(dir string, _ os.FileInfo) error {
+	return unix.Access(dir, unix.W_OK|unix.X_OK)
+}
@@ -4,42 +4,94 @@ import (
 	"NATS_TIRE_SERVICE/internal/domain"
 	"fmt"
 	"github.com/joho/godotenv"
-	"github.com/kelseyhightower/envconfig"
 	"os"
 	"strings"
 )
 
 type Config struct {
-	domain.MainSettings
-	domain.NATSTireServer
-	domain.JetStreamSettings
-	domain.NATSSecurity
-	domain.LoggerSettings
-	domain.HTTPServer
+	domain.MainSettings          `yaml:"app"`
+	domain.NATSTireServer        `yaml:"nats"`
+	domain.JetStreamSettings     `yaml:"jetstream"`
+	domain.NATSSecurity          `yaml:"security"`
+	domain.ClusterSettings       `yaml:"cluster"`
+	domain.LoggerSettings        `yaml:"logger"`
+	domain.HTTPServer            `yaml:"http"`
+	domain.TracingSettings       `yaml:"tracing"`
+	domain.JourneySettings       `yaml:"journey"`
+	domain.LagMonitorSettings    `yaml:"lag_monitor"`
+	domain.AlertSettings         `yaml:"alerts"`
+	domain.StaleConsumerSettings `yaml:"stale_consumers"`
+	domain.ForkDetectorSettings  `yaml:"fork_detector"`
+	domain.OddsFilterSettings    `yaml:"odds_filter"`
+	domain.NormalizeSettings     `yaml:"normalize"`
+
+	configFile  string
+	sources     map[string]string
+	secretFiles map[string]string
 }
 
-func LoadConfigurations(envFile string) (*Config, error) {
+// LoadOptions задает источники конфигурации. Overrides - значения флагов командной строки по ключам окружения.
+type LoadOptions struct {
+	EnvFile    string
+	ConfigFile string
+	Overrides  map[string]string
+}
+
+func LoadConfigurations(envFile, configFile string) (*Config, error) {
+	return Load(LoadOptions{EnvFile: envFile, ConfigFile: configFile})
+}
+
+// Load собирает конфигурацию из значений по умолчанию, YAML файла (ConfigFile или CONFIG_FILE),
+// .env файла, переменных окружения и флагов - каждый следующий источник перекрывает предыдущий.
+// Источники объединяются в памяти, окружение процесса не меняется.
+func Load(opts LoadOptions) (*Config, error) {
+	envFile, configFile := opts.EnvFile, opts.ConfigFile
+	if envFile == "" {
+		envFile = ".env"
+	}
+	dotEnv, err := godotenv.Read(envFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Notice: .env file not found at %s, using environment variables\n", envFile)
+		dotEnv = nil
+	}
+
+	// по убыванию приоритета
+	sources := []source{
+		mapSource(SourceFlag, opts.Overrides),
+		{name: SourceEnv, lookup: os.LookupEnv},
+		mapSource(SourceDotEnv, dotEnv),
+	}
 
-	if envFile != "" {
-		if err := godotenv.Load(envFile); err != nil {
-			fmt.Printf("Notice: .env file not found at %s, using environment variables\n", envFile)
+	if configFile == "" {
+		configFile, _, _ = lookup(sources, "CONFIG_FILE")
+	}
+	var file *fileContents
+	if configFile != "" {
+		if file, err = readConfigFile(configFile); err != nil {
+			return nil, err
 		}
-	} else {
-		if err := godotenv.Load(); err != nil {
-			fmt.Printf("Notice: .env file not found, using environment variables\n")
+		sources = append(sources, mapSource(SourceFile, file.values))
+	}
+
+	cfg := &Config{configFile: configFile}
+	if err := cfg.resolve(sources); err != nil {
+		return nil, err
+	}
+	if file != nil {
+		if err := cfg.applyNested(file); err != nil {
+			return nil, err
 		}
 	}
 
-	var cfg Config
-	if err := envconfig.Process("", &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse environment variables: %w", err)
+	if err := loadSecretFiles(cfg, sources); err != nil {
+		return nil, err
 	}
 
-	if err := validateConfig(&cfg); err != nil {
+	if err := validateConfig(cfg, dotEnv); err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
 func (c *Config) createDirs(cfg Config) error {
@@ -59,6 +111,10 @@ func (c *Config) createDirs(cfg Config) error {
 
 }
 
+func (c *Config) EnsureDirs() error {
+	return c.createDirs(*c)
+}
+
 func getDirFromPath(filePath string) string {
 	if idx := strings.LastIndex(filePath, "/"); idx != -1 {
 		return filePath[:idx]
@@ -70,42 +126,6 @@ func getDirFromPath(filePath string) string {
 	return "."
 }
 
-// TODO: расширить валидирование конфигураций
-func validateConfig(cfg *Config) error {
-
-	if cfg.NATSPort < 1 || cfg.NATSPort > 65535 {
-		return fmt.Errorf("invalid NATS port: %d", cfg.NATSPort)
-	}
-
-	if cfg.NATSHTTPPort < 1 || cfg.NATSHTTPPort > 65535 {
-		return fmt.Errorf("invalid NATS http port: %d", cfg.NATSHTTPPort)
-	}
-	if cfg.HTTPPort < 1 || cfg.HTTPPort > 65535 {
-		return fmt.Errorf("invalid HTTP port: %d", cfg.HTTPPort)
-	}
-
-	ports := map[int]bool{
-		cfg.NATSHTTPPort: true,
-		cfg.NATSHTTPPort: true,
-		cfg.HTTPPort:     true,
-	}
-
-	if len(ports) != 2 {
-		return fmt.Errorf("ports must be unique: NATS=%d, NATS_HTTP=%d, HTTP=%d",
-			cfg.NATSPort, cfg.NATSHTTPPort, cfg.HTTPPort)
-	}
-
-	if cfg.NATSUsername != "" && cfg.NATSPassword == "" {
-		return fmt.Errorf("password is required when username is set")
-	}
-
-	if cfg.LogFormat != "json" && cfg.LogFormat != "console" {
-		return fmt.Errorf("invalid log format: %s", cfg.LogFormat)
-	}
-
-	return nil
-}
-
 func (c *Config) GetNATSURL() string {
 	return fmt.Sprintf("nats://localhost:%d", c.NATSPort)
 }
@@ -126,6 +146,14 @@ func (c *Config) IsDevelopment() bool {
 	return c.Env == "development"
 }
 
+func (c *Config) LameDuckEnabled() bool {
+	return c.LameDuckDuration > 0
+}
+
 func (c *Config) NATSHasAuth() bool {
-	return c.NATSUsername != "" && c.NATSPassword != ""
+	return c.NATSUsername != "" && c.Secret(&c.NATSPassword).IsSet() || len(c.NATSUsers) > 0
+}
+
+func (c *Config) ClusterEnabled() bool {
+	return c.ClusterPort > 0
 }
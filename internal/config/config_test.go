@@ -0,0 +1,236 @@
+package config
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", `
+app:
+  env: staging
+logger:
+  level: warn
+  format: json
+nats:
+  port: 1001
+http:
+  port: 2001
+journey:
+  max_events: 7
+`)
+	envFile := writeFile(t, ".env", "LOG_LEVEL=error\nNATS_PORT=1002\nHTTP_PORT=2002\n")
+	t.Setenv("NATS_PORT", "1003")
+	t.Setenv("HTTP_PORT", "2003")
+
+	cfg, err := Load(LoadOptions{
+		EnvFile:    envFile,
+		ConfigFile: configFile,
+		Overrides:  map[string]string{"HTTP_PORT": "2004", "DATA_DIR": t.TempDir()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value, source string
+	}{
+		{"APP_NAME", "nats_tire_service", SourceDefault},
+		{"ENV", "staging", SourceFile},
+		{"LOG_FORMAT", "json", SourceFile},
+		{"LOG_LEVEL", "error", SourceDotEnv},
+		{"NATS_PORT", "1003", SourceEnv},
+		{"HTTP_PORT", "2004", SourceFlag},
+	}
+	effective := make(map[string]Entry)
+	for _, e := range cfg.Effective() {
+		effective[e.Key] = e
+	}
+	for _, tt := range tests {
+		if e := effective[tt.key]; e.Value != tt.value || e.Source != tt.source {
+			t.Errorf("%s = %q from %s, want %q from %s", tt.key, e.Value, e.Source, tt.value, tt.source)
+		}
+	}
+
+	if cfg.JourneyMaxEvents != 7 || cfg.ConfigFile() != configFile {
+		t.Errorf("JourneyMaxEvents = %d, ConfigFile = %q", cfg.JourneyMaxEvents, cfg.ConfigFile())
+	}
+	// источники не попадают в окружение процесса
+	for _, key := range []string{"LOG_LEVEL", "LOG_FORMAT", "JOURNEY_MAX_EVENTS"} {
+		if value, ok := os.LookupEnv(key); ok {
+			t.Errorf("%s leaked into environment: %q", key, value)
+		}
+	}
+}
+
+func TestLoadConfigFileFromDotEnv(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", "logger:\n  format: json\n")
+	envFile := writeFile(t, ".env", "CONFIG_FILE="+configFile+"\nDATA_DIR="+t.TempDir()+"\n")
+
+	cfg, err := Load(LoadOptions{EnvFile: envFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogFormat != "json" || cfg.ConfigFile() != configFile {
+		t.Errorf("LogFormat = %q, ConfigFile = %q", cfg.LogFormat, cfg.ConfigFile())
+	}
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", "nats:\n  prot: 4222\nlogger:\n  level: info\n  colour: true\n")
+	_, err := Load(LoadOptions{EnvFile: writeFile(t, ".env", ""), ConfigFile: configFile})
+	if err == nil || !strings.Contains(err.Error(), "unknown keys in config file") ||
+		!strings.Contains(err.Error(), "logger.colour, nats.prot") {
+		t.Errorf("config file: %v", err)
+	}
+
+	envFile := writeFile(t, ".env", "NATS_PROT=4222\nDATA_DIR="+t.TempDir()+"\n")
+	_, err = Load(LoadOptions{EnvFile: envFile})
+	if err == nil || !strings.Contains(err.Error(), "unknown .env key NATS_PROT (did you mean NATS_PORT?)") {
+		t.Errorf(".env: %v", err)
+	}
+}
+
+func TestLoadInvalidValues(t *testing.T) {
+	envFile := writeFile(t, ".env", "NATS_PORT=abc\nJETSTREAM_ENABLED=maybe\n")
+	_, err := Load(LoadOptions{EnvFile: envFile, Overrides: map[string]string{"DATA_DIR": t.TempDir()}})
+	if err == nil || !strings.Contains(err.Error(), "2 problems") ||
+		!strings.Contains(err.Error(), `NATS_PORT="abc" (from .env)`) {
+		t.Errorf("Load = %v", err)
+	}
+}
+
+func TestLoadListsAndMaps(t *testing.T) {
+	envFile := writeFile(t, ".env", "STALE_CONSUMER_KEEP=\nALERT_WEBHOOK_URLS=http://a/1, http://b/2\n")
+	cfg, err := Load(LoadOptions{
+		EnvFile:   envFile,
+		Overrides: map[string]string{"DATA_DIR": t.TempDir(), "STALE_CONSUMER_STREAM_POLICIES": "EVENTS:delete,AUDIT:ignore"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.StaleConsumerKeep) != 0 {
+		t.Errorf("StaleConsumerKeep = %q", cfg.StaleConsumerKeep)
+	}
+	if len(cfg.AlertWebhookURLs) != 2 || cfg.AlertWebhookURLs[1] != "http://b/2" {
+		t.Errorf("AlertWebhookURLs = %q", cfg.AlertWebhookURLs)
+	}
+	if p := cfg.StaleConsumerStreamPolicies; len(p) != 2 || p["EVENTS"] != "delete" || p["AUDIT"] != "ignore" {
+		t.Errorf("StaleConsumerStreamPolicies = %v", p)
+	}
+}
+
+func TestLoadNestedSections(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", `
+jetstream:
+  streams:
+    - name: EVENTS
+      subjects: ["events.>"]
+      max_bytes: 1GiB
+      max_age: 24h
+    - name: JOBS
+      subjects: [jobs.>]
+      storage: memory
+      retention: workqueue
+security:
+  users:
+    - username: reader
+      password: secret
+      subscribe: ["events.>"]
+cluster:
+  name: scanners
+  port: 6222
+  routes: [nats-route://node2:6222, nats-route://node3:6222]
+stale_consumers:
+  stream_policies:
+    EVENTS: delete
+`)
+	cfg, err := Load(LoadOptions{
+		EnvFile:    writeFile(t, ".env", ""),
+		ConfigFile: configFile,
+		Overrides:  map[string]string{"DATA_DIR": t.TempDir()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Streams) != 2 || cfg.Streams[0].MaxBytes != domain.GiB || cfg.Streams[0].MaxAge != 24*time.Hour ||
+		cfg.Streams[1].Storage != "memory" || cfg.Streams[1].Subjects[0] != "jobs.>" {
+		t.Errorf("Streams = %+v", cfg.Streams)
+	}
+	if len(cfg.NATSUsers) != 1 || cfg.NATSUsers[0].Password.Value() != "secret" || !cfg.NATSHasAuth() {
+		t.Errorf("NATSUsers = %+v", cfg.NATSUsers)
+	}
+	if !cfg.ClusterEnabled() || cfg.ClusterName != "scanners" || len(cfg.ClusterRoutes) != 2 {
+		t.Errorf("cluster = %q %d %q", cfg.ClusterName, cfg.ClusterPort, cfg.ClusterRoutes)
+	}
+	if cfg.StaleConsumerStreamPolicies["EVENTS"] != "delete" {
+		t.Errorf("StaleConsumerStreamPolicies = %v", cfg.StaleConsumerStreamPolicies)
+	}
+
+	for _, e := range cfg.Effective() {
+		if e.Path == "security.users" && (e.Key != "-" || e.Source != SourceFile || strings.Contains(e.Value, "secret")) {
+			t.Errorf("security.users entry: %+v", e)
+		}
+	}
+}
+
+func TestLoadNestedSectionErrors(t *testing.T) {
+	configFile := writeFile(t, "config.yaml", `
+jetstream:
+  streams:
+    - name: EVENTS
+      subject: ["events.>"]
+`)
+	_, err := Load(LoadOptions{EnvFile: writeFile(t, ".env", ""), ConfigFile: configFile})
+	if err == nil || !strings.Contains(err.Error(), "invalid jetstream.streams in config file") ||
+		!strings.Contains(err.Error(), "field subject not found") {
+		t.Errorf("unknown stream field: %v", err)
+	}
+
+	configFile = writeFile(t, "config.yaml", `
+jetstream:
+  streams:
+    - name: EVENTS
+      subjects: ["events.>"]
+      storage: disk
+    - name: EVENTS
+security:
+  users:
+    - username: reader
+cluster:
+  port: 8080
+  routes: [node2:6222]
+`)
+	_, err = Load(LoadOptions{
+		EnvFile:    writeFile(t, ".env", ""),
+		ConfigFile: configFile,
+		Overrides:  map[string]string{"DATA_DIR": t.TempDir()},
+	})
+	for _, want := range []string{
+		`stream EVENTS: invalid storage "disk"`,
+		"jetstream.streams[1]: duplicate stream EVENTS",
+		"stream EVENTS: subjects must not be empty",
+		"security.users[0]: password is required",
+		"CLUSTER_NAME is required",
+		`invalid cluster route "node2:6222"`,
+		"ports must be unique: HTTP and CLUSTER both use 8080",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// source - источник значений по ключам окружения: флаги, окружение, .env или файл конфигурации
+type source struct {
+	name   string
+	lookup func(key string) (string, bool)
+}
+
+func mapSource(name string, values map[string]string) source {
+	return source{name: name, lookup: func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}}
+}
+
+// lookup ищет ключ в источниках по убыванию приоритета и возвращает значение и имя источника
+func lookup(sources []source, key string) (string, string, bool) {
+	for _, s := range sources {
+		if value, ok := s.lookup(key); ok {
+			return value, s.name, true
+		}
+	}
+	return "", "", false
+}
+
+// Defaults возвращает конфигурацию только из значений по умолчанию, без .env, файла и окружения.
+// Нужна там, где окружение процесса не должно влиять на результат, например в тестах.
+func Defaults() (*Config, error) {
+	cfg := &Config{}
+	if err := cfg.resolve(nil); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// resolve заполняет каждый параметр из первого источника, где он задан, иначе значением по умолчанию.
+// Заданное, но пустое значение не заменяется значением по умолчанию, как в envconfig.
+func (c *Config) resolve(sources []source) error {
+	verr := &ValidationError{}
+	c.sources = make(map[string]string)
+
+	for _, f := range fields(c) {
+		raw, from, ok := lookup(sources, f.key)
+		if !ok {
+			raw, from = f.def, SourceDefault
+		}
+		c.sources[f.key] = from
+		if !ok && raw == "" {
+			continue
+		}
+
+		if err := setValue(f.value, raw); err != nil {
+			if from == SourceDefault {
+				return fmt.Errorf("invalid default %q for %s: %w", raw, f.key, err)
+			}
+			verr.add("invalid %s=%q (from %s): %v", f.key, raw, from, err)
+		}
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// setValue разбирает строку так же, как envconfig: списки через запятую, словари как key:value через запятую
+func setValue(v reflect.Value, raw string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if strings.TrimSpace(raw) == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		parts := strings.Split(raw, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if strings.TrimSpace(raw) == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(raw, ",") {
+			key, value, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("invalid map item %q, expected key:value", pair)
+			}
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := setValue(k, strings.TrimSpace(key)); err != nil {
+				return err
+			}
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(val, strings.TrimSpace(value)); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, val)
+		}
+		v.Set(m)
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Источники значений в порядке возрастания приоритета:
+// значение по умолчанию < файл конфигурации < .env < переменные окружения < флаги
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceDotEnv  = ".env"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Entry - итоговое значение одного параметра конфигурации
+type Entry struct {
+	Key    string
+	Path   string
+	Value  string
+	Source string
+	Secret bool
+}
+
+type field struct {
+	key   string
+	path  string
+	def   string
+	value reflect.Value
+}
+
+// fields обходит Config и возвращает все параметры, у которых есть envconfig ключ
+func fields(cfg *Config) []field {
+	var result []field
+	for _, f := range allFields(cfg) {
+		if f.key != "" {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// nestedFields возвращает параметры без envconfig ключа - списки структур, которые задаются только в файле
+func nestedFields(cfg *Config) []field {
+	var result []field
+	for _, f := range allFields(cfg) {
+		if f.key == "" {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func allFields(cfg *Config) []field {
+	var result []field
+
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+
+			name := yamlName(sf)
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+
+			key := sf.Tag.Get("envconfig")
+			if sf.Type.Kind() == reflect.Struct && key == "" {
+				walk(v.Field(i), path)
+				continue
+			}
+			if key == "-" || key == "" && sf.Tag.Get("yaml") == "" {
+				continue
+			}
+
+			result = append(result, field{
+				key:   key,
+				path:  path,
+				def:   sf.Tag.Get("default"),
+				value: v.Field(i),
+			})
+		}
+	}
+
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return result
+}
+
+func yamlName(sf reflect.StructField) string {
+	if tag := strings.Split(sf.Tag.Get("yaml"), ",")[0]; tag != "" {
+		return tag
+	}
+	return strings.ToLower(sf.Name)
+}
+
+// fileContents - значения файла конфигурации: простые параметры по ключам переменных окружения
+// и вложенные списки по пути в файле
+type fileContents struct {
+	values map[string]string
+	nested map[string]interface{}
+}
+
+// readConfigFile читает YAML файл и раскладывает значения по ключам переменных окружения
+func readConfigFile(path string) (*fileContents, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	keys := make(map[string]string)
+	for _, f := range fields(&Config{}) {
+		keys[f.path] = f.key
+		if isSecretField(f) {
+			keys[f.path+"_file"] = f.key + secretFileSuffix
+		}
+	}
+
+	nestedPaths := make(map[string]bool)
+	for _, f := range nestedFields(&Config{}) {
+		nestedPaths[f.path] = true
+	}
+
+	file := &fileContents{values: make(map[string]string), nested: make(map[string]interface{})}
+	var unknown []string
+
+	var walk func(node map[string]interface{}, prefix string)
+	walk = func(node map[string]interface{}, prefix string) {
+		for name, raw := range node {
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+
+			if key, ok := keys[path]; ok {
+				file.values[key] = scalarString(raw)
+				continue
+			}
+			if nestedPaths[path] {
+				file.nested[path] = raw
+				continue
+			}
+			if nested, ok := raw.(map[string]interface{}); ok {
+				walk(nested, path)
+				continue
+			}
+			unknown = append(unknown, path)
+		}
+	}
+	walk(doc, "")
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	return file, nil
+}
+
+// applyNested заполняет вложенные списки из файла. Неизвестные ключи внутри элементов - ошибка.
+func (c *Config) applyNested(file *fileContents) error {
+	verr := &ValidationError{}
+	for _, f := range nestedFields(c) {
+		raw, ok := file.nested[f.path]
+		if !ok {
+			continue
+		}
+
+		data, err := yaml.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", f.path, err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(f.value.Addr().Interface()); err != nil {
+			verr.add("invalid %s in config file: %v", f.path, err)
+			continue
+		}
+		c.sources[f.path] = SourceFile
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func scalarString(raw interface{}) string {
+	switch v := raw.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, scalarString(item))
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		parts := make([]string, 0, len(v))
+		for key, item := range v {
+			parts = append(parts, key+":"+scalarString(item))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Effective возвращает итоговую конфигурацию с источником каждого значения, секреты скрыты.
+// У вложенных списков нет ключа окружения, вместо него "-".
+func (c *Config) Effective() []Entry {
+	var entries []Entry
+	for _, f := range allFields(c) {
+		value := fmt.Sprint(f.value.Interface())
+
+		key, sourceKey := f.key, f.key
+		if key == "" {
+			key, sourceKey = "-", f.path
+		}
+		source := c.sources[sourceKey]
+		if source == "" {
+			source = SourceDefault
+		}
+
+		entries = append(entries, Entry{
+			Key:    key,
+			Path:   f.path,
+			Value:  value,
+			Source: source,
+			Secret: isSecretField(f),
+		})
+	}
+	return entries
+}
+
+// Parameters описывает все параметры конфигурации со значениями по умолчанию, например для генерации флагов
+func Parameters() []Entry {
+	var entries []Entry
+	for _, f := range fields(&Config{}) {
+		entries = append(entries, Entry{
+			Key:    f.key,
+			Path:   f.path,
+			Value:  f.def,
+			Source: SourceDefault,
+			Secret: isSecretField(f),
+		})
+	}
+	return entries
+}
+
+func (c *Config) ConfigFile() string {
+	return c.configFile
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	SourceSecretFile = "secret file"
+	secretFileSuffix = "_FILE"
+)
+
+var secretType = reflect.TypeOf(domain.Secret(""))
+
+// secretsMu защищает секретные поля от RefreshSecrets, пока их читают другие горутины
+var secretsMu sync.RWMutex
+
+func isSecretField(f field) bool {
+	return f.value.Type() == secretType
+}
+
+// loadSecretFiles заполняет секреты из файлов, указанных в <KEY>_FILE (Docker/Kubernetes secrets),
+// и пароли security.users из password_file
+func loadSecretFiles(cfg *Config, sources []source) error {
+	verr := &ValidationError{}
+	cfg.secretFiles = make(map[string]string)
+
+	for _, f := range fields(cfg) {
+		if !isSecretField(f) {
+			continue
+		}
+
+		fileKey := f.key + secretFileSuffix
+		path, _, _ := lookup(sources, fileKey)
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if f.value.String() != "" {
+			verr.add("both %s and %s are set, use only one", f.key, fileKey)
+			continue
+		}
+
+		value, err := readSecretFile(path)
+		if err != nil {
+			verr.add("%s: %v", fileKey, err)
+			continue
+		}
+
+		f.value.SetString(value)
+		cfg.secretFiles[f.key] = path
+		cfg.sources[f.key] = SourceSecretFile
+	}
+
+	for i := range cfg.NATSUsers {
+		u := &cfg.NATSUsers[i]
+		if u.PasswordFile == "" {
+			continue
+		}
+		if u.Password.IsSet() {
+			verr.add("security.users[%d]: both password and password_file are set, use only one", i)
+			continue
+		}
+		value, err := readSecretFile(u.PasswordFile)
+		if err != nil {
+			verr.add("security.users[%d].password_file: %v", i, err)
+			continue
+		}
+		u.Password = domain.Secret(value)
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// RefreshSecrets перечитывает файлы секретов, например перед перезагрузкой сервера.
+// Новые значения применяются только если все файлы прочитаны и прошли проверку.
+func (c *Config) RefreshSecrets() error {
+	next := *c
+	next.NATSUsers = append([]domain.NATSUser(nil), c.NATSUsers...)
+	values := make(map[string]string)
+	for _, f := range fields(&next) {
+		path, ok := c.secretFiles[f.key]
+		if !ok {
+			continue
+		}
+
+		value, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", f.key, err)
+		}
+		f.value.SetString(value)
+		values[f.key] = value
+	}
+	for i := range next.NATSUsers {
+		u := &next.NATSUsers[i]
+		if u.PasswordFile == "" {
+			continue
+		}
+		value, err := readSecretFile(u.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to refresh password of user %s: %w", u.Username, err)
+		}
+		u.Password = domain.Secret(value)
+	}
+
+	if err := validateSecrets(&next); err != nil {
+		return err
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, f := range fields(c) {
+		if value, ok := values[f.key]; ok {
+			f.value.SetString(value)
+		}
+	}
+	for i := range c.NATSUsers {
+		c.NATSUsers[i].Password = next.NATSUsers[i].Password
+	}
+	return nil
+}
+
+// Secret читает секретное поле конфигурации так, чтобы не пересечься с RefreshSecrets.
+// Нужен везде, где поле читается параллельно с перезагрузкой, например в обработчиках HTTP.
+func (c *Config) Secret(field *domain.Secret) domain.Secret {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	return *field
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return value, nil
+}
+
+func validateSecrets(cfg *Config) error {
+	if cfg.NATSPassword.IsBcrypt() {
+		if _, err := bcrypt.Cost([]byte(cfg.NATSPassword.Value())); err != nil {
+			return fmt.Errorf("NATS_PASSWORD is not a valid bcrypt hash: %w", err)
+		}
+	}
+	for _, u := range cfg.NATSUsers {
+		if u.Password.IsBcrypt() {
+			if _, err := bcrypt.Cost([]byte(u.Password.Value())); err != nil {
+				return fmt.Errorf("password of user %s is not a valid bcrypt hash: %w", u.Username, err)
+			}
+		}
+	}
+	return nil
+}
+
+func secretFileKeys(cfg *Config) map[string]bool {
+	keys := make(map[string]bool)
+	for _, f := range fields(cfg) {
+		if isSecretField(f) {
+			keys[f.key+secretFileSuffix] = true
+		}
+	}
+	return keys
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSecretFile(t *testing.T) {
+	password := writeFile(t, "password", "secret\n")
+	token := writeFile(t, "token", "admin-token")
+	configFile := writeFile(t, "config.yaml", "http:\n  admin_token_file: "+token+"\n")
+	envFile := writeFile(t, ".env", "NATS_USERNAME=scanner\nNATS_PASSWORD_FILE="+password+"\n")
+
+	cfg, err := Load(LoadOptions{EnvFile: envFile, ConfigFile: configFile, Overrides: map[string]string{"DATA_DIR": t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NATSPassword.Value() != "secret" || cfg.AdminToken.Value() != "admin-token" {
+		t.Errorf("NATSPassword = %q, AdminToken = %q", cfg.NATSPassword.Value(), cfg.AdminToken.Value())
+	}
+
+	for _, e := range cfg.Effective() {
+		if e.Key != "NATS_PASSWORD" && e.Key != "ADMIN_TOKEN" {
+			continue
+		}
+		if !e.Secret || e.Source != SourceSecretFile || e.Value != "******" {
+			t.Errorf("%s: %+v", e.Key, e)
+		}
+	}
+}
+
+func TestLoadSecretFileErrors(t *testing.T) {
+	dataDir := t.TempDir()
+	tests := []struct {
+		name, env, want string
+	}{
+		{
+			name: "value and file",
+			env:  "NATS_USERNAME=scanner\nNATS_PASSWORD=secret\nNATS_PASSWORD_FILE=" + writeFile(t, "password", "secret"),
+			want: "both NATS_PASSWORD and NATS_PASSWORD_FILE are set",
+		},
+		{
+			name: "empty file",
+			env:  "ADMIN_TOKEN_FILE=" + writeFile(t, "token", "\n"),
+			want: "ADMIN_TOKEN_FILE: secret file",
+		},
+		{
+			name: "missing file",
+			env:  "ADMIN_TOKEN_FILE=" + filepath.Join(dataDir, "missing"),
+			want: "ADMIN_TOKEN_FILE: failed to read secret file",
+		},
+		{
+			name: "invalid bcrypt hash",
+			env:  "NATS_USERNAME=scanner\nNATS_PASSWORD_FILE=" + writeFile(t, "hash", "$2a$10$short"),
+			want: "NATS_PASSWORD is not a valid bcrypt hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envFile := writeFile(t, ".env", tt.env+"\nDATA_DIR="+dataDir+"\n")
+			_, err := Load(LoadOptions{EnvFile: envFile})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshSecrets(t *testing.T) {
+	password := writeFile(t, "password", "secret")
+	envFile := writeFile(t, ".env", "NATS_USERNAME=scanner\nNATS_PASSWORD_FILE="+password+"\nDATA_DIR="+t.TempDir()+"\n")
+	cfg, err := Load(LoadOptions{EnvFile: envFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(password, []byte("rotated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RefreshSecrets(); err != nil || cfg.NATSPassword.Value() != "rotated" {
+		t.Fatalf("RefreshSecrets = %v, password %q", err, cfg.NATSPassword.Value())
+	}
+
+	// при ошибке остается прежнее значение
+	if err := os.WriteFile(password, []byte("$2a$10$short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RefreshSecrets(); err == nil || cfg.NATSPassword.Value() != "rotated" {
+		t.Errorf("invalid hash: RefreshSecrets = %v, password %q", err, cfg.NATSPassword.Value())
+	}
+	if err := os.Remove(password); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RefreshSecrets(); err == nil || cfg.NATSPassword.Value() != "rotated" {
+		t.Errorf("missing file: RefreshSecrets = %v, password %q", err, cfg.NATSPassword.Value())
+	}
+}
+
+func TestRefreshSecretsConcurrentReads(t *testing.T) {
+	token := writeFile(t, "token", "first")
+	envFile := writeFile(t, ".env", "ADMIN_TOKEN_FILE="+token+"\nDATA_DIR="+t.TempDir()+"\n")
+	cfg, err := Load(LoadOptions{EnvFile: envFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// обработчики HTTP читают токен во время перезагрузки; под -race гонка была бы видна здесь
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if v := cfg.Secret(&cfg.AdminToken).Value(); v != "first" && v != "second" {
+				t.Errorf("AdminToken = %q", v)
+				return
+			}
+		}
+	}()
+	if err := os.WriteFile(token, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RefreshSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	if v := cfg.Secret(&cfg.AdminToken).Value(); v != "second" {
+		t.Errorf("AdminToken after refresh = %q", v)
+	}
+}
+
+func TestUserPasswordFile(t *testing.T) {
+	password := writeFile(t, "reader", "secret\n")
+	configFile := writeFile(t, "config.yaml", "security:\n  users:\n    - username: reader\n      password_file: "+password+"\n")
+	cfg, err := Load(LoadOptions{
+		EnvFile:    writeFile(t, ".env", ""),
+		ConfigFile: configFile,
+		Overrides:  map[string]string{"DATA_DIR": t.TempDir()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.NATSUsers[0].Password.Value(); got != "secret" {
+		t.Fatalf("password = %q", got)
+	}
+
+	if err := os.WriteFile(password, []byte("rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RefreshSecrets(); err != nil || cfg.NATSUsers[0].Password.Value() != "rotated" {
+		t.Errorf("RefreshSecrets = %v, password %q", err, cfg.NATSUsers[0].Password.Value())
+	}
+
+	configFile = writeFile(t, "config.yaml", "security:\n  users:\n    - username: reader\n      password: secret\n      password_file: "+password+"\n")
+	_, err = Load(LoadOptions{
+		EnvFile:    writeFile(t, ".env", ""),
+		ConfigFile: configFile,
+		Overrides:  map[string]string{"DATA_DIR": t.TempDir()},
+	})
+	if err == nil || !strings.Contains(err.Error(), "both password and password_file are set") {
+		t.Errorf("password and password_file: %v", err)
+	}
+}
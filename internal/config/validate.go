@@ -0,0 +1,405 @@
+package config
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/pkg/levenshtein"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	validEnvs            = []string{"development", "staging", "production"}
+	validExporters       = []string{"otlp", "file"}
+	validLogLevels       = []string{"debug", "info", "warn", "error"}
+	validLogFormats      = []string{"json", "console"}
+	validOddsFilterModes = []string{domain.OddsFilterSuppress, domain.OddsFilterAnnotate}
+	validStreamStorages  = []string{"", "file", "memory"}
+	validRetentions      = []string{"", "limits", "interest", "workqueue"}
+	validStalePolicies   = []string{
+		domain.StalePolicyWarn, domain.StalePolicyInactiveThreshold, domain.StalePolicyDelete, domain.StalePolicyIgnore,
+	}
+)
+
+// ключи .env, которые не относятся к полям Config, но используются сервисом
+var auxiliaryKeys = map[string]bool{
+	"CONFIG_FILE": true,
+}
+
+// ValidationError собирает все найденные проблемы конфигурации
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s",
+		len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+func validateConfig(cfg *Config, dotEnv map[string]string) error {
+	verr := &ValidationError{}
+
+	validatePorts(cfg, verr)
+
+	if !oneOf(cfg.Env, validEnvs) {
+		verr.add("invalid env %q, expected one of %s", cfg.Env, strings.Join(validEnvs, "/"))
+	}
+
+	if cfg.JetStreamEnabled && cfg.MaxMemoryStore == 0 && cfg.MaxFileStore == 0 {
+		verr.add("JetStream is enabled but both MAX_MEMORY_STORE and MAX_FILE_STORE are 0")
+	}
+
+	if strings.TrimSpace(cfg.DataDir) == "" {
+		verr.add("DATA_DIR must not be empty")
+	} else if err := checkWritableDir(cfg.DataDir); err != nil {
+		verr.add("DATA_DIR: %v", err)
+	}
+
+	if cfg.NATSUsername != "" && !cfg.NATSPassword.IsSet() {
+		verr.add("password is required when username is set")
+	}
+	if cfg.NATSUsername == "" && cfg.NATSPassword.IsSet() {
+		verr.add("username is required when password is set")
+	}
+	validateUsers(cfg, verr)
+	if err := validateSecrets(cfg); err != nil {
+		verr.add("%v", err)
+	}
+	validateStreams(cfg, verr)
+	validateCluster(cfg, verr)
+
+	if cfg.LameDuckDuration < 0 || cfg.LameDuckGracePeriod < 0 || cfg.ShutdownDrainDelay < 0 {
+		verr.add("shutdown durations must not be negative")
+	}
+	if cfg.LameDuckDuration > 0 && cfg.LameDuckGracePeriod >= cfg.LameDuckDuration {
+		verr.add("lame duck grace period (%v) must be lower than lame duck duration (%v)",
+			cfg.LameDuckGracePeriod, cfg.LameDuckDuration)
+	}
+
+	if !oneOf(cfg.LogLevel, validLogLevels) {
+		verr.add("invalid log level %q, expected one of %s", cfg.LogLevel, strings.Join(validLogLevels, "/"))
+	}
+	if !oneOf(cfg.LogFormat, validLogFormats) {
+		verr.add("invalid log format %q, expected one of %s", cfg.LogFormat, strings.Join(validLogFormats, "/"))
+	}
+	if cfg.LogFile != "" {
+		if err := checkWritableDir(filepath.Dir(cfg.LogFile)); err != nil {
+			verr.add("LOG_FILE: %v", err)
+		}
+	}
+	if cfg.NATSLogLevel != "" && !oneOf(cfg.NATSLogLevel, validLogLevels) {
+		verr.add("invalid NATS log level %q, expected one of %s", cfg.NATSLogLevel, strings.Join(validLogLevels, "/"))
+	}
+	if cfg.NATSLogSampleInitial < 0 || cfg.NATSLogSampleThereafter < 0 {
+		verr.add("NATS log sampling counts must not be negative")
+	}
+	if cfg.NATSLogSampleInitial > 0 && cfg.NATSLogSampleInterval <= 0 {
+		verr.add("NATS_LOG_SAMPLE_INTERVAL must be positive when sampling is enabled")
+	}
+	if cfg.LogMaxSize > 0 && cfg.LogMaxSize < domain.KiB {
+		verr.add("LOG_MAX_SIZE must be at least 1KiB, got %s", cfg.LogMaxSize)
+	}
+	if cfg.LogRotateInterval < 0 || cfg.LogMaxAge < 0 {
+		verr.add("log rotation durations must not be negative")
+	}
+	if cfg.LogMaxBackups < 0 {
+		verr.add("LOG_MAX_BACKUPS must not be negative")
+	}
+
+	if cfg.TracingEnabled {
+		if !oneOf(cfg.TracingExporter, validExporters) {
+			verr.add("invalid tracing exporter %q, expected one of %s", cfg.TracingExporter, strings.Join(validExporters, "/"))
+		}
+		if cfg.TracingExporter == "otlp" && strings.TrimSpace(cfg.OTLPEndpoint) == "" {
+			verr.add("OTLP_ENDPOINT is required for otlp exporter")
+		}
+		if cfg.TracingExporter == "file" {
+			if cfg.TracingFile == "" {
+				verr.add("TRACING_FILE is required for file exporter")
+			} else if err := checkWritableDir(filepath.Dir(cfg.TracingFile)); err != nil {
+				verr.add("TRACING_FILE: %v", err)
+			}
+		}
+	}
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		verr.add("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", cfg.TracingSampleRatio)
+	}
+
+	if cfg.JourneyEnabled {
+		if strings.TrimSpace(cfg.JourneyStream) == "" {
+			verr.add("JOURNEY_STREAM must not be empty")
+		}
+		if cfg.JourneyMaxEvents <= 0 {
+			verr.add("JOURNEY_MAX_EVENTS must be positive, got %d", cfg.JourneyMaxEvents)
+		}
+	}
+
+	// интервал общий для проверки отставания и неактивных консюмеров
+	if (cfg.LagMonitorEnabled || cfg.StaleConsumersEnabled) && cfg.LagCheckInterval <= 0 {
+		verr.add("LAG_CHECK_INTERVAL must be positive, got %v", cfg.LagCheckInterval)
+	}
+	if cfg.LagMaxAckPending < 0 || cfg.LagMaxRedelivered < 0 {
+		verr.add("lag thresholds must not be negative")
+	}
+	for _, raw := range cfg.AlertWebhookURLs {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.add("invalid alert webhook url %q, expected http(s)://host/path", raw)
+		}
+	}
+	if cfg.AlertWebhookTimeout <= 0 {
+		verr.add("ALERT_WEBHOOK_TIMEOUT must be positive, got %v", cfg.AlertWebhookTimeout)
+	}
+	if cfg.AlertRepeatInterval < 0 {
+		verr.add("ALERT_REPEAT_INTERVAL must not be negative")
+	}
+
+	if cfg.StaleConsumersEnabled {
+		if cfg.StaleConsumerAfter <= 0 {
+			verr.add("STALE_CONSUMER_AFTER must be positive, got %v", cfg.StaleConsumerAfter)
+		}
+		if !oneOf(cfg.StaleConsumerPolicy, validStalePolicies) {
+			verr.add("invalid stale consumer policy %q, expected one of %s",
+				cfg.StaleConsumerPolicy, strings.Join(validStalePolicies, "/"))
+		}
+		for stream, policy := range cfg.StaleConsumerStreamPolicies {
+			if !oneOf(policy, validStalePolicies) {
+				verr.add("invalid stale consumer policy %q for stream %s, expected one of %s",
+					policy, stream, strings.Join(validStalePolicies, "/"))
+			}
+		}
+		if cfg.StaleConsumerDeleteAfter < cfg.StaleConsumerAfter {
+			verr.add("STALE_CONSUMER_DELETE_AFTER (%v) must not be lower than STALE_CONSUMER_AFTER (%v)",
+				cfg.StaleConsumerDeleteAfter, cfg.StaleConsumerAfter)
+		}
+	}
+
+	if cfg.ForkDetectorEnabled {
+		if strings.TrimSpace(cfg.ForkDetectorStream) == "" || strings.TrimSpace(cfg.ForkDetectorSubject) == "" {
+			verr.add("FORK_DETECTOR_STREAM and FORK_DETECTOR_SUBJECT must not be empty")
+		}
+		if cfg.ForkMinProfit < 0 {
+			verr.add("FORK_MIN_PROFIT must not be negative, got %v", cfg.ForkMinProfit)
+		}
+		if cfg.ForkMaxProfit != 0 && cfg.ForkMaxProfit <= cfg.ForkMinProfit {
+			verr.add("FORK_MAX_PROFIT (%v) must be greater than FORK_MIN_PROFIT (%v)", cfg.ForkMaxProfit, cfg.ForkMinProfit)
+		}
+		if cfg.ForkOddsMaxAge <= 0 {
+			verr.add("FORK_ODDS_MAX_AGE must be positive, got %v", cfg.ForkOddsMaxAge)
+		}
+		if cfg.ForkMaxLegSkew < 0 || cfg.ForkRepublishDelta < 0 {
+			verr.add("FORK_MAX_LEG_SKEW and FORK_REPUBLISH_DELTA must not be negative")
+		}
+	}
+
+	if cfg.OddsFilterEnabled {
+		if strings.TrimSpace(cfg.OddsFilterStream) == "" || strings.TrimSpace(cfg.OddsFilterDurable) == "" {
+			verr.add("ODDS_FILTER_STREAM and ODDS_FILTER_DURABLE must not be empty")
+		}
+		if !oneOf(cfg.OddsFilterMode, validOddsFilterModes) {
+			verr.add("invalid odds filter mode %q, expected one of %s",
+				cfg.OddsFilterMode, strings.Join(validOddsFilterModes, "/"))
+		}
+		if cfg.OddsTTL <= 0 || cfg.OddsFlickerWindow <= 0 {
+			verr.add("ODDS_TTL and ODDS_FLICKER_WINDOW must be positive")
+		}
+		if cfg.OddsFlickerMaxChanges < 1 {
+			verr.add("ODDS_FLICKER_MAX_CHANGES must be at least 1, got %d", cfg.OddsFlickerMaxChanges)
+		}
+	}
+
+	if cfg.NormalizeEnabled {
+		if strings.TrimSpace(cfg.NormalizeBucket) == "" {
+			verr.add("NORMALIZE_BUCKET must not be empty")
+		}
+		if cfg.NormalizeMinConfidence <= 0 || cfg.NormalizeMinConfidence > 1 {
+			verr.add("NORMALIZE_MIN_CONFIDENCE must be in (0, 1], got %v", cfg.NormalizeMinConfidence)
+		}
+		if cfg.NormalizeRepublish {
+			if strings.TrimSpace(cfg.NormalizeStream) == "" || strings.TrimSpace(cfg.NormalizeDurable) == "" {
+				verr.add("NORMALIZE_STREAM and NORMALIZE_DURABLE must not be empty")
+			}
+			if !strings.HasPrefix(cfg.NormalizeSubjectPrefix, "events.") || strings.ContainsAny(cfg.NormalizeSubjectPrefix, "*> ") {
+				verr.add("NORMALIZE_SUBJECT_PREFIX must be a literal subject under events., got %q", cfg.NormalizeSubjectPrefix)
+			}
+		}
+	}
+
+	validateKnownKeys(cfg, dotEnv, verr)
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func validatePorts(cfg *Config, verr *ValidationError) {
+	type namedPort struct {
+		name string
+		port int
+	}
+	ports := []namedPort{
+		{"NATS", cfg.NATSPort},
+		{"NATS_HTTP", cfg.NATSHTTPPort},
+		{"HTTP", cfg.HTTPPort},
+	}
+	if cfg.ClusterEnabled() {
+		ports = append(ports, namedPort{"CLUSTER", cfg.ClusterPort})
+	}
+
+	used := make(map[int]string)
+	for _, p := range ports {
+		if p.port < 1 || p.port > 65535 {
+			verr.add("invalid %s port: %d", p.name, p.port)
+			continue
+		}
+		if other, ok := used[p.port]; ok {
+			verr.add("ports must be unique: %s and %s both use %d", other, p.name, p.port)
+			continue
+		}
+		used[p.port] = p.name
+	}
+}
+
+// validateUsers проверяет пользователей security.users: имена уникальны, пароль задан
+func validateUsers(cfg *Config, verr *ValidationError) {
+	seen := map[string]bool{cfg.NATSUsername: cfg.NATSUsername != ""}
+	for i, u := range cfg.NATSUsers {
+		switch {
+		case strings.TrimSpace(u.Username) == "":
+			verr.add("security.users[%d]: username must not be empty", i)
+		case seen[u.Username]:
+			verr.add("security.users[%d]: duplicate username %s", i, u.Username)
+		}
+		seen[u.Username] = true
+		if !u.Password.IsSet() {
+			verr.add("security.users[%d]: password is required", i)
+		}
+	}
+}
+
+// validateStreams проверяет стримы jetstream.streams из файла конфигурации
+func validateStreams(cfg *Config, verr *ValidationError) {
+	if len(cfg.Streams) > 0 && !cfg.JetStreamEnabled {
+		verr.add("jetstream.streams are configured but JetStream is disabled")
+	}
+
+	seen := make(map[string]bool)
+	for i, sc := range cfg.Streams {
+		switch {
+		case sc.Name == "" || strings.ContainsAny(sc.Name, " .*>"):
+			verr.add("jetstream.streams[%d]: invalid stream name %q", i, sc.Name)
+		case seen[sc.Name]:
+			verr.add("jetstream.streams[%d]: duplicate stream %s", i, sc.Name)
+		}
+		seen[sc.Name] = true
+
+		if len(sc.Subjects) == 0 {
+			verr.add("stream %s: subjects must not be empty", sc.Name)
+		}
+		if !oneOf(sc.Storage, validStreamStorages) {
+			verr.add("stream %s: invalid storage %q, expected file/memory", sc.Name, sc.Storage)
+		}
+		if !oneOf(sc.Retention, validRetentions) {
+			verr.add("stream %s: invalid retention %q, expected limits/interest/workqueue", sc.Name, sc.Retention)
+		}
+		if sc.MaxAge < 0 || sc.MaxMsgs < 0 || sc.Replicas < 0 {
+			verr.add("stream %s: max_age, max_msgs and replicas must not be negative", sc.Name)
+		}
+	}
+}
+
+// validateCluster проверяет имя кластера и адреса маршрутов
+func validateCluster(cfg *Config, verr *ValidationError) {
+	if !cfg.ClusterEnabled() {
+		if len(cfg.ClusterRoutes) > 0 {
+			verr.add("CLUSTER_ROUTES are set but CLUSTER_PORT is 0")
+		}
+		return
+	}
+
+	if strings.TrimSpace(cfg.ClusterName) == "" {
+		verr.add("CLUSTER_NAME is required when CLUSTER_PORT is set")
+	}
+	for _, raw := range cfg.ClusterRoutes {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "nats-route" && u.Scheme != "nats") || u.Port() == "" {
+			verr.add("invalid cluster route %q, expected nats-route://host:port", raw)
+		}
+	}
+}
+
+// validateKnownKeys ищет в .env ключи, которых нет в конфигурации (например опечатки LOG_LIFE вместо LOG_FILE)
+func validateKnownKeys(cfg *Config, dotEnv map[string]string, verr *ValidationError) {
+	known := make(map[string]bool)
+	var keys []string
+	for _, f := range fields(cfg) {
+		known[f.key] = true
+		keys = append(keys, f.key)
+	}
+
+	fileKeys := secretFileKeys(cfg)
+
+	var unknown []string
+	for key := range dotEnv {
+		if !known[key] && !auxiliaryKeys[key] && !fileKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, key := range unknown {
+		if suggestion := closestKey(key, keys); suggestion != "" {
+			verr.add("unknown .env key %s (did you mean %s?)", key, suggestion)
+		} else {
+			verr.add("unknown .env key %s", key)
+		}
+	}
+}
+
+func closestKey(key string, candidates []string) string {
+	best, bestDistance := "", len(key)/3+1
+	for _, c := range candidates {
+		if d := levenshtein.Distance(key, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// checkWritableDir проверяет правами доступа, что в каталоге (или ближайшем существующем родителе)
+// можно создавать файлы. Сама проверка ничего не создает.
+func checkWritableDir(dir string) error {
+	for current := filepath.Clean(dir); ; current = filepath.Dir(current) {
+		info, err := os.Stat(current)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", current)
+			}
+			if err := checkDirAccess(current, info); err != nil {
+				return fmt.Errorf("directory %s is not writable: %w", current, err)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if parent := filepath.Dir(current); parent == current {
+			return fmt.Errorf("no existing parent directory for %s", dir)
+		}
+	}
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigCollectsAllProblems(t *testing.T) {
+	cfg, err := Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DataDir = t.TempDir()
+	cfg.Env = "prod"
+	cfg.NATSUsername = "scanner"
+	cfg.LogLevel = "verbose"
+	cfg.TracingSampleRatio = 2
+
+	err = validateConfig(cfg, map[string]string{"LOG_LEVLE": "info", "CONFIG_FILE": "", "NATS_PASSWORD_FILE": ""})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("validateConfig = %v, want *ValidationError", err)
+	}
+
+	want := []string{
+		`invalid env "prod"`,
+		"password is required when username is set",
+		`invalid log level "verbose"`,
+		"TRACING_SAMPLE_RATIO must be between 0 and 1",
+		"unknown .env key LOG_LEVLE (did you mean LOG_LEVEL?)",
+	}
+	if len(verr.Problems) != len(want) {
+		t.Fatalf("problems:\n%s", strings.Join(verr.Problems, "\n"))
+	}
+	for i, problem := range verr.Problems {
+		if !strings.Contains(problem, want[i]) {
+			t.Errorf("problem %d = %q, want %q", i, problem, want[i])
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "invalid configuration (5 problems):") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestValidatePorts(t *testing.T) {
+	tests := []struct {
+		name                 string
+		nats, natsHTTP, http int
+		want                 string
+	}{
+		{"valid", 4222, 8222, 8080, ""},
+		{"duplicate", 4222, 8222, 4222, "ports must be unique: NATS and HTTP both use 4222"},
+		{"zero", 0, 8222, 8080, "invalid NATS port: 0"},
+		{"too large", 4222, 70000, 8080, "invalid NATS_HTTP port: 70000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			cfg.NATSPort, cfg.NATSHTTPPort, cfg.HTTPPort = tt.nats, tt.natsHTTP, tt.http
+
+			verr := &ValidationError{}
+			validatePorts(cfg, verr)
+			got := strings.Join(verr.Problems, "; ")
+			if got != tt.want {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	keys := []string{"NATS_PORT", "NATS_HTTP_PORT", "HTTP_PORT", "LOG_LEVEL", "JETSTREAM_ENABLED"}
+	tests := map[string]string{
+		"NATS_PROT":        "NATS_PORT",
+		"HTTP_PROT":        "HTTP_PORT",
+		"LOGLEVEL":         "LOG_LEVEL",
+		"JETSTREAM_ENABLE": "JETSTREAM_ENABLED",
+		"NATS_HTTPPORT":    "NATS_HTTP_PORT",
+		"DATABASE_URL":     "",
+		"PORT":             "",
+	}
+	for key, want := range tests {
+		if got := closestKey(key, keys); got != want {
+			t.Errorf("closestKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestCheckWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := checkWritableDir(filepath.Join(dir, "missing", "nested")); err != nil {
+		t.Errorf("missing directory with writable parent: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("check left files behind: %v", entries)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWritableDir(file); err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Errorf("file instead of directory: %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	readOnly := filepath.Join(dir, "ro")
+	if err := os.Mkdir(readOnly, 0500); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWritableDir(readOnly); err == nil || !strings.Contains(err.Error(), "is not writable") {
+		t.Errorf("read-only directory: %v", err)
+	}
+}
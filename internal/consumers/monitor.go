@@ -0,0 +1,227 @@
+package consumers
+
+import (
+	"NATS_TIRE_SERVICE/internal/alerts"
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	MetricPending     = "num_pending"
+	MetricAckPending  = "num_ack_pending"
+	MetricRedelivered = "num_redelivered"
+)
+
+// Monitor периодически снимает состояние durable консюмеров всех стримов, поднимает алерты,
+// когда отставание превышает пороги, и применяет политику к неактивным консюмерам
+type Monitor struct {
+	cfg     *config.Config
+	logger  *utils.Logger
+	connect func(name string) (*nats.Conn, error)
+	alerts  *alerts.Manager
+
+	mu         sync.RWMutex
+	sampledAt  time.Time
+	samples    []domain.ConsumerLag
+	lastActive map[string]time.Time
+	stale      []domain.StaleConsumer
+
+	nc     *nats.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewMonitor(cfg *config.Config, logger *utils.Logger, connect func(name string) (*nats.Conn, error), manager *alerts.Manager) *Monitor {
+	return &Monitor{
+		cfg:        cfg,
+		logger:     logger.WithFields(zap.String("component", "consumer-monitor")),
+		connect:    connect,
+		alerts:     manager,
+		lastActive: make(map[string]time.Time),
+	}
+}
+
+func (m *Monitor) Start(context.Context) error {
+	nc, err := m.connect("consumer-monitor")
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.nc, m.cancel, m.done = nc, cancel, make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		m.run(ctx, js)
+	}()
+	return nil
+}
+
+func (m *Monitor) Stop(ctx context.Context) error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	m.nc.Close()
+	return nil
+}
+
+func (m *Monitor) run(ctx context.Context, js jetstream.JetStream) {
+	ticker := time.NewTicker(m.cfg.LagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx, js)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context, js jetstream.JetStream) {
+	checkCtx, cancel := context.WithTimeout(ctx, m.cfg.LagCheckInterval)
+	defer cancel()
+
+	samples, err := Sample(checkCtx, js)
+	if err != nil {
+		// без снимка нельзя отличить исправленное отставание от недоступного сервера,
+		// поэтому активные алерты не трогаем
+		if ctx.Err() == nil {
+			m.logger.Warn("failed to sample consumers", zap.Error(err))
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	m.mu.Lock()
+	m.samples, m.sampledAt = samples, now
+	m.trackActivity(samples, now)
+	m.mu.Unlock()
+
+	if m.cfg.LagMonitorEnabled {
+		m.alerts.Evaluate(ctx, domain.AlertSourceConsumerLag, Breaches(samples, m.cfg.LagMonitorSettings), now)
+	}
+	if m.cfg.StaleConsumersEnabled {
+		m.checkStale(checkCtx, js, now)
+	}
+}
+
+// Status - последний снимок консюмеров и активные алерты
+func (m *Monitor) Status() domain.ConsumerStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return domain.ConsumerStatus{
+		SampledAt: m.sampledAt,
+		Consumers: m.samples,
+		Alerts:    m.alerts.Active(),
+	}
+}
+
+// Sample собирает состояние durable консюмеров всех стримов. Эфемерные консюмеры (ordered,
+// временные подписки) пропускаются: у них нет постоянного имени для алертов.
+func Sample(ctx context.Context, js jetstream.JetStream) ([]domain.ConsumerLag, error) {
+	var samples []domain.ConsumerLag
+
+	streams := js.StreamNames(ctx)
+	for name := range streams.Name() {
+		stream, err := js.Stream(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stream %s: %w", name, err)
+		}
+
+		lister := stream.ListConsumers(ctx)
+		for info := range lister.Info() {
+			if info.Config.Durable == "" {
+				continue
+			}
+			samples = append(samples, consumerLag(info))
+		}
+		if err := lister.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list consumers of %s: %w", name, err)
+		}
+	}
+	if err := streams.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list streams: %w", err)
+	}
+
+	return samples, nil
+}
+
+func consumerLag(info *jetstream.ConsumerInfo) domain.ConsumerLag {
+	lag := domain.ConsumerLag{
+		Stream:         info.Stream,
+		Consumer:       info.Name,
+		NumPending:     info.NumPending,
+		NumAckPending:  info.NumAckPending,
+		NumRedelivered: info.NumRedelivered,
+		NumWaiting:     info.NumWaiting,
+		PushBound:      info.PushBound,
+		Created:        info.Created,
+	}
+
+	for _, t := range []*time.Time{info.Delivered.Last, info.AckFloor.Last} {
+		if t != nil && (lag.LastActive == nil || t.After(*lag.LastActive)) {
+			lag.LastActive = t
+		}
+	}
+	return lag
+}
+
+// Breaches возвращает нарушения порогов, по одному на метрику консюмера. Порог 0 отключает проверку.
+func Breaches(samples []domain.ConsumerLag, settings domain.LagMonitorSettings) []domain.Alert {
+	var result []domain.Alert
+
+	for _, s := range samples {
+		checks := []struct {
+			metric    string
+			value     float64
+			threshold float64
+		}{
+			{MetricPending, float64(s.NumPending), float64(settings.LagMaxPending)},
+			{MetricAckPending, float64(s.NumAckPending), float64(settings.LagMaxAckPending)},
+			{MetricRedelivered, float64(s.NumRedelivered), float64(settings.LagMaxRedelivered)},
+		}
+
+		for _, c := range checks {
+			if c.threshold <= 0 || c.value <= c.threshold {
+				continue
+			}
+			result = append(result, domain.Alert{
+				Fingerprint: fmt.Sprintf("%s/%s/%s", s.Stream, s.Consumer, c.metric),
+				Summary: fmt.Sprintf("consumer %s on stream %s: %s is %.0f, threshold %.0f",
+					s.Consumer, s.Stream, c.metric, c.value, c.threshold),
+				Stream:    s.Stream,
+				Consumer:  s.Consumer,
+				Metric:    c.metric,
+				Value:     c.value,
+				Threshold: c.threshold,
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package consumers
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"testing"
+)
+
+func TestBreaches(t *testing.T) {
+	settings := domain.LagMonitorSettings{LagMaxPending: 1000, LagMaxAckPending: 100, LagMaxRedelivered: 0}
+	samples := []domain.ConsumerLag{
+		{Stream: "EVENTS", Consumer: "monitoring", NumPending: 1500, NumAckPending: 100, NumRedelivered: 900},
+		{Stream: "EVENTS", Consumer: "forks", NumPending: 10, NumAckPending: 250},
+	}
+
+	got := Breaches(samples, settings)
+	want := []string{"EVENTS/monitoring/num_pending", "EVENTS/forks/num_ack_pending"}
+
+	if len(got) != len(want) {
+		t.Fatalf("breaches = %+v, want %v", got, want)
+	}
+	for i, fp := range want {
+		if got[i].Fingerprint != fp {
+			t.Errorf("breach %d = %s, want %s", i, got[i].Fingerprint, fp)
+		}
+	}
+	if got[0].Value != 1500 || got[0].Threshold != 1000 || got[0].Consumer != "monitoring" {
+		t.Errorf("breach = %+v", got[0])
+	}
+}
@@ -0,0 +1,169 @@
+package consumers
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"slices"
+	"sort"
+	"time"
+)
+
+func consumerKey(stream, consumer string) string {
+	return stream + "/" + consumer
+}
+
+// trackActivity обновляет время последней активности: доставка, подтверждение, а также
+// ожидающие pull запросы или push подписка - консюмер на тихом стриме простоем не считается.
+// Вызывается под m.mu.
+func (m *Monitor) trackActivity(samples []domain.ConsumerLag, now time.Time) {
+	present := make(map[string]bool, len(samples))
+
+	for _, s := range samples {
+		key := consumerKey(s.Stream, s.Consumer)
+		present[key] = true
+
+		active := s.Created
+		if s.LastActive != nil && s.LastActive.After(active) {
+			active = *s.LastActive
+		}
+		if s.NumWaiting > 0 || s.PushBound {
+			active = now
+		}
+		if active.After(m.lastActive[key]) {
+			m.lastActive[key] = active
+		}
+	}
+
+	for key := range m.lastActive {
+		if !present[key] {
+			delete(m.lastActive, key)
+		}
+	}
+}
+
+// StalePolicy - политика для консюмера с учетом настроек стрима и списка исключений
+func StalePolicy(settings domain.StaleConsumerSettings, stream, consumer string) string {
+	if slices.Contains(settings.StaleConsumerKeep, consumer) {
+		return domain.StalePolicyIgnore
+	}
+	if policy, ok := settings.StaleConsumerStreamPolicies[stream]; ok {
+		return policy
+	}
+	return settings.StaleConsumerPolicy
+}
+
+func (m *Monitor) checkStale(ctx context.Context, js jetstream.JetStream, now time.Time) {
+	settings := m.cfg.StaleConsumerSettings
+
+	m.mu.RLock()
+	var stale []domain.StaleConsumer
+	for _, s := range m.samples {
+		lastActive := m.lastActive[consumerKey(s.Stream, s.Consumer)]
+		if now.Sub(lastActive) < settings.StaleConsumerAfter {
+			continue
+		}
+		stale = append(stale, domain.StaleConsumer{
+			Stream:      s.Stream,
+			Consumer:    s.Consumer,
+			LastActive:  lastActive,
+			InactiveFor: now.Sub(lastActive).Round(time.Second).String(),
+			Policy:      StalePolicy(settings, s.Stream, s.Consumer),
+		})
+	}
+	m.mu.RUnlock()
+
+	var warnings []domain.Alert
+	for i := range stale {
+		c := &stale[i]
+		switch c.Policy {
+		case domain.StalePolicyWarn:
+			warnings = append(warnings, staleAlert(*c, now, settings.StaleConsumerAfter))
+		case domain.StalePolicyInactiveThreshold:
+			c.Action = m.setInactiveThreshold(ctx, js, *c, settings.StaleConsumerDeleteAfter)
+		case domain.StalePolicyDelete:
+			deleteAt := c.LastActive.Add(settings.StaleConsumerDeleteAfter)
+			c.DeleteAt = &deleteAt
+			if !now.Before(deleteAt) {
+				c.Action = m.deleteConsumer(ctx, js, *c)
+			}
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return consumerKey(stale[i].Stream, stale[i].Consumer) < consumerKey(stale[j].Stream, stale[j].Consumer)
+	})
+	m.mu.Lock()
+	m.stale = stale
+	m.mu.Unlock()
+
+	m.alerts.Evaluate(ctx, domain.AlertSourceStaleConsumer, warnings, now)
+}
+
+func staleAlert(c domain.StaleConsumer, now time.Time, after time.Duration) domain.Alert {
+	return domain.Alert{
+		Fingerprint: consumerKey(c.Stream, c.Consumer) + "/inactive",
+		Summary: fmt.Sprintf("consumer %s on stream %s has been inactive since %s",
+			c.Consumer, c.Stream, c.LastActive.Format(time.RFC3339)),
+		Stream:    c.Stream,
+		Consumer:  c.Consumer,
+		Metric:    "inactive_seconds",
+		Value:     now.Sub(c.LastActive).Seconds(),
+		Threshold: after.Seconds(),
+	}
+}
+
+// setInactiveThreshold передает удаление серверу: консюмер без активности дольше threshold
+// сервер удалит сам, а при возобновлении чтения отсчет начнется заново
+func (m *Monitor) setInactiveThreshold(ctx context.Context, js jetstream.JetStream, c domain.StaleConsumer, threshold time.Duration) string {
+	consumer, err := js.Consumer(ctx, c.Stream, c.Consumer)
+	if err != nil {
+		m.logger.Warn("failed to get stale consumer", zap.String("stream", c.Stream), zap.String("consumer", c.Consumer), zap.Error(err))
+		return ""
+	}
+
+	cfg := consumer.CachedInfo().Config
+	if cfg.InactiveThreshold == threshold {
+		return "inactive_threshold_set"
+	}
+	cfg.InactiveThreshold = threshold
+
+	if _, err := js.UpdateConsumer(ctx, c.Stream, cfg); err != nil {
+		m.logger.Warn("failed to set inactive threshold", zap.String("stream", c.Stream), zap.String("consumer", c.Consumer), zap.Error(err))
+		return ""
+	}
+	m.logger.Warn("inactive threshold set on stale consumer",
+		zap.String("stream", c.Stream),
+		zap.String("consumer", c.Consumer),
+		zap.Time("last_active", c.LastActive),
+		zap.Duration("threshold", threshold),
+	)
+	return "inactive_threshold_set"
+}
+
+func (m *Monitor) deleteConsumer(ctx context.Context, js jetstream.JetStream, c domain.StaleConsumer) string {
+	if err := js.DeleteConsumer(ctx, c.Stream, c.Consumer); err != nil {
+		m.logger.Warn("failed to delete stale consumer", zap.String("stream", c.Stream), zap.String("consumer", c.Consumer), zap.Error(err))
+		return ""
+	}
+	m.logger.Warn("stale consumer deleted",
+		zap.String("stream", c.Stream),
+		zap.String("consumer", c.Consumer),
+		zap.Time("last_active", c.LastActive),
+	)
+	return "deleted"
+}
+
+// Stale - неактивные консюмеры на последней проверке
+func (m *Monitor) Stale() domain.StaleConsumersResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return domain.StaleConsumersResponse{
+		CheckedAt:     m.sampledAt,
+		InactiveAfter: m.cfg.StaleConsumerAfter.String(),
+		Consumers:     m.stale,
+	}
+}
@@ -0,0 +1,61 @@
+package consumers
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestStalePolicy(t *testing.T) {
+	settings := domain.StaleConsumerSettings{
+		StaleConsumerPolicy:         domain.StalePolicyWarn,
+		StaleConsumerStreamPolicies: map[string]string{"EVENTS": domain.StalePolicyDelete},
+		StaleConsumerKeep:           []string{"arbitrage-consumer-events-match-monitoring"},
+	}
+
+	tests := []struct {
+		stream, consumer, want string
+	}{
+		{"EVENTS", "test-consumer", domain.StalePolicyDelete},
+		{"EVENTS", "arbitrage-consumer-events-match-monitoring", domain.StalePolicyIgnore},
+		{"ODDS", "test-consumer", domain.StalePolicyWarn},
+	}
+	for _, tt := range tests {
+		if got := StalePolicy(settings, tt.stream, tt.consumer); got != tt.want {
+			t.Errorf("StalePolicy(%s, %s) = %s, want %s", tt.stream, tt.consumer, got, tt.want)
+		}
+	}
+}
+
+func TestTrackActivity(t *testing.T) {
+	m := &Monitor{lastActive: make(map[string]time.Time)}
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	delivered := created.Add(time.Hour)
+	now := created.Add(48 * time.Hour)
+
+	m.trackActivity([]domain.ConsumerLag{
+		{Stream: "EVENTS", Consumer: "test-consumer", Created: created},
+		{Stream: "EVENTS", Consumer: "test-publisher", Created: created, LastActive: &delivered},
+		{Stream: "EVENTS", Consumer: "quiet-puller", Created: created, NumWaiting: 1},
+		{Stream: "EVENTS", Consumer: "gone", Created: created},
+	}, now)
+	m.trackActivity([]domain.ConsumerLag{
+		{Stream: "EVENTS", Consumer: "test-consumer", Created: created},
+		{Stream: "EVENTS", Consumer: "test-publisher", Created: created, LastActive: &delivered},
+		{Stream: "EVENTS", Consumer: "quiet-puller", Created: created},
+	}, now.Add(time.Minute))
+
+	want := map[string]time.Time{
+		"EVENTS/test-consumer":  created,
+		"EVENTS/test-publisher": delivered,
+		"EVENTS/quiet-puller":   now,
+	}
+	if len(m.lastActive) != len(want) {
+		t.Fatalf("lastActive = %v, want %v", m.lastActive, want)
+	}
+	for key, at := range want {
+		if !m.lastActive[key].Equal(at) {
+			t.Errorf("%s last active = %v, want %v", key, m.lastActive[key], at)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+
+	AlertSourceConsumerLag   = "consumer_lag"
+	AlertSourceStaleConsumer = "stale_consumer"
+)
+
+// Alert - тело запроса к вебхуку. Fingerprint одинаков для одного и того же нарушения,
+// по нему алерты дедуплицируются и закрываются.
+type Alert struct {
+	Fingerprint string     `json:"fingerprint"`
+	Status      string     `json:"status"`
+	Source      string     `json:"source"`
+	Service     string     `json:"service"`
+	Summary     string     `json:"summary"`
+	Stream      string     `json:"stream,omitempty"`
+	Consumer    string     `json:"consumer,omitempty"`
+	Metric      string     `json:"metric,omitempty"`
+	Value       float64    `json:"value"`
+	Threshold   float64    `json:"threshold"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+}
+
+// ConsumerLag - снимок состояния durable консюмера
+type ConsumerLag struct {
+	Stream         string     `json:"stream"`
+	Consumer       string     `json:"consumer"`
+	NumPending     uint64     `json:"num_pending"`
+	NumAckPending  int        `json:"num_ack_pending"`
+	NumRedelivered int        `json:"num_redelivered"`
+	NumWaiting     int        `json:"num_waiting"`
+	PushBound      bool       `json:"push_bound"`
+	Created        time.Time  `json:"created"`
+	LastActive     *time.Time `json:"last_active,omitempty"` //последняя доставка или подтверждение
+}
+
+type ConsumerStatus struct {
+	SampledAt time.Time     `json:"sampled_at"`
+	Consumers []ConsumerLag `json:"consumers"`
+	Alerts    []Alert       `json:"alerts"`
+}
+
+// Политики для неактивных durable консюмеров
+const (
+	StalePolicyWarn              = "warn"               //алерт и запись в лог
+	StalePolicyInactiveThreshold = "inactive_threshold" //сервер сам удалит консюмер через STALE_CONSUMER_DELETE_AFTER простоя
+	StalePolicyDelete            = "delete"             //сервис удаляет консюмер после STALE_CONSUMER_DELETE_AFTER простоя
+	StalePolicyIgnore            = "ignore"
+)
+
+type StaleConsumer struct {
+	Stream      string     `json:"stream"`
+	Consumer    string     `json:"consumer"`
+	LastActive  time.Time  `json:"last_active"`
+	InactiveFor string     `json:"inactive_for"`
+	Policy      string     `json:"policy"`
+	Action      string     `json:"action,omitempty"` //что сделано на последней проверке
+	DeleteAt    *time.Time `json:"delete_at,omitempty"`
+}
+
+type StaleConsumersResponse struct {
+	CheckedAt     time.Time       `json:"checked_at"`
+	InactiveAfter string          `json:"inactive_after"`
+	Consumers     []StaleConsumer `json:"consumers"`
+}
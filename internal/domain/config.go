@@ -1,34 +1,161 @@
 package domain
 
+import "time"
+
 type MainSettings struct {
-	AppName string `envconfig:"APP_NAME" default:"nats_tire_service"`
-	Version string `envconfig:"APP_VERSION"`
-	Env     string `envconfig:"ENV" default:"production"`
+	AppName string `envconfig:"APP_NAME" yaml:"name" default:"nats_tire_service"`
+	Version string `envconfig:"APP_VERSION" yaml:"version"`
+	Env     string `envconfig:"ENV" yaml:"env" default:"production"`
 }
 
 type NATSTireServer struct {
-	NATSPort     int    `envconfig:"NATS_PORT" default:"4222"`
-	NATSHTTPPort int    `envconfig:"NATS_HTTP_PORT" default:"8222"`
-	DataDir      string `envconfig:"DATA_DIR" default:"./data"`
+	NATSPort     int    `envconfig:"NATS_PORT" yaml:"port" default:"4222"`
+	NATSHTTPPort int    `envconfig:"NATS_HTTP_PORT" yaml:"http_port" default:"8222"`
+	DataDir      string `envconfig:"DATA_DIR" yaml:"data_dir" default:"./data"`
+	NATSNoListen bool   `envconfig:"NATS_NO_LISTEN" yaml:"no_listen"` //без TCP порта для клиентов, только in-process подключения
+
+	LameDuckDuration    time.Duration `envconfig:"LAME_DUCK_DURATION" yaml:"lame_duck_duration" default:"30s"` //0 - immediate shutdown
+	LameDuckGracePeriod time.Duration `envconfig:"LAME_DUCK_GRACE_PERIOD" yaml:"lame_duck_grace_period" default:"10s"`
+	ShutdownDrainDelay  time.Duration `envconfig:"SHUTDOWN_DRAIN_DELAY" yaml:"shutdown_drain_delay" default:"5s"`
 }
 
 type JetStreamSettings struct {
-	JetStreamEnabled bool   `envconfig:"JETSTREAM_ENABLED" default:"true"`
-	MaxMemoryStore   string `envconfig:"MAX_MEMORY_STORE" default:"1GB"`
-	MaxFileStore     string `envconfig:"MAX_FILE_STORE" default:"10GB"`
+	JetStreamEnabled bool     `envconfig:"JETSTREAM_ENABLED" yaml:"enabled" default:"true"`
+	MaxMemoryStore   ByteSize `envconfig:"MAX_MEMORY_STORE" yaml:"max_memory_store" default:"1GiB"` //-1 - без лимита
+	MaxFileStore     ByteSize `envconfig:"MAX_FILE_STORE" yaml:"max_file_store" default:"10GiB"`
+
+	Streams []StreamSettings `yaml:"streams"` //только в файле конфигурации, создаются или обновляются при старте
+}
+
+// StreamSettings - стрим JetStream из файла конфигурации
+type StreamSettings struct {
+	Name      string        `yaml:"name"`
+	Subjects  []string      `yaml:"subjects"`
+	Storage   string        `yaml:"storage"`   //file/memory, пусто - file
+	Retention string        `yaml:"retention"` //limits/interest/workqueue, пусто - limits
+	MaxAge    time.Duration `yaml:"max_age"`   //0 - без ограничения
+	MaxBytes  ByteSize      `yaml:"max_bytes"` //0 или -1 - без ограничения
+	MaxMsgs   int64         `yaml:"max_msgs"`  //0 - без ограничения
+	Replicas  int           `yaml:"replicas"`  //0 - 1
 }
 
 type NATSSecurity struct {
-	NATSUsername string `envconfig:"NATS_USERNAME"`
-	NATSPassword string `envconfig:"NATS_PASSWORD"`
+	NATSUsername string `envconfig:"NATS_USERNAME" yaml:"username"`
+	NATSPassword Secret `envconfig:"NATS_PASSWORD" yaml:"password"` //пароль или bcrypt хеш, также NATS_PASSWORD_FILE
+
+	NATSUsers []NATSUser `yaml:"users"` //только в файле конфигурации
+}
+
+// NATSUser - дополнительный пользователь сервера. Пустые Publish и Subscribe - без ограничений.
+type NATSUser struct {
+	Username     string   `yaml:"username"`
+	Password     Secret   `yaml:"password"`      //пароль или bcrypt хеш
+	PasswordFile string   `yaml:"password_file"` //файл с паролем вместо password
+	Publish      []string `yaml:"publish"`
+	Subscribe    []string `yaml:"subscribe"`
+}
+
+// ClusterSettings - кластер встроенных серверов. Имя сервера (APP_NAME-ENV) должно отличаться на каждом узле.
+type ClusterSettings struct {
+	ClusterName   string   `envconfig:"CLUSTER_NAME" yaml:"name"`
+	ClusterHost   string   `envconfig:"CLUSTER_HOST" yaml:"host" default:"0.0.0.0"`
+	ClusterPort   int      `envconfig:"CLUSTER_PORT" yaml:"port"`     //0 - без кластера
+	ClusterRoutes []string `envconfig:"CLUSTER_ROUTES" yaml:"routes"` //nats-route://host:port через запятую
 }
 
 type LoggerSettings struct {
-	LogLevel  string `envconfig:"LOG_LEVEL" default:"debug"`
-	LogFile   string `envconfig:"LOG_FILE"`
-	LogFormat string `envconfig:"LOG_FORMAT" default:"console"` //json/console
+	LogLevel  string `envconfig:"LOG_LEVEL" yaml:"level" default:"debug"`
+	LogFile   string `envconfig:"LOG_FILE" yaml:"file"`
+	LogFormat string `envconfig:"LOG_FORMAT" yaml:"format" default:"console"` //json/console
+
+	LogMaxSize        ByteSize      `envconfig:"LOG_MAX_SIZE" yaml:"max_size" default:"100MiB"`          //0 - без ротации по размеру
+	LogRotateInterval time.Duration `envconfig:"LOG_ROTATE_INTERVAL" yaml:"rotate_interval" default:"0"` //например 24h, 0 - без ротации по времени
+	LogMaxBackups     int           `envconfig:"LOG_MAX_BACKUPS" yaml:"max_backups" default:"10"`        //0 - хранить все
+	LogMaxAge         time.Duration `envconfig:"LOG_MAX_AGE" yaml:"max_age" default:"168h"`              //0 - без ограничения по возрасту
+	LogCompress       bool          `envconfig:"LOG_COMPRESS" yaml:"compress" default:"true"`            //gzip для старых файлов
+
+	NATSDebug bool `envconfig:"NATS_DEBUG" yaml:"nats_debug"` //debug логи встроенного сервера
+	NATSTrace bool `envconfig:"NATS_TRACE" yaml:"nats_trace"` //trace протокола встроенного сервера
+
+	NATSLogLevel            string        `envconfig:"NATS_LOG_LEVEL" yaml:"nats_level"`                                       //пусто - как LOG_LEVEL
+	NATSLogSampleInitial    int           `envconfig:"NATS_LOG_SAMPLE_INITIAL" yaml:"nats_sample_initial" default:"100"`       //первые N строк шаблона за интервал, 0 - без сэмплирования
+	NATSLogSampleThereafter int           `envconfig:"NATS_LOG_SAMPLE_THEREAFTER" yaml:"nats_sample_thereafter" default:"100"` //дальше каждая M-я строка
+	NATSLogSampleInterval   time.Duration `envconfig:"NATS_LOG_SAMPLE_INTERVAL" yaml:"nats_sample_interval" default:"1s"`
 }
 
 type HTTPServer struct {
-	HTTPPort int `envconfig:"HTTP_PORT" default:"8080"`
+	HTTPPort   int    `envconfig:"HTTP_PORT" yaml:"port" default:"8080"`
+	AdminToken Secret `envconfig:"ADMIN_TOKEN" yaml:"admin_token"` //пустой токен отключает /admin
+}
+
+type TracingSettings struct {
+	TracingEnabled     bool    `envconfig:"TRACING_ENABLED" yaml:"enabled"`
+	TracingExporter    string  `envconfig:"TRACING_EXPORTER" yaml:"exporter" default:"otlp"` //otlp/file
+	OTLPEndpoint       string  `envconfig:"OTLP_ENDPOINT" yaml:"otlp_endpoint" default:"localhost:4318"`
+	OTLPInsecure       bool    `envconfig:"OTLP_INSECURE" yaml:"otlp_insecure" default:"true"`
+	TracingFile        string  `envconfig:"TRACING_FILE" yaml:"file" default:"./logs/traces.json"` //для exporter=file
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" yaml:"sample_ratio" default:"1"`
+}
+
+type JourneySettings struct {
+	JourneyEnabled   bool   `envconfig:"JOURNEY_ENABLED" yaml:"enabled" default:"true"`
+	JourneyStream    string `envconfig:"JOURNEY_STREAM" yaml:"stream" default:"EVENTS"`
+	JourneyMaxEvents int    `envconfig:"JOURNEY_MAX_EVENTS" yaml:"max_events" default:"100000"` //старые события вытесняются
+}
+
+type LagMonitorSettings struct {
+	LagMonitorEnabled bool          `envconfig:"LAG_MONITOR_ENABLED" yaml:"enabled" default:"true"`
+	LagCheckInterval  time.Duration `envconfig:"LAG_CHECK_INTERVAL" yaml:"check_interval" default:"15s"`
+	LagMaxPending     uint64        `envconfig:"LAG_MAX_PENDING" yaml:"max_pending" default:"1000"` //0 - не проверять
+	LagMaxAckPending  int           `envconfig:"LAG_MAX_ACK_PENDING" yaml:"max_ack_pending" default:"100"`
+	LagMaxRedelivered int           `envconfig:"LAG_MAX_REDELIVERED" yaml:"max_redelivered" default:"50"`
+}
+
+type AlertSettings struct {
+	AlertWebhookURLs    []string      `envconfig:"ALERT_WEBHOOK_URLS" yaml:"webhook_urls"` //через запятую, пусто - только логи
+	AlertWebhookTimeout time.Duration `envconfig:"ALERT_WEBHOOK_TIMEOUT" yaml:"webhook_timeout" default:"5s"`
+	AlertRepeatInterval time.Duration `envconfig:"ALERT_REPEAT_INTERVAL" yaml:"repeat_interval" default:"1h"` //0 - не повторять активные алерты
+}
+
+// ForkDetectorSettings - встроенный поиск вилок по событиям мониторинга
+type ForkDetectorSettings struct {
+	ForkDetectorEnabled bool          `envconfig:"FORK_DETECTOR_ENABLED" yaml:"enabled" default:"false"`
+	ForkDetectorStream  string        `envconfig:"FORK_DETECTOR_STREAM" yaml:"stream" default:"EVENTS"`
+	ForkDetectorSubject string        `envconfig:"FORK_DETECTOR_SUBJECT" yaml:"subject" default:"events.match.monitoring"` //events.match.monitoring.filtered - после фильтра
+	ForkMinProfit       float64       `envconfig:"FORK_MIN_PROFIT" yaml:"min_profit" default:"0.5"`                        //в процентах
+	ForkMaxProfit       float64       `envconfig:"FORK_MAX_PROFIT" yaml:"max_profit" default:"15"`                         //больше - скорее ошибка в коэффициентах, 0 - без ограничения
+	ForkOddsMaxAge      time.Duration `envconfig:"FORK_ODDS_MAX_AGE" yaml:"odds_max_age" default:"30s"`                    //более старые коэффициенты не участвуют
+	ForkMaxLegSkew      time.Duration `envconfig:"FORK_MAX_LEG_SKEW" yaml:"max_leg_skew" default:"10s"`                    //разница во времени коэффициентов двух плеч, 0 - не проверять
+	ForkRepublishDelta  float64       `envconfig:"FORK_REPUBLISH_DELTA" yaml:"republish_delta" default:"0.25"`             //изменение прибыли в п.п. для повторной публикации
+}
+
+// OddsFilterSettings - фильтр устаревших и мигающих коэффициентов перед поиском вилок
+type OddsFilterSettings struct {
+	OddsFilterEnabled     bool          `envconfig:"ODDS_FILTER_ENABLED" yaml:"enabled" default:"false"`
+	OddsFilterStream      string        `envconfig:"ODDS_FILTER_STREAM" yaml:"stream" default:"EVENTS"`
+	OddsFilterDurable     string        `envconfig:"ODDS_FILTER_DURABLE" yaml:"durable" default:"odds-filter"`
+	OddsFilterMode        string        `envconfig:"ODDS_FILTER_MODE" yaml:"mode" default:"suppress"` //suppress - убрать ненадежные ставки, annotate - только пометить
+	OddsTTL               time.Duration `envconfig:"ODDS_TTL" yaml:"ttl" default:"60s"`               //событие с коэффициентом старше - устаревший
+	OddsFlickerWindow     time.Duration `envconfig:"ODDS_FLICKER_WINDOW" yaml:"flicker_window" default:"10s"`
+	OddsFlickerMaxChanges int           `envconfig:"ODDS_FLICKER_MAX_CHANGES" yaml:"flicker_max_changes" default:"3"` //больше изменений за окно - мигающий
+}
+
+// NormalizeSettings - нормализация названий команд и рынков по словарю алиасов в KV
+type NormalizeSettings struct {
+	NormalizeEnabled       bool    `envconfig:"NORMALIZE_ENABLED" yaml:"enabled" default:"false"`
+	NormalizeBucket        string  `envconfig:"NORMALIZE_BUCKET" yaml:"bucket" default:"aliases"`
+	NormalizeMinConfidence float64 `envconfig:"NORMALIZE_MIN_CONFIDENCE" yaml:"min_confidence" default:"0.8"` //ниже - название остается как есть
+	NormalizeRepublish     bool    `envconfig:"NORMALIZE_REPUBLISH" yaml:"republish" default:"false"`         //публиковать нормализованные события в NORMALIZE_SUBJECT_PREFIX
+	NormalizeStream        string  `envconfig:"NORMALIZE_STREAM" yaml:"stream" default:"EVENTS"`
+	NormalizeDurable       string  `envconfig:"NORMALIZE_DURABLE" yaml:"durable" default:"normalizer"`
+	NormalizeSubjectPrefix string  `envconfig:"NORMALIZE_SUBJECT_PREFIX" yaml:"subject_prefix" default:"events.normalized"` //events.match.monitoring -> events.normalized.match.monitoring
+}
+
+type StaleConsumerSettings struct {
+	StaleConsumersEnabled       bool              `envconfig:"STALE_CONSUMERS_ENABLED" yaml:"enabled" default:"true"`
+	StaleConsumerAfter          time.Duration     `envconfig:"STALE_CONSUMER_AFTER" yaml:"inactive_after" default:"24h"` //без доставок и подтверждений дольше - неактивный
+	StaleConsumerPolicy         string            `envconfig:"STALE_CONSUMER_POLICY" yaml:"policy" default:"warn"`       //warn/inactive_threshold/delete/ignore
+	StaleConsumerDeleteAfter    time.Duration     `envconfig:"STALE_CONSUMER_DELETE_AFTER" yaml:"delete_after" default:"168h"`
+	StaleConsumerStreamPolicies map[string]string `envconfig:"STALE_CONSUMER_STREAM_POLICIES" yaml:"stream_policies"` //STREAM:policy через запятую
+	StaleConsumerKeep           []string          `envconfig:"STALE_CONSUMER_KEEP" yaml:"keep"`                       //консюмеры, которые политика не трогает
 }
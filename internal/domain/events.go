@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	StreamEvents = "EVENTS"
+
+	SubjectBundleMatch             = "events.bundle.match"
+	SubjectMatchMonitoring         = "events.match.monitoring"
+	SubjectMatchMonitoringFiltered = "events.match.monitoring.filtered"
+	SubjectMatchFound              = "events.match.found"
+	SubjectForkFound               = "events.fork.found"
+)
+
+// EventHeader - заголовок события, который добавляет NATS_TIRE_LIBRARY
+type EventHeader struct {
+	EventID       string    `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	Timestamp     time.Time `json:"timestamp"`
+	Source        string    `json:"source"`
+	Version       string    `json:"version"`
+	CorrelationID int64     `json:"correlation_id"`
+}
+
+// EventEnvelope - общая обертка событий стрима EVENTS. Payload разбирается отдельно по subject.
+// Сообщения мониторинга от старых издателей приходят без event_header.
+type EventEnvelope struct {
+	Header  *EventHeader    `json:"event_header,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// payloadCorrelation - correlation id из payload: в событиях библиотеки поле CorrelationID,
+// в мониторинге - correlation_id
+type payloadCorrelation struct {
+	CamelCase *int64 `json:"CorrelationID"`
+	SnakeCase *int64 `json:"correlation_id"`
+}
+
+// payloadTime - время события из payload (Timestamp у форков, timestamp у мониторинга)
+type payloadTime struct {
+	CamelCase *time.Time `json:"Timestamp"`
+	SnakeCase *time.Time `json:"timestamp"`
+}
+
+func ParseEnvelope(data []byte) (EventEnvelope, error) {
+	var env EventEnvelope
+	err := json.Unmarshal(data, &env)
+	return env, err
+}
+
+// CorrelationID берет correlation id из заголовка, а если там 0 (библиотека его не заполняет) - из payload
+func (e EventEnvelope) CorrelationID() (int64, bool) {
+	if e.Header != nil && e.Header.CorrelationID != 0 {
+		return e.Header.CorrelationID, true
+	}
+	if len(e.Payload) == 0 {
+		return 0, false
+	}
+
+	var p payloadCorrelation
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return 0, false
+	}
+	switch {
+	case p.CamelCase != nil:
+		return *p.CamelCase, true
+	case p.SnakeCase != nil:
+		return *p.SnakeCase, true
+	}
+	return 0, false
+}
+
+// EventTime - время события из заголовка или payload
+func (e EventEnvelope) EventTime() (time.Time, bool) {
+	if e.Header != nil && !e.Header.Timestamp.IsZero() {
+		return e.Header.Timestamp, true
+	}
+	if len(e.Payload) == 0 {
+		return time.Time{}, false
+	}
+
+	var p payloadTime
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return time.Time{}, false
+	}
+	switch {
+	case p.CamelCase != nil && !p.CamelCase.IsZero():
+		return *p.CamelCase, true
+	case p.SnakeCase != nil && !p.SnakeCase.IsZero():
+		return *p.SnakeCase, true
+	}
+	return time.Time{}, false
+}
+
+const (
+	EventTypeMatchBundle     = "match.bundle"
+	EventTypeMatchMonitoring = "match.monitoring"
+	EventTypeForkFound       = "fork.found"
+
+	EventVersion = "1.0.0"
+)
+
+// SubjectByEventType - subject, в который публикуется событие данного типа
+func SubjectByEventType(eventType string) (string, bool) {
+	switch eventType {
+	case EventTypeMatchBundle:
+		return SubjectBundleMatch, true
+	case EventTypeMatchMonitoring:
+		return SubjectMatchMonitoring, true
+	case EventTypeForkFound:
+		return SubjectForkFound, true
+	}
+	return "", false
+}
+
+// MatchBundle - payload events.bundle.match. Поля без json тегов, как в NATS_TIRE_LIBRARY.
+type MatchBundle struct {
+	CorrelationID   int64
+	TeamNames       []string
+	BookmakerBundle map[string]string
+}
+
+type MonitoringBet struct {
+	BetMarket string  `json:"bet_market"`
+	TargetBet string  `json:"target_bet"`
+	Less      float64 `json:"less"`
+	More      float64 `json:"more"`
+}
+
+// MatchMonitoring - payload events.match.monitoring, коэффициенты по букмекерам
+type MatchMonitoring struct {
+	CorrelationID   int64                      `json:"correlation_id"`
+	SportType       string                     `json:"sport_type"`
+	TeamNames       []string                   `json:"team_names"`
+	BookmakerBundle map[string]string          `json:"bookmaker_bundle"`
+	Bets            map[string][]MonitoringBet `json:"bets"`
+	Timestamp       time.Time                  `json:"timestamp"`
+}
+
+// ForkLeg - ставка вилки у одного букмекера
+type ForkLeg struct {
+	Bookmaker string
+	TargetBet string
+	Odds      float64
+	Stake     float64 //доля банка
+}
+
+// ForkFound - payload events.fork.found
+type ForkFound struct {
+	CorrelationID int64
+	TeamNames     []string
+	BetMarket     string
+	Profit        float64 //гарантированная прибыль в процентах
+	Legs          []ForkLeg
+	Timestamp     time.Time
+}
+
+// Event - событие для публикации: заголовок в формате библиотеки и типизированный payload
+type Event struct {
+	Header  *EventHeader `json:"event_header,omitempty"`
+	Payload interface{}  `json:"payload"`
+}
+
+func NewEvent(eventType, source string, correlationID int64, payload interface{}) Event {
+	return Event{
+		Header: &EventHeader{
+			EventID:       uuid.NewString(),
+			EventType:     eventType,
+			Timestamp:     time.Now().UTC(),
+			Source:        source,
+			Version:       EventVersion,
+			CorrelationID: correlationID,
+		},
+		Payload: payload,
+	}
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "testing"
+
+func TestEnvelopeCorrelationID(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int64
+		ok   bool
+	}{
+		{
+			name: "bundle with empty header correlation",
+			data: `{"event_header":{"event_id":"e1","event_type":"match.bundle","correlation_id":0},"payload":{"CorrelationID":42,"TeamNames":["NaVi","G2"]}}`,
+			want: 42, ok: true,
+		},
+		{
+			name: "header correlation wins",
+			data: `{"event_header":{"event_type":"fork.found","correlation_id":7},"payload":{"CorrelationID":42}}`,
+			want: 7, ok: true,
+		},
+		{
+			name: "monitoring without header",
+			data: `{"payload":{"correlation_id":999,"sport_type":"counter-strike"}}`,
+			want: 999, ok: true,
+		},
+		{
+			name: "no correlation",
+			data: `{"i":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		env, err := ParseEnvelope([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, ok := env.CorrelationID()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: CorrelationID() = %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// ArbitrageFork - найденная вилка на двухисходный рынок: less у одного букмекера и more у другого
+type ArbitrageFork struct {
+	CorrelationID int64     `json:"correlation_id"`
+	TeamNames     []string  `json:"team_names"`
+	BetMarket     string    `json:"bet_market"`
+	TargetBet     string    `json:"target_bet"`
+	LessBookmaker string    `json:"less_bookmaker"`
+	Less          float64   `json:"less"`
+	MoreBookmaker string    `json:"more_bookmaker"`
+	More          float64   `json:"more"`
+	Margin        float64   `json:"margin"` //1/less + 1/more, меньше 1 - вилка
+	Profit        float64   `json:"profit"` //гарантированная прибыль в процентах
+	OddsAt        time.Time `json:"odds_at"`
+	PublishedAt   time.Time `json:"published_at"`
+}
+
+// ForksResponse - ответ GET /forks
+type ForksResponse struct {
+	Ready     bool            `json:"ready"` //стрим дочитан до текущего момента
+	Matches   int             `json:"matches"`
+	Published uint64          `json:"published"`
+	Forks     []ArbitrageFork `json:"forks"`
+}
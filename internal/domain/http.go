@@ -9,3 +9,19 @@ type HealthResponse struct {
 	Version   string    `json:"version"`
 	Uptime    string    `json:"uptime,omitempty"`
 }
+
+type LogLevelRequest struct {
+	Level       *string `json:"level,omitempty"`
+	ServerDebug *bool   `json:"server_debug,omitempty"`
+	ServerTrace *bool   `json:"server_trace,omitempty"`
+}
+
+type LogLevelResponse struct {
+	Level       string `json:"level"`
+	ServerDebug bool   `json:"server_debug"`
+	ServerTrace bool   `json:"server_trace"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
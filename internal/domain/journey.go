@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Этапы пути матча по subject события
+const (
+	StageBundleFound      = "bundle_found"
+	StageMonitoringUpdate = "monitoring_update"
+	StageOddsFiltered     = "odds_filtered"
+	StageMatchFound       = "match_found"
+	StageForkFound        = "fork_found"
+)
+
+// StageBySubject - этап пути матча для subject события, для неизвестных subject возвращается сам subject
+func StageBySubject(subject string) string {
+	switch subject {
+	case SubjectBundleMatch:
+		return StageBundleFound
+	case SubjectMatchMonitoring:
+		return StageMonitoringUpdate
+	case SubjectMatchMonitoringFiltered:
+		return StageOddsFiltered
+	case SubjectMatchFound:
+		return StageMatchFound
+	case SubjectForkFound:
+		return StageForkFound
+	}
+	return subject
+}
+
+// JourneyEvent - одно событие в истории correlation id
+type JourneyEvent struct {
+	Seq       uint64          `json:"seq"`
+	Subject   string          `json:"subject"`
+	Stage     string          `json:"stage"`
+	EventID   string          `json:"event_id,omitempty"`
+	EventType string          `json:"event_type,omitempty"`
+	Source    string          `json:"source,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	StoredAt  time.Time       `json:"stored_at"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+type JourneyResponse struct {
+	CorrelationID     int64          `json:"correlation_id"`
+	FirstSeen         time.Time      `json:"first_seen"`
+	LastSeen          time.Time      `json:"last_seen"`
+	Duration          string         `json:"duration"`
+	MonitoringUpdates int            `json:"monitoring_updates"`
+	ForkFound         bool           `json:"fork_found"`
+	Events            []JourneyEvent `json:"events"`
+}
+
+type JourneyStats struct {
+	Ready          bool   `json:"ready"` //история стрима прочитана до конца
+	CorrelationIDs int    `json:"correlation_ids"`
+	Events         int    `json:"events"`
+	Evicted        uint64 `json:"evicted"`
+	LastSeq        uint64 `json:"last_seq"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// Виды названий в словаре алиасов
+const (
+	AliasKindTeam   = "team"
+	AliasKindMarket = "market"
+)
+
+// AliasEntry - каноническое название и его варианты у букмекеров, значение в KV бакете алиасов
+type AliasEntry struct {
+	Kind      string    `json:"kind"`
+	Canonical string    `json:"canonical"`
+	Aliases   []string  `json:"aliases"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NameMatch - результат нормализации одного названия
+type NameMatch struct {
+	Kind       string  `json:"kind"`
+	Raw        string  `json:"raw"`
+	Canonical  string  `json:"canonical,omitempty"` //пусто - совпадение не найдено
+	Confidence float64 `json:"confidence"`          //1 - точное совпадение с алиасом
+}
+
+// NormalizedMonitoring - payload events.normalized.match.monitoring.
+// Normalization содержит названия, которые не совпали с алиасом точно.
+type NormalizedMonitoring struct {
+	MatchMonitoring
+	Normalization []NameMatch `json:"normalization,omitempty"`
+}
+
+// NormalizedBundle - payload events.normalized.bundle.match
+type NormalizedBundle struct {
+	MatchBundle
+	Normalization []NameMatch `json:"normalization,omitempty"`
+}
+
+// UnmatchedName - название без алиаса, кандидат для словаря
+type UnmatchedName struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+// AliasesResponse - ответ GET /admin/aliases
+type AliasesResponse struct {
+	Bucket    string          `json:"bucket"`
+	Entries   []AliasEntry    `json:"entries"`
+	Unmatched []UnmatchedName `json:"unmatched"`
+	Processed uint64          `json:"processed"`
+	Published uint64          `json:"published"`
+}
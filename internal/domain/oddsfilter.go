@@ -0,0 +1,47 @@
+package domain
+
+// Режимы фильтра коэффициентов
+const (
+	OddsFilterSuppress = "suppress"
+	OddsFilterAnnotate = "annotate"
+)
+
+// Причины, по которым коэффициент признан ненадежным
+const (
+	OddsStale   = "stale"
+	OddsFlicker = "flicker"
+)
+
+// OddsIssue - ненадежный коэффициент одного букмекера на рынке
+type OddsIssue struct {
+	Bookmaker  string  `json:"bookmaker"`
+	BetMarket  string  `json:"bet_market"`
+	TargetBet  string  `json:"target_bet"`
+	Reason     string  `json:"reason"`
+	AgeSeconds float64 `json:"age_seconds"` //сколько прошло с последнего подтверждения коэффициента
+	Changes    int     `json:"changes"`     //изменений за окно ODDS_FLICKER_WINDOW
+	Suppressed bool    `json:"suppressed"`  //ставка удалена из bets
+}
+
+// FilteredMonitoring - payload events.match.monitoring.filtered: мониторинг после фильтра коэффициентов
+type FilteredMonitoring struct {
+	MatchMonitoring
+	OddsIssues []OddsIssue `json:"odds_issues,omitempty"`
+}
+
+// BookmakerOddsStats - счетчики фильтра по букмекеру
+type BookmakerOddsStats struct {
+	Checked    uint64 `json:"checked"`
+	Stale      uint64 `json:"stale"`
+	Flicker    uint64 `json:"flicker"`
+	Suppressed uint64 `json:"suppressed"`
+}
+
+// OddsFilterResponse - ответ GET /odds-filter
+type OddsFilterResponse struct {
+	Mode       string                        `json:"mode"`
+	Processed  uint64                        `json:"processed"`
+	Published  uint64                        `json:"published"`
+	Tracked    int                           `json:"tracked"` //отслеживаемые коэффициенты
+	Bookmakers map[string]BookmakerOddsStats `json:"bookmakers"`
+}
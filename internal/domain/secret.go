@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"regexp"
+)
+
+var bcryptPrefix = regexp.MustCompile(`^\$2[abxy]?\$\d{2}\$`)
+
+// Secret - чувствительное значение (пароли, токены, ключи). При форматировании и сериализации
+// всегда маскируется, исходное значение доступно только через Value.
+// Для каждого поля этого типа конфигурация поддерживает вариант <KEY>_FILE.
+type Secret string
+
+const redactedSecret = "******"
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) IsSet() bool {
+	return s != ""
+}
+
+// IsBcrypt сообщает, что значение является bcrypt хешем, а не паролем в открытом виде
+func (s Secret) IsBcrypt() bool {
+	return bcryptPrefix.MatchString(string(s))
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
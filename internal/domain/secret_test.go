@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	s := Secret("hunter2")
+	settings := struct {
+		User     string
+		Password Secret `json:"password" yaml:"password"`
+	}{"scanner", s}
+
+	formatted := []string{
+		s.String(),
+		fmt.Sprint(s),
+		fmt.Sprintf("%v %s %q", s, s, s),
+		fmt.Sprintf("%+v", settings),
+		fmt.Sprintf("%#v", settings),
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted = append(formatted, string(data))
+	data, err = yaml.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted = append(formatted, string(data))
+
+	for _, out := range formatted {
+		if strings.Contains(out, "hunter2") || !strings.Contains(out, redactedSecret) {
+			t.Errorf("secret is not redacted: %s", out)
+		}
+	}
+	if s.Value() != "hunter2" {
+		t.Errorf("Value() = %q", s.Value())
+	}
+
+	// пустой секрет выглядит пустым, чтобы было видно, что он не задан
+	if Secret("").String() != "" || Secret("").IsSet() {
+		t.Error("empty secret is reported as set")
+	}
+}
+
+func TestSecretIsBcrypt(t *testing.T) {
+	tests := map[string]bool{
+		"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy": true,
+		"$2y$12$abc": true,
+		"$2$04$abc":  true,
+		"plain":      false,
+		"$1$abc":     false,
+		"x$2a$10$ab": false,
+	}
+	for value, want := range tests {
+		if got := Secret(value).IsBcrypt(); got != want {
+			t.Errorf("IsBcrypt(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
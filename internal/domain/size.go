@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ByteSize - размер в байтах для полей конфигурации. -1 означает отсутствие лимита.
+type ByteSize int64
+
+const Unlimited ByteSize = -1
+
+const (
+	KB ByteSize = 1000
+	MB          = KB * 1000
+	GB          = MB * 1000
+	TB          = GB * 1000
+	PB          = TB * 1000
+
+	KiB ByteSize = 1024
+	MiB          = KiB * 1024
+	GiB          = MiB * 1024
+	TiB          = GiB * 1024
+	PiB          = TiB * 1024
+)
+
+// единицы от больших к меньшим: String выбирает первую, на которую размер делится без остатка
+var sizeUnits = []struct {
+	name  string
+	value ByteSize
+}{
+	{"PiB", PiB}, {"PB", PB},
+	{"TiB", TiB}, {"TB", TB},
+	{"GiB", GiB}, {"GB", GB},
+	{"MiB", MiB}, {"MB", MB},
+	{"KiB", KiB}, {"KB", KB},
+}
+
+var sizeSuffixes = map[string]ByteSize{
+	"":  1,
+	"B": 1,
+	"K": KB, "KB": KB, "KI": KiB, "KIB": KiB,
+	"M": MB, "MB": MB, "MI": MiB, "MIB": MiB,
+	"G": GB, "GB": GB, "GI": GiB, "GIB": GiB,
+	"T": TB, "TB": TB, "TI": TiB, "TIB": TiB,
+	"P": PB, "PB": PB, "PI": PiB, "PIB": PiB,
+}
+
+// ParseByteSize разбирает размеры вида "10GB", "512M", "1.5GiB", "10 GiB", "-1".
+// Десятичные единицы (KB, MB, ...) кратны 1000, двоичные (KiB, MiB, ...) - 1024, так что "1GB" - это 10⁹ байт.
+// Дробная часть считается точно и округляется до целого байта.
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.TrimSpace(s)
+	if value == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+	if value == "-1" || strings.EqualFold(value, "unlimited") {
+		return Unlimited, nil
+	}
+	if strings.HasPrefix(value, "-") {
+		return 0, fmt.Errorf("invalid size %q: negative size, use -1 for unlimited", s)
+	}
+
+	idx := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, suffix := value, ""
+	if idx >= 0 {
+		number, suffix = value[:idx], strings.TrimSpace(value[idx:])
+	}
+
+	multiplier, ok := sizeSuffixes[strings.ToUpper(suffix)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, suffix)
+	}
+
+	whole, frac, _ := strings.Cut(number, ".")
+	if whole == "" && frac == "" || strings.Contains(frac, ".") {
+		return 0, fmt.Errorf("invalid size %q: expected number with optional unit", s)
+	}
+
+	// whole.frac * multiplier = (whole * 10^len(frac) + frac) * multiplier / 10^len(frac)
+	n, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: expected number with optional unit", s)
+	}
+	n.Mul(n, big.NewInt(int64(multiplier)))
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(frac))), nil)
+	n.Add(n, new(big.Int).Rsh(scale, 1)) // округление половины вверх
+	n.Quo(n, scale)
+
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("invalid size %q: value is too large", s)
+	}
+	return ByteSize(n.Int64()), nil
+}
+
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
+
+func (b ByteSize) IsUnlimited() bool {
+	return b == Unlimited
+}
+
+// String возвращает человекочитаемое значение, которое ParseByteSize разбирает обратно в то же число
+func (b ByteSize) String() string {
+	switch {
+	case b == Unlimited:
+		return "unlimited"
+	case b == 0:
+		return "0B"
+	}
+
+	for _, u := range sizeUnits {
+		if b%u.value == 0 {
+			return fmt.Sprintf("%d%s", b/u.value, u.name)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(b))
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ByteSize
+		err  string
+	}{
+		{in: "0", want: 0},
+		{in: "512", want: 512},
+		{in: "512B", want: 512},
+		{in: "1KB", want: 1000},
+		{in: "1k", want: 1000},
+		{in: "1KiB", want: 1024},
+		{in: "1ki", want: 1024},
+		{in: "1GB", want: 1_000_000_000},
+		{in: "1GiB", want: 1 << 30},
+		{in: "10 GiB", want: 10 << 30},
+		{in: " 100MiB ", want: 100 << 20},
+		{in: "2PB", want: 2 * PB},
+
+		{in: "4.1MB", want: 4_100_000},
+		{in: "1.5GiB", want: 3 << 29},
+		{in: "0.001KB", want: 1},
+		{in: ".5KiB", want: 512},
+		{in: "5.KB", want: 5000},
+		{in: "0.1234567890123456789GB", want: 123_456_789},
+		{in: "1.5", want: 2},
+		{in: "0.4", want: 0},
+		{in: "1.0005KB", want: 1001},
+
+		{in: "9223372036854775807", want: math.MaxInt64},
+		{in: "9223372036854775807B", want: math.MaxInt64},
+		{in: "8191PiB", want: 8191 * PiB},
+		{in: "9223372036854775808", err: "too large"},
+		{in: "8192PiB", err: "too large"},
+		{in: "99999999999999999999999KB", err: "too large"},
+
+		{in: "-1", want: Unlimited},
+		{in: "unlimited", want: Unlimited},
+		{in: "Unlimited", want: Unlimited},
+		{in: "-2", err: "negative size"},
+		{in: "-1GB", err: "negative size"},
+
+		{in: "", err: "empty size"},
+		{in: "GB", err: "expected number"},
+		{in: ".", err: "expected number"},
+		{in: "1.2.3MB", err: "expected number"},
+		{in: "1e3", err: "unknown unit"},
+		{in: "10 gigabytes", err: "unknown unit"},
+		{in: "1XB", err: "unknown unit"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseByteSize(%q) = %d, %v; want error %q", tt.in, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestByteSizeStringRoundTrip(t *testing.T) {
+	for _, size := range []ByteSize{0, 1, 1000, 1024, 1536, 4_100_000, GB, 3 * GiB, 5 * PiB, math.MaxInt64, Unlimited} {
+		parsed, err := ParseByteSize(size.String())
+		if err != nil || parsed != size {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", size.String(), parsed, err, size)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package fixtures
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"text/template"
+	"time"
+)
+
+// Message - готовое к публикации событие
+type Message struct {
+	Subject       string
+	EventType     string
+	CorrelationID int64
+	// Delay - пауза после публикации
+	Delay time.Duration
+
+	event domain.Event
+	// stamp - время в payload не задано в фикстуре и ставится при публикации, как и в заголовке
+	stamp func(now time.Time)
+}
+
+// Encode сериализует событие со временем публикации, чтобы задержки между событиями были видны в их времени
+func (m Message) Encode(now time.Time) ([]byte, error) {
+	m.event.Header.Timestamp = now.UTC()
+	if m.stamp != nil {
+		m.stamp(now.UTC())
+	}
+	return json.Marshal(m.event)
+}
+
+// Builder превращает записи фикстур в события с заголовком. Correlation id выдаются
+// подряд начиная с first: по одному на каждый ключ match в проходе и на каждое событие без ключа.
+type Builder struct {
+	source string
+	nextID int64
+	funcs  template.FuncMap
+}
+
+func NewBuilder(source string, first int64, seed uint64) *Builder {
+	rnd := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	return &Builder{source: source, nextID: first, funcs: funcs(rnd)}
+}
+
+// Build собирает события одного прохода по фикстурам. Каждый проход получает новые correlation id.
+func (b *Builder) Build(entries []Entry, iteration int) ([]Message, error) {
+	matches := make(map[string]int64)
+	var msgs []Message
+
+	for _, e := range entries {
+		subject, ok := domain.SubjectByEventType(e.EventType)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported event_type", e)
+		}
+
+		for r := 0; r < max(e.Repeat, 1); r++ {
+			id := e.CorrelationID
+			switch {
+			case id != 0:
+			case e.Match != "":
+				if id, ok = matches[e.Match]; !ok {
+					id = b.allocate()
+					matches[e.Match] = id
+				}
+			default:
+				id = b.allocate()
+			}
+
+			data := templateData{CorrelationID: id, Match: e.Match, Iteration: iteration, Repeat: r}
+			rendered, err := render(e.Payload, b.funcs, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e, err)
+			}
+
+			payload, stamp, err := typedPayload(e.EventType, rendered, id)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e, err)
+			}
+
+			source := b.source
+			if e.Source != "" {
+				source = e.Source
+			}
+			msgs = append(msgs, Message{
+				Subject:       subject,
+				EventType:     e.EventType,
+				CorrelationID: id,
+				Delay:         e.delay,
+				event:         domain.NewEvent(e.EventType, source, id, payload),
+				stamp:         stamp,
+			})
+		}
+	}
+	return msgs, nil
+}
+
+func (b *Builder) allocate() int64 {
+	id := b.nextID
+	b.nextID++
+	return id
+}
+
+// typedPayload строго разбирает payload в тип события, чтобы фикстура не разошлась с форматом
+// библиотеки: лишнее или опечатанное поле - ошибка. Пустой correlation id заполняется,
+// для пустого времени возвращается stamp, который поставит время публикации.
+func typedPayload(eventType string, rendered interface{}, id int64) (interface{}, func(time.Time), error) {
+	raw, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, nil, fmt.Errorf("payload: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	switch eventType {
+	case domain.EventTypeMatchBundle:
+		var p domain.MatchBundle
+		if err := dec.Decode(&p); err != nil {
+			return nil, nil, fmt.Errorf("payload does not match MatchBundle: %w", err)
+		}
+		if p.CorrelationID == 0 {
+			p.CorrelationID = id
+		}
+		return p, nil, validateBundle(p)
+
+	case domain.EventTypeMatchMonitoring:
+		p := &domain.MatchMonitoring{}
+		if err := dec.Decode(p); err != nil {
+			return nil, nil, fmt.Errorf("payload does not match MatchMonitoring: %w", err)
+		}
+		if p.CorrelationID == 0 {
+			p.CorrelationID = id
+		}
+		return p, stampIfZero(&p.Timestamp), validateMonitoring(*p)
+
+	case domain.EventTypeForkFound:
+		p := &domain.ForkFound{}
+		if err := dec.Decode(p); err != nil {
+			return nil, nil, fmt.Errorf("payload does not match ForkFound: %w", err)
+		}
+		if p.CorrelationID == 0 {
+			p.CorrelationID = id
+		}
+		return p, stampIfZero(&p.Timestamp), validateFork(*p)
+	}
+	return nil, nil, fmt.Errorf("unsupported event_type %q", eventType)
+}
+
+func stampIfZero(t *time.Time) func(time.Time) {
+	if !t.IsZero() {
+		return nil
+	}
+	return func(now time.Time) { *t = now }
+}
+
+func validateBundle(p domain.MatchBundle) error {
+	if len(p.TeamNames) != 2 {
+		return fmt.Errorf("TeamNames must contain two teams, got %d", len(p.TeamNames))
+	}
+	if len(p.BookmakerBundle) == 0 {
+		return errors.New("BookmakerBundle is empty")
+	}
+	return nil
+}
+
+func validateMonitoring(p domain.MatchMonitoring) error {
+	if p.SportType == "" {
+		return errors.New("sport_type is required")
+	}
+	if len(p.TeamNames) != 2 {
+		return fmt.Errorf("team_names must contain two teams, got %d", len(p.TeamNames))
+	}
+	if len(p.Bets) == 0 {
+		return errors.New("bets are empty")
+	}
+	for bookmaker, bets := range p.Bets {
+		for _, bet := range bets {
+			if bet.BetMarket == "" || bet.TargetBet == "" {
+				return fmt.Errorf("bets.%s: bet_market and target_bet are required", bookmaker)
+			}
+			if bet.Less <= 0 || bet.More <= 0 {
+				return fmt.Errorf("bets.%s: odds must be positive", bookmaker)
+			}
+		}
+	}
+	return nil
+}
+
+func validateFork(p domain.ForkFound) error {
+	if p.BetMarket == "" {
+		return errors.New("BetMarket is required")
+	}
+	if len(p.Legs) < 2 {
+		return fmt.Errorf("fork needs at least two legs, got %d", len(p.Legs))
+	}
+	for i, leg := range p.Legs {
+		if leg.Bookmaker == "" || leg.TargetBet == "" {
+			return fmt.Errorf("Legs[%d]: Bookmaker and TargetBet are required", i)
+		}
+		if leg.Odds <= 1 {
+			return fmt.Errorf("Legs[%d]: odds must be greater than 1", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package fixtures собирает события из файлов-фикстур для ручной проверки потребителей
+package fixtures
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry - описание события в фикстуре. Payload пишется в формате типа события
+// (MatchBundle, MatchMonitoring, ForkFound), строки в нем могут быть шаблонами.
+type Entry struct {
+	EventType string `yaml:"event_type"`
+	// Match - ключ матча: события с одним ключом получают общий correlation id
+	Match string `yaml:"match"`
+	// CorrelationID - явный correlation id вместо выданного по Match
+	CorrelationID int64       `yaml:"correlation_id"`
+	Source        string      `yaml:"source"`
+	Repeat        int         `yaml:"repeat"`
+	Delay         string      `yaml:"delay"`
+	Payload       interface{} `yaml:"payload"`
+
+	delay time.Duration
+	file  string
+	line  int
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s:%d (%s)", e.file, e.line, e.EventType)
+}
+
+// Load читает фикстуру: YAML или JSON со списком событий или одним событием,
+// .jsonl - по событию в строке
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	name := filepath.Base(path)
+
+	var entries []Entry
+	if strings.HasSuffix(path, ".jsonl") {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for n := 1; scanner.Scan(); n++ {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var node yaml.Node
+			if err := yaml.Unmarshal(line, &node); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+			}
+			parsed, err := decode(&node, name, n)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, parsed...)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+	} else {
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if entries, err = decode(&node, name, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: no events", name)
+	}
+	return entries, nil
+}
+
+// decode разбирает документ: список событий или одно событие. line > 0 - номер строки jsonl.
+func decode(doc *yaml.Node, file string, line int) ([]Entry, error) {
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	items := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		e := Entry{file: file, line: item.Line}
+		if line > 0 {
+			e.line = line
+		}
+		if err := checkKeys(item); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, e.line, err)
+		}
+		if err := item.Decode(&e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, e.line, err)
+		}
+		if err := e.check(); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, e.line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+var entryKeys = map[string]bool{
+	"event_type": true, "match": true, "correlation_id": true, "source": true,
+	"repeat": true, "delay": true, "payload": true,
+}
+
+// checkKeys - опечатка в ключе события иначе молча превратилась бы в значение по умолчанию
+func checkKeys(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("event must be an object")
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i].Value; !entryKeys[key] {
+			return fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return nil
+}
+
+func (e *Entry) check() error {
+	if e.EventType == "" {
+		return fmt.Errorf("event_type is required")
+	}
+	if e.Payload == nil {
+		return fmt.Errorf("payload is required")
+	}
+	if e.Repeat < 0 {
+		return fmt.Errorf("repeat must not be negative")
+	}
+	if e.Delay != "" {
+		d, err := time.ParseDuration(e.Delay)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid delay %q", e.Delay)
+		}
+		e.delay = d
+	}
+	return nil
+}
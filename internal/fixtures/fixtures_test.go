@@ -0,0 +1,98 @@
+package fixtures
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildExample(t *testing.T) {
+	entries, err := Load("../../fixtures/match_day.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder("test", 100, 1)
+	msgs, err := b.Build(entries, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("got %d messages, want 5", len(msgs))
+	}
+	for _, m := range msgs {
+		if m.CorrelationID != 100 {
+			t.Errorf("%s: correlation_id %d, want 100", m.Subject, m.CorrelationID)
+		}
+	}
+
+	var monitoring struct {
+		Header  domain.EventHeader     `json:"event_header"`
+		Payload domain.MatchMonitoring `json:"payload"`
+	}
+	data, err := msgs[1].Encode(time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &monitoring); err != nil {
+		t.Fatal(err)
+	}
+	if msgs[1].Subject != domain.SubjectMatchMonitoring || monitoring.Header.EventType != domain.EventTypeMatchMonitoring {
+		t.Errorf("monitoring event: %s %+v", msgs[1].Subject, monitoring.Header)
+	}
+	if !monitoring.Header.Timestamp.Equal(monitoring.Payload.Timestamp) || monitoring.Payload.Timestamp.Hour() != 18 {
+		t.Errorf("timestamps = %s, %s", monitoring.Header.Timestamp, monitoring.Payload.Timestamp)
+	}
+	if odds := monitoring.Payload.Bets["fonbet"][0].Less; odds < 1.80 || odds > 1.90 {
+		t.Errorf("templated odds = %v, want within [1.80, 1.90]", odds)
+	}
+	if monitoring.Payload.CorrelationID != 100 || monitoring.Payload.BookmakerBundle["fonbet"] != "https://fonbet.ru/match/100" {
+		t.Errorf("payload = %+v", monitoring.Payload)
+	}
+
+	next, err := b.Build(entries, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next[0].CorrelationID != 101 {
+		t.Errorf("second pass correlation_id = %d, want 101", next[0].CorrelationID)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown key":   `{"event_type": "fork.found", "paylod": {}}`,
+		"unknown field": `{"event_type": "match.bundle", "payload": {"TeamNames": ["A", "B"], "BookmakerBundle": {"x": "y"}, "Teams": []}}`,
+		"event type":    `{"event_type": "match.found", "payload": {}}`,
+		"validation":    `{"event_type": "fork.found", "payload": {"BetMarket": "match-winner", "Legs": [{"Bookmaker": "a", "TargetBet": "A", "Odds": 2.1}]}}`,
+		"template":      `{"event_type": "fork.found", "payload": {"BetMarket": "{{odds 2 1}}"}}`,
+	}
+
+	for name, line := range cases {
+		path := writeFixture(t, "bad.jsonl", "\n"+line+"\n")
+		entries, err := Load(path)
+		if err == nil {
+			_, err = NewBuilder("test", 1, 1).Build(entries, 0)
+		}
+		if err == nil {
+			t.Errorf("%s: no error", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), "bad.jsonl:2") {
+			t.Errorf("%s: error %q does not point to the line", name, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package fixtures
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData - значения, доступные в шаблонах payload
+type templateData struct {
+	CorrelationID int64
+	Match         string
+	Iteration     int // номер прохода по фикстурам
+	Repeat        int // номер повтора события внутри Entry.Repeat
+}
+
+// funcs - функции шаблонов: случайные значения берутся из генератора с заданным seed,
+// поэтому прогон с тем же -seed дает те же события
+func funcs(rnd *rand.Rand) template.FuncMap {
+	return template.FuncMap{
+		// odds 1.80 2.10 - коэффициент в диапазоне с точностью до сотых
+		"odds": func(lo, hi float64) (float64, error) {
+			if lo > hi {
+				return 0, fmt.Errorf("odds: min %v is greater than max %v", lo, hi)
+			}
+			return math.Round((lo+rnd.Float64()*(hi-lo))*100) / 100, nil
+		},
+		// int 1 10 - целое в диапазоне включительно
+		"int": func(lo, hi int) (int, error) {
+			if lo > hi {
+				return 0, fmt.Errorf("int: min %d is greater than max %d", lo, hi)
+			}
+			return lo + rnd.IntN(hi-lo+1), nil
+		},
+		// pick "NaVi" "G2" - одно из значений
+		"pick": func(values ...string) (string, error) {
+			if len(values) == 0 {
+				return "", fmt.Errorf("pick: no values")
+			}
+			return values[rnd.IntN(len(values))], nil
+		},
+		"now": func() string {
+			return time.Now().UTC().Format(time.RFC3339Nano)
+		},
+	}
+}
+
+// render подставляет шаблоны во все строки payload. Строка, целиком состоящая из одного шаблона,
+// превращается в число, если результат - число, чтобы "{{odds 1.8 2.1}}" стал коэффициентом.
+func render(value interface{}, fm template.FuncMap, data templateData) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return renderString(v, fm, data)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			k, err := renderString(key, fm, data)
+			if err != nil {
+				return nil, err
+			}
+			ks, ok := k.(string)
+			if !ok {
+				ks = fmt.Sprint(k)
+			}
+			if out[ks], err = render(item, fm, data); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if out[i], err = render(item, fm, data); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+func renderString(s string, fm template.FuncMap, data templateData) (interface{}, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("payload").Funcs(fm).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", s, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("template %q: %w", s, err)
+	}
+
+	out := buf.String()
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+		if n, err := strconv.ParseInt(out, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(out, 64); err == nil {
+			return f, nil
+		}
+	}
+	return out, nil
+}
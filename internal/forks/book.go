@@ -0,0 +1,213 @@
+// Package forks ищет вилки по коэффициентам из событий мониторинга и публикует ForkFound
+package forks
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"time"
+)
+
+// marketKey - двухисходный рынок: исход target_bet рынка bet_market со ставками less и more
+type marketKey struct {
+	market string
+	target string
+}
+
+type quote struct {
+	less float64
+	more float64
+	at   time.Time
+}
+
+type match struct {
+	teams   []string
+	updated time.Time
+	// котировки по рынку и букмекеру
+	markets map[marketKey]map[string]quote
+}
+
+// Book хранит последние коэффициенты по correlation id. Не потокобезопасен, им владеет Detector.
+type Book struct {
+	matches map[int64]*match
+}
+
+func NewBook() *Book {
+	return &Book{matches: make(map[int64]*match)}
+}
+
+// Update заменяет котировки букмекеров из события: рынки, которых у букмекера больше нет в событии,
+// удаляются. Букмекеры, которых нет в событии, сохраняют прежние котировки до устаревания.
+// Событие старше уже известных котировок букмекера (пришло не по порядку) его котировки не меняет.
+func (b *Book) Update(id int64, m domain.MatchMonitoring, at time.Time) {
+	mt, ok := b.matches[id]
+	if !ok {
+		mt = &match{markets: make(map[marketKey]map[string]quote)}
+		b.matches[id] = mt
+	}
+	if len(m.TeamNames) > 0 {
+		mt.teams = m.TeamNames
+	}
+	if at.After(mt.updated) {
+		mt.updated = at
+	}
+
+	for bookmaker, bets := range m.Bets {
+		if mt.latest(bookmaker).After(at) {
+			continue
+		}
+		for key, quotes := range mt.markets {
+			delete(quotes, bookmaker)
+			if len(quotes) == 0 {
+				delete(mt.markets, key)
+			}
+		}
+		for _, bet := range bets {
+			if bet.Less <= 1 || bet.More <= 1 {
+				continue
+			}
+			key := marketKey{market: bet.BetMarket, target: bet.TargetBet}
+			if mt.markets[key] == nil {
+				mt.markets[key] = make(map[string]quote)
+			}
+			mt.markets[key][bookmaker] = quote{less: bet.Less, more: bet.More, at: at}
+		}
+	}
+}
+
+func (mt *match) latest(bookmaker string) time.Time {
+	var latest time.Time
+	for _, quotes := range mt.markets {
+		if q, ok := quotes[bookmaker]; ok && q.at.After(latest) {
+			latest = q.at
+		}
+	}
+	return latest
+}
+
+// Expire удаляет котировки старше maxAge и матчи без котировок, возвращает удаленные correlation id
+func (b *Book) Expire(now time.Time, maxAge time.Duration) []int64 {
+	var removed []int64
+	for id, mt := range b.matches {
+		for key, quotes := range mt.markets {
+			for bookmaker, q := range quotes {
+				if now.Sub(q.at) > maxAge {
+					delete(quotes, bookmaker)
+				}
+			}
+			if len(quotes) == 0 {
+				delete(mt.markets, key)
+			}
+		}
+		if len(mt.markets) == 0 {
+			delete(b.matches, id)
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+func (b *Book) IDs() []int64 {
+	ids := make([]int64, 0, len(b.matches))
+	for id := range b.matches {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (b *Book) Len() int {
+	return len(b.matches)
+}
+
+// Thresholds - условия, при которых пара котировок считается вилкой
+type Thresholds struct {
+	MinProfit  float64
+	MaxProfit  float64
+	MaxAge     time.Duration
+	MaxLegSkew time.Duration
+}
+
+// Detect находит по каждому рынку матча лучшую вилку из котировок разных букмекеров
+func (b *Book) Detect(id int64, now time.Time, th Thresholds) []domain.ArbitrageFork {
+	mt, ok := b.matches[id]
+	if !ok {
+		return nil
+	}
+
+	var forks []domain.ArbitrageFork
+	for key, quotes := range mt.markets {
+		best, found := bestPair(quotes, now, th)
+		if !found {
+			continue
+		}
+		best.CorrelationID = id
+		best.TeamNames = mt.teams
+		best.BetMarket = key.market
+		best.TargetBet = key.target
+		forks = append(forks, best)
+	}
+	return forks
+}
+
+func bestPair(quotes map[string]quote, now time.Time, th Thresholds) (domain.ArbitrageFork, bool) {
+	var best domain.ArbitrageFork
+	found := false
+
+	for lessBM, lq := range quotes {
+		if now.Sub(lq.at) > th.MaxAge {
+			continue
+		}
+		for moreBM, mq := range quotes {
+			if moreBM == lessBM || now.Sub(mq.at) > th.MaxAge {
+				continue
+			}
+			if th.MaxLegSkew > 0 && absDuration(lq.at.Sub(mq.at)) > th.MaxLegSkew {
+				continue
+			}
+
+			margin := 1/lq.less + 1/mq.more
+			profit := Profit(margin)
+			if profit < th.MinProfit || (th.MaxProfit > 0 && profit > th.MaxProfit) {
+				continue
+			}
+			// при равной прибыли выбор не должен зависеть от порядка обхода map
+			if found && (profit < best.Profit || (profit == best.Profit && pairAfter(lessBM, moreBM, best))) {
+				continue
+			}
+
+			oddsAt := lq.at
+			if mq.at.After(oddsAt) {
+				oddsAt = mq.at
+			}
+			best = domain.ArbitrageFork{
+				LessBookmaker: lessBM,
+				Less:          lq.less,
+				MoreBookmaker: moreBM,
+				More:          mq.more,
+				Margin:        margin,
+				Profit:        profit,
+				OddsAt:        oddsAt,
+			}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// pairAfter сравнивает пару букмекеров с парой вилки: сначала по LessBookmaker, затем по MoreBookmaker
+func pairAfter(lessBM, moreBM string, f domain.ArbitrageFork) bool {
+	if lessBM != f.LessBookmaker {
+		return lessBM > f.LessBookmaker
+	}
+	return moreBM > f.MoreBookmaker
+}
+
+// Profit - гарантированная прибыль в процентах при марже 1/less + 1/more
+func Profit(margin float64) float64 {
+	return (1/margin - 1) * 100
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
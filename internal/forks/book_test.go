@@ -0,0 +1,111 @@
+package forks
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"math"
+	"testing"
+	"time"
+)
+
+var th = Thresholds{MinProfit: 0.5, MaxProfit: 15, MaxAge: 30 * time.Second, MaxLegSkew: 10 * time.Second}
+
+func monitoring(bookmaker string, less, more float64) domain.MatchMonitoring {
+	return domain.MatchMonitoring{
+		TeamNames: []string{"NaVi", "G2"},
+		Bets: map[string][]domain.MonitoringBet{
+			bookmaker: {{BetMarket: "total-maps", TargetBet: "2.5", Less: less, More: more}},
+		},
+	}
+}
+
+func TestDetect(t *testing.T) {
+	now := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	b := NewBook()
+	b.Update(1, monitoring("fonbet", 2.10, 1.70), now.Add(-5*time.Second))
+	b.Update(1, monitoring("winline", 1.75, 2.05), now.Add(-2*time.Second))
+	// у marathon лучшие less и more сразу, но сам с собой букмекер вилку не образует
+	b.Update(1, monitoring("marathon", 2.30, 2.30), now.Add(-time.Second))
+
+	forks := b.Detect(1, now, th)
+	if len(forks) != 1 {
+		t.Fatalf("forks = %+v", forks)
+	}
+	f := forks[0]
+	if f.LessBookmaker != "fonbet" || f.MoreBookmaker != "marathon" {
+		t.Errorf("legs = %s/%s, want fonbet/marathon", f.LessBookmaker, f.MoreBookmaker)
+	}
+	wantMargin := 1/2.10 + 1/2.30
+	if math.Abs(f.Margin-wantMargin) > 1e-9 || math.Abs(f.Profit-(1/wantMargin-1)*100) > 1e-9 {
+		t.Errorf("margin %v profit %v", f.Margin, f.Profit)
+	}
+	if f.BetMarket != "total-maps" || f.TargetBet != "2.5" || f.CorrelationID != 1 || !f.OddsAt.Equal(now.Add(-time.Second)) {
+		t.Errorf("fork = %+v", f)
+	}
+
+	event := ForkEvent(f)
+	total := event.Legs[0].Stake*event.Legs[0].Odds - event.Legs[1].Stake*event.Legs[1].Odds
+	if math.Abs(total) > 0.001 || math.Abs(event.Legs[0].Stake+event.Legs[1].Stake-1) > 0.001 {
+		t.Errorf("stakes do not equalize payout: %+v", event.Legs)
+	}
+}
+
+func TestDetectThresholds(t *testing.T) {
+	now := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		lessAt   time.Duration
+		moreAt   time.Duration
+		less     float64
+		more     float64
+		wantFork bool
+	}{
+		{"fork", -time.Second, -time.Second, 2.10, 2.05, true},
+		{"no arbitrage", -time.Second, -time.Second, 1.90, 1.90, false},
+		{"below min profit", -time.Second, -time.Second, 2.01, 2.00, false},
+		{"suspicious profit", -time.Second, -time.Second, 3.00, 3.00, false},
+		{"stale leg", -40 * time.Second, -time.Second, 2.10, 2.05, false},
+		{"legs too far apart", -25 * time.Second, -time.Second, 2.10, 2.05, false},
+	}
+
+	for _, tc := range cases {
+		b := NewBook()
+		b.Update(1, monitoring("fonbet", tc.less, 1.5), now.Add(tc.lessAt))
+		b.Update(1, monitoring("winline", 1.5, tc.more), now.Add(tc.moreAt))
+		if got := len(b.Detect(1, now, th)) > 0; got != tc.wantFork {
+			t.Errorf("%s: fork = %v, want %v", tc.name, got, tc.wantFork)
+		}
+	}
+}
+
+func TestUpdateIgnoresOutOfOrderEvents(t *testing.T) {
+	now := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	b := NewBook()
+	b.Update(1, monitoring("fonbet", 2.10, 1.70), now)
+	b.Update(1, monitoring("fonbet", 1.50, 1.50), now.Add(-time.Second))
+	b.Update(1, monitoring("winline", 1.70, 2.05), now)
+
+	if forks := b.Detect(1, now, th); len(forks) != 1 || forks[0].Less != 2.10 {
+		t.Errorf("forks = %+v", forks)
+	}
+
+	if removed := b.Expire(now.Add(time.Minute), th.MaxAge); len(removed) != 1 || b.Len() != 0 {
+		t.Errorf("expire removed %v, %d matches left", removed, b.Len())
+	}
+}
+
+func TestDetectTieBreak(t *testing.T) {
+	now := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	// склейка имён дала бы ab/bc ("abbc" < "abc"), пары сравниваются по less, затем по more
+	for i := 0; i < 20; i++ {
+		b := NewBook()
+		b.Update(1, monitoring("ab", 2.10, 1.5), now)
+		b.Update(1, monitoring("a", 2.10, 1.5), now)
+		b.Update(1, monitoring("c", 1.5, 2.05), now)
+		b.Update(1, monitoring("bc", 1.5, 2.05), now)
+
+		forks := b.Detect(1, now, th)
+		if len(forks) != 1 || forks[0].LessBookmaker != "a" || forks[0].MoreBookmaker != "bc" {
+			t.Fatalf("forks = %+v, want a/bc", forks)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package forks
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const retryDelay = 5 * time.Second
+
+// Detector читает events.match.monitoring (или FORK_DETECTOR_SUBJECT), ведет Book и публикует ForkFound в events.fork.found.
+// При старте стрим читается с момента now - FORK_ODDS_MAX_AGE: коэффициенты восстанавливаются,
+// а публикация начинается только после того, как стрим дочитан до конца (без повторов старых вилок).
+type Detector struct {
+	cfg     *config.Config
+	logger  *utils.Logger
+	connect func(name string) (*nats.Conn, error)
+	book    *Book
+
+	mu        sync.RWMutex
+	ready     bool
+	matches   int
+	published uint64
+	active    map[string]domain.ArbitrageFork // опубликованные и еще действующие вилки по fingerprint
+
+	nc     *nats.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDetector(cfg *config.Config, logger *utils.Logger, connect func(name string) (*nats.Conn, error)) *Detector {
+	return &Detector{
+		cfg:     cfg,
+		logger:  logger.WithFields(zap.String("component", "fork-detector")),
+		connect: connect,
+		book:    NewBook(),
+		active:  make(map[string]domain.ArbitrageFork),
+	}
+}
+
+func (d *Detector) Start(context.Context) error {
+	nc, err := d.connect("fork-detector")
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.nc, d.cancel, d.done = nc, cancel, make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+		d.run(ctx, js)
+	}()
+	return nil
+}
+
+func (d *Detector) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	d.nc.Close()
+	return nil
+}
+
+// Forks - действующие опубликованные вилки, по убыванию прибыли
+func (d *Detector) Forks() domain.ForksResponse {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	resp := domain.ForksResponse{
+		Ready:     d.ready,
+		Matches:   d.matches,
+		Published: d.published,
+		Forks:     make([]domain.ArbitrageFork, 0, len(d.active)),
+	}
+	for _, f := range d.active {
+		resp.Forks = append(resp.Forks, f)
+	}
+	sort.Slice(resp.Forks, func(i, j int) bool { return resp.Forks[i].Profit > resp.Forks[j].Profit })
+	return resp
+}
+
+func (d *Detector) thresholds() Thresholds {
+	return Thresholds{
+		MinProfit:  d.cfg.ForkMinProfit,
+		MaxProfit:  d.cfg.ForkMaxProfit,
+		MaxAge:     d.cfg.ForkOddsMaxAge,
+		MaxLegSkew: d.cfg.ForkMaxLegSkew,
+	}
+}
+
+// run переподключает consumer при ошибках и ждет появления стрима, если его еще нет
+func (d *Detector) run(ctx context.Context, js jetstream.JetStream) {
+	waiting := false
+
+	for ctx.Err() == nil {
+		err := d.consume(ctx, js)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, jetstream.ErrStreamNotFound):
+			if !waiting {
+				d.logger.Warn("stream not found, waiting for it to be created", zap.String("stream", d.cfg.ForkDetectorStream))
+				waiting = true
+			}
+		case err != nil:
+			d.logger.Warn("fork detector consumer stopped, restarting", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (d *Detector) consume(ctx context.Context, js jetstream.JetStream) error {
+	stream, err := js.Stream(ctx, d.cfg.ForkDetectorStream)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now().Add(-d.cfg.ForkOddsMaxAge)
+	consumer, err := stream.OrderedConsumer(ctx, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{d.cfg.ForkDetectorSubject},
+		DeliverPolicy:  jetstream.DeliverByStartTimePolicy,
+		OptStartTime:   &from,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ordered consumer: %w", err)
+	}
+
+	msgs, err := consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to consume stream: %w", err)
+	}
+	defer msgs.Stop()
+
+	go func() {
+		<-ctx.Done()
+		msgs.Stop()
+	}()
+
+	d.logger.Info("fork detector consuming monitoring events",
+		zap.String("stream", d.cfg.ForkDetectorStream),
+		zap.String("subject", d.cfg.ForkDetectorSubject),
+		zap.Time("from", from),
+		zap.Float64("min_profit", d.cfg.ForkMinProfit),
+	)
+	if consumer.CachedInfo().NumPending == 0 {
+		d.markReady(ctx, js)
+	}
+
+	// устаревшие коэффициенты удаляются по таймеру, даже если сообщения идут непрерывно
+	sweep := d.cfg.ForkOddsMaxAge / 2
+	lastSweep := time.Now()
+
+	for {
+		msg, err := msgs.Next(jetstream.NextMaxWait(sweep))
+		switch {
+		case errors.Is(err, nats.ErrTimeout):
+			// пауза без сообщений значит, что стрим дочитан
+			d.markReady(ctx, js)
+		case errors.Is(err, jetstream.ErrMsgIteratorClosed):
+			return ctx.Err()
+		case err != nil:
+			return err
+		default:
+			if caughtUp := d.handle(ctx, js, msg); caughtUp {
+				d.markReady(ctx, js)
+			}
+		}
+
+		if time.Since(lastSweep) >= sweep {
+			d.expire(ctx, js)
+			lastSweep = time.Now()
+		}
+	}
+}
+
+// handle обновляет коэффициенты матча. Возвращает true, когда это последнее сообщение в стриме.
+func (d *Detector) handle(ctx context.Context, js jetstream.JetStream, msg jetstream.Msg) bool {
+	traceCtx, span := tracing.StartConsume(ctx, msg.Subject(), msg.Headers(), msg.Data())
+	defer span.End()
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		d.logger.Warn("failed to read message metadata", zap.Error(err))
+		return false
+	}
+	caughtUp := meta.NumPending == 0
+
+	env, err := domain.ParseEnvelope(msg.Data())
+	if err != nil || len(env.Payload) == 0 {
+		d.logger.Debug("skipping non-event message", zap.Uint64("seq", meta.Sequence.Stream))
+		return caughtUp
+	}
+	var monitoring domain.MatchMonitoring
+	if err := json.Unmarshal(env.Payload, &monitoring); err != nil {
+		d.logger.Debug("skipping invalid monitoring payload", zap.Uint64("seq", meta.Sequence.Stream), zap.Error(err))
+		return caughtUp
+	}
+	id, ok := env.CorrelationID()
+	if !ok {
+		return caughtUp
+	}
+
+	// время коэффициентов - из события, а если его нет - время записи в стрим
+	at := meta.Timestamp
+	if t, ok := env.EventTime(); ok {
+		at = t
+	}
+	d.book.Update(id, monitoring, at)
+
+	d.mu.RLock()
+	ready := d.ready
+	d.mu.RUnlock()
+	if ready {
+		d.evaluate(traceCtx, js, id, time.Now())
+	}
+	return caughtUp
+}
+
+// evaluate публикует новые вилки матча и вилки, прибыль которых заметно изменилась,
+// и забывает вилки, которых больше нет
+func (d *Detector) evaluate(ctx context.Context, js jetstream.JetStream, id int64, now time.Time) {
+	current := make(map[string]domain.ArbitrageFork)
+	for _, f := range d.book.Detect(id, now, d.thresholds()) {
+		current[fingerprint(f)] = f
+	}
+
+	d.mu.Lock()
+	for fp, f := range d.active {
+		if _, ok := current[fp]; !ok && f.CorrelationID == id {
+			delete(d.active, fp)
+		}
+	}
+	var toPublish []domain.ArbitrageFork
+	for fp, f := range current {
+		prev, ok := d.active[fp]
+		if ok && math.Abs(prev.Profit-f.Profit) < d.cfg.ForkRepublishDelta {
+			continue
+		}
+		toPublish = append(toPublish, f)
+	}
+	d.matches = d.book.Len()
+	d.mu.Unlock()
+
+	for _, f := range toPublish {
+		duplicate, err := d.publish(ctx, js, f)
+		if err != nil {
+			d.logger.Warn("failed to publish fork",
+				zap.Int64("correlation_id", f.CorrelationID),
+				zap.String("market", f.BetMarket),
+				zap.Error(err),
+			)
+			continue
+		}
+		// вилка вернулась с той же прибылью в окне дедупликации - подписчики ее не получили,
+		// поэтому она не считается опубликованной и будет отправлена после окна
+		if duplicate {
+			d.logger.Debug("fork dropped by stream deduplication",
+				zap.Int64("correlation_id", f.CorrelationID),
+				zap.String("market", f.BetMarket),
+			)
+			continue
+		}
+
+		f.PublishedAt = time.Now().UTC()
+		d.mu.Lock()
+		d.active[fingerprint(f)] = f
+		d.published++
+		d.mu.Unlock()
+
+		d.logger.Info("fork found",
+			zap.Int64("correlation_id", f.CorrelationID),
+			zap.String("market", f.BetMarket),
+			zap.String("target", f.TargetBet),
+			zap.String("less", fmt.Sprintf("%s@%.2f", f.LessBookmaker, f.Less)),
+			zap.String("more", fmt.Sprintf("%s@%.2f", f.MoreBookmaker, f.More)),
+			zap.Float64("profit", f.Profit),
+		)
+	}
+}
+
+// publish отправляет fork.found. duplicate - стрим отбросил сообщение с тем же Nats-Msg-Id.
+func (d *Detector) publish(ctx context.Context, js jetstream.JetStream, f domain.ArbitrageFork) (duplicate bool, err error) {
+	event := domain.NewEvent(domain.EventTypeForkFound, d.cfg.AppName, f.CorrelationID, ForkEvent(f))
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+
+	msg := &nats.Msg{Subject: domain.SubjectForkFound, Data: data, Header: nats.Header{}}
+	// после перезапуска та же вилка с той же прибылью отбрасывается дедупликацией стрима
+	msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("fork:%s:%.2f", fingerprint(f), f.Profit))
+
+	ctx, span := tracing.StartPublish(ctx, msg)
+	defer func() { tracing.End(span, err) }()
+
+	pubCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	ack, err := js.PublishMsg(pubCtx, msg)
+	if err != nil {
+		return false, err
+	}
+	return ack.Duplicate, nil
+}
+
+// expire удаляет устаревшие коэффициенты и пересчитывает вилки: вилка пропадает, когда плечо устарело
+func (d *Detector) expire(ctx context.Context, js jetstream.JetStream) {
+	now := time.Now()
+	removed := d.book.Expire(now, d.cfg.ForkOddsMaxAge)
+
+	d.mu.Lock()
+	ids := make(map[int64]bool)
+	for _, f := range d.active {
+		ids[f.CorrelationID] = true
+	}
+	for _, id := range removed {
+		ids[id] = true
+	}
+	d.matches = d.book.Len()
+	d.mu.Unlock()
+
+	for id := range ids {
+		d.evaluate(ctx, js, id, now)
+	}
+}
+
+// markReady включает публикацию после прочтения стрима и проверяет все матчи, накопленные при чтении
+func (d *Detector) markReady(ctx context.Context, js jetstream.JetStream) {
+	d.mu.Lock()
+	if d.ready {
+		d.mu.Unlock()
+		return
+	}
+	d.ready = true
+	d.matches = d.book.Len()
+	d.mu.Unlock()
+
+	d.logger.Info("fork detector is ready", zap.Int("matches", d.book.Len()))
+	now := time.Now()
+	for _, id := range d.book.IDs() {
+		d.evaluate(ctx, js, id, now)
+	}
+}
+
+// ForkEvent - payload ForkFound: плечи less и more с долями банка, при которых выплата одинакова
+func ForkEvent(f domain.ArbitrageFork) domain.ForkFound {
+	return domain.ForkFound{
+		CorrelationID: f.CorrelationID,
+		TeamNames:     f.TeamNames,
+		BetMarket:     f.BetMarket,
+		Profit:        math.Round(f.Profit*100) / 100,
+		Legs: []domain.ForkLeg{
+			{Bookmaker: f.LessBookmaker, TargetBet: f.TargetBet + " less", Odds: f.Less, Stake: stake(f.Less, f.Margin)},
+			{Bookmaker: f.MoreBookmaker, TargetBet: f.TargetBet + " more", Odds: f.More, Stake: stake(f.More, f.Margin)},
+		},
+		Timestamp: f.OddsAt.UTC(),
+	}
+}
+
+func stake(odds, margin float64) float64 {
+	return math.Round((1/odds)/margin*10000) / 10000
+}
+
+func fingerprint(f domain.ArbitrageFork) string {
+	return fmt.Sprintf("%d/%s/%s/%s/%s", f.CorrelationID, f.BetMarket, f.TargetBet, f.LessBookmaker, f.MoreBookmaker)
+}
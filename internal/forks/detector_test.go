@@ -0,0 +1,82 @@
+package forks_test
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/forks"
+	"NATS_TIRE_SERVICE/internal/natstest"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func publishOdds(h *natstest.Harness, bookmaker string, less, more float64) {
+	h.Publish(domain.SubjectMatchMonitoring, []byte(fmt.Sprintf(`{"payload":{"correlation_id":7,"team_names":["NaVi","G2"],`+
+		`"bets":{%q:[{"bet_market":"match-winner","target_bet":"NaVi","less":%v,"more":%v}]},"timestamp":%q}}`,
+		bookmaker, less, more, time.Now().UTC().Format(time.RFC3339Nano))))
+}
+
+func TestDetectorPublishesFork(t *testing.T) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.ForkDetectorEnabled = true
+			cfg.ForkOddsMaxAge = 2 * time.Second
+		}),
+	)
+
+	detector := forks.NewDetector(h.Config, h.Logger, h.Server.ClientConn)
+	if err := detector.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { detector.Stop(context.Background()) })
+	natstest.Eventually(t, 5*time.Second, func() bool { return detector.Forks().Ready }, "detector is not ready")
+
+	publishOdds(h, "fonbet", 2.10, 1.70)
+	publishOdds(h, "winline", 1.75, 2.05)
+	natstest.Eventually(t, 5*time.Second, func() bool { return len(detector.Forks().Forks) == 1 }, "fork was not detected")
+
+	// те же коэффициенты еще раз - вилка не публикуется повторно
+	publishOdds(h, "winline", 1.75, 2.05)
+	time.Sleep(200 * time.Millisecond)
+	if resp := detector.Forks(); resp.Published != 1 || resp.Matches != 1 {
+		t.Errorf("forks = %+v", resp)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := h.JetStream.Stream(ctx, domain.StreamEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := stream.GetLastMsgForSubject(ctx, domain.SubjectForkFound)
+	if err != nil {
+		t.Fatalf("fork.found was not published: %v", err)
+	}
+
+	var event struct {
+		Header  domain.EventHeader `json:"event_header"`
+		Payload domain.ForkFound   `json:"payload"`
+	}
+	if err := json.Unmarshal(raw.Data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Header.EventType != domain.EventTypeForkFound || event.Header.CorrelationID != 7 ||
+		event.Payload.BetMarket != "match-winner" || len(event.Payload.Legs) != 2 ||
+		event.Payload.Legs[0].Bookmaker != "fonbet" || event.Payload.Legs[1].Bookmaker != "winline" {
+		t.Errorf("event = %+v", event)
+	}
+
+	// коэффициенты устарели - вилка пропадает из /forks
+	natstest.Eventually(t, 5*time.Second, func() bool { return len(detector.Forks().Forks) == 0 }, "stale fork was not expired")
+
+	// вилка вернулась с той же прибылью в окне дедупликации - стрим ее отбросил, активной она не считается
+	publishOdds(h, "fonbet", 2.10, 1.70)
+	publishOdds(h, "winline", 1.75, 2.05)
+	time.Sleep(500 * time.Millisecond)
+	if resp := detector.Forks(); resp.Published != 1 || len(resp.Forks) != 0 {
+		t.Errorf("duplicate fork: published = %d, forks = %+v", resp.Published, resp.Forks)
+	}
+}
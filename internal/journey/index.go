@@ -0,0 +1,130 @@
+package journey
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"sync"
+)
+
+// Index - история событий по correlation id в памяти. Хранит не больше max событий,
+// самые старые вытесняются первыми.
+type Index struct {
+	mu      sync.RWMutex
+	max     int
+	byID    map[int64][]domain.JourneyEvent
+	order   []int64 // correlation id событий в порядке добавления
+	head    int
+	events  int
+	evicted uint64
+	lastSeq uint64
+	ready   bool
+}
+
+func NewIndex(max int) *Index {
+	return &Index{
+		max:  max,
+		byID: make(map[int64][]domain.JourneyEvent),
+	}
+}
+
+// Add добавляет событие. Повторно доставленные сообщения (seq не больше последнего) пропускаются.
+func (idx *Index) Add(correlationID int64, ev domain.JourneyEvent) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if ev.Seq != 0 && ev.Seq <= idx.lastSeq {
+		return false
+	}
+	if ev.Seq != 0 {
+		idx.lastSeq = ev.Seq
+	}
+
+	idx.byID[correlationID] = append(idx.byID[correlationID], ev)
+	idx.order = append(idx.order, correlationID)
+	idx.events++
+
+	for idx.events > idx.max {
+		idx.evictOldest()
+	}
+	return true
+}
+
+func (idx *Index) evictOldest() {
+	id := idx.order[idx.head]
+	idx.head++
+
+	if events := idx.byID[id]; len(events) > 1 {
+		idx.byID[id] = events[1:]
+	} else {
+		delete(idx.byID, id)
+	}
+	idx.events--
+	idx.evicted++
+
+	// сжимаем очередь, когда прочитанная часть занимает больше половины
+	if idx.head > len(idx.order)/2 {
+		idx.order = append([]int64(nil), idx.order[idx.head:]...)
+		idx.head = 0
+	}
+}
+
+// Timeline возвращает историю correlation id по порядку стрима
+func (idx *Index) Timeline(correlationID int64) (domain.JourneyResponse, bool) {
+	idx.mu.RLock()
+	events := append([]domain.JourneyEvent(nil), idx.byID[correlationID]...)
+	idx.mu.RUnlock()
+
+	if len(events) == 0 {
+		return domain.JourneyResponse{}, false
+	}
+
+	resp := domain.JourneyResponse{
+		CorrelationID: correlationID,
+		FirstSeen:     events[0].Timestamp,
+		LastSeen:      events[0].Timestamp,
+		Events:        events,
+	}
+	for _, ev := range events {
+		if ev.Timestamp.Before(resp.FirstSeen) {
+			resp.FirstSeen = ev.Timestamp
+		}
+		if ev.Timestamp.After(resp.LastSeen) {
+			resp.LastSeen = ev.Timestamp
+		}
+		switch ev.Stage {
+		case domain.StageMonitoringUpdate:
+			resp.MonitoringUpdates++
+		case domain.StageForkFound:
+			resp.ForkFound = true
+		}
+	}
+	resp.Duration = resp.LastSeen.Sub(resp.FirstSeen).String()
+
+	return resp, true
+}
+
+// LastSeq - последний проиндексированный номер сообщения стрима
+func (idx *Index) LastSeq() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.lastSeq
+}
+
+// SetReady отмечает, что история стрима прочитана до конца
+func (idx *Index) SetReady() {
+	idx.mu.Lock()
+	idx.ready = true
+	idx.mu.Unlock()
+}
+
+func (idx *Index) Stats() domain.JourneyStats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return domain.JourneyStats{
+		Ready:          idx.ready,
+		CorrelationIDs: len(idx.byID),
+		Events:         idx.events,
+		Evicted:        idx.evicted,
+		LastSeq:        idx.lastSeq,
+	}
+}
@@ -0,0 +1,73 @@
+package journey
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"testing"
+	"time"
+)
+
+func event(seq uint64, stage string, at time.Time) domain.JourneyEvent {
+	return domain.JourneyEvent{Seq: seq, Stage: stage, Timestamp: at}
+}
+
+func TestIndexTimeline(t *testing.T) {
+	idx := NewIndex(10)
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	idx.Add(42, event(1, domain.StageBundleFound, start))
+	idx.Add(7, event(2, domain.StageBundleFound, start))
+	idx.Add(42, event(3, domain.StageMonitoringUpdate, start.Add(time.Second)))
+	idx.Add(42, event(4, domain.StageMonitoringUpdate, start.Add(2*time.Second)))
+	idx.Add(42, event(5, domain.StageForkFound, start.Add(3*time.Second)))
+
+	tl, ok := idx.Timeline(42)
+	if !ok {
+		t.Fatal("timeline for 42 not found")
+	}
+	if len(tl.Events) != 4 || tl.MonitoringUpdates != 2 || !tl.ForkFound {
+		t.Fatalf("timeline = %+v", tl)
+	}
+	if tl.Duration != "3s" || !tl.FirstSeen.Equal(start) {
+		t.Fatalf("first_seen = %v, duration = %s", tl.FirstSeen, tl.Duration)
+	}
+	if _, ok := idx.Timeline(100); ok {
+		t.Fatal("timeline for unknown id found")
+	}
+}
+
+func TestIndexSkipsRedelivered(t *testing.T) {
+	idx := NewIndex(10)
+	now := time.Now()
+
+	if !idx.Add(1, event(5, domain.StageBundleFound, now)) {
+		t.Fatal("first event rejected")
+	}
+	if idx.Add(1, event(5, domain.StageBundleFound, now)) || idx.Add(1, event(3, domain.StageBundleFound, now)) {
+		t.Fatal("redelivered event accepted")
+	}
+	if stats := idx.Stats(); stats.Events != 1 || stats.LastSeq != 5 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestIndexEvictsOldest(t *testing.T) {
+	idx := NewIndex(3)
+	now := time.Now()
+
+	idx.Add(1, event(1, domain.StageBundleFound, now))
+	idx.Add(2, event(2, domain.StageBundleFound, now))
+	idx.Add(1, event(3, domain.StageMonitoringUpdate, now))
+	idx.Add(3, event(4, domain.StageBundleFound, now))
+	idx.Add(3, event(5, domain.StageMonitoringUpdate, now))
+
+	stats := idx.Stats()
+	if stats.Events != 3 || stats.Evicted != 2 || stats.CorrelationIDs != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if _, ok := idx.Timeline(2); ok {
+		t.Fatal("evicted correlation id still indexed")
+	}
+	if tl, _ := idx.Timeline(1); len(tl.Events) != 1 || tl.Events[0].Seq != 3 {
+		t.Fatalf("timeline 1 = %+v", tl.Events)
+	}
+}
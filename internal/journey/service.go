@@ -0,0 +1,221 @@
+package journey
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"time"
+)
+
+const retryDelay = 5 * time.Second
+
+// Service строит Index: читает стрим событий с начала ordered consumer'ом (без durable)
+// и дальше следит за новыми сообщениями
+type Service struct {
+	cfg     *config.Config
+	logger  *utils.Logger
+	connect func(name string) (*nats.Conn, error)
+	index   *Index
+
+	nc     *nats.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewService(cfg *config.Config, logger *utils.Logger, connect func(name string) (*nats.Conn, error)) *Service {
+	return &Service{
+		cfg:     cfg,
+		logger:  logger.WithFields(zap.String("component", "journey")),
+		connect: connect,
+		index:   NewIndex(cfg.JourneyMaxEvents),
+	}
+}
+
+func (s *Service) Index() *Index {
+	return s.index
+}
+
+func (s *Service) Start(context.Context) error {
+	nc, err := s.connect("journey")
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.nc, s.cancel, s.done = nc, cancel, make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		s.run(ctx, js)
+	}()
+	return nil
+}
+
+func (s *Service) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.nc.Close()
+	return nil
+}
+
+// run переподключает consumer при ошибках и ждет появления стрима, если его еще нет
+func (s *Service) run(ctx context.Context, js jetstream.JetStream) {
+	waiting := false
+
+	for ctx.Err() == nil {
+		err := s.consume(ctx, js)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, jetstream.ErrStreamNotFound):
+			if !waiting {
+				s.logger.Warn("stream not found, waiting for it to be created", zap.String("stream", s.cfg.JourneyStream))
+				waiting = true
+			}
+		case err != nil:
+			s.logger.Warn("journey consumer stopped, restarting", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (s *Service) consume(ctx context.Context, js jetstream.JetStream) error {
+	stream, err := js.Stream(ctx, s.cfg.JourneyStream)
+	if err != nil {
+		return err
+	}
+	info := stream.CachedInfo()
+
+	// после перезапуска продолжаем с места остановки, а не перечитываем стрим
+	cc := jetstream.OrderedConsumerConfig{DeliverPolicy: jetstream.DeliverAllPolicy}
+	if last := s.index.LastSeq(); last > 0 {
+		cc = jetstream.OrderedConsumerConfig{
+			DeliverPolicy: jetstream.DeliverByStartSequencePolicy,
+			OptStartSeq:   last + 1,
+		}
+	}
+
+	consumer, err := stream.OrderedConsumer(ctx, cc)
+	if err != nil {
+		return fmt.Errorf("failed to create ordered consumer: %w", err)
+	}
+
+	msgs, err := consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to consume stream: %w", err)
+	}
+	defer msgs.Stop()
+
+	go func() {
+		<-ctx.Done()
+		msgs.Stop()
+	}()
+
+	replayTo := info.State.LastSeq
+	if replayTo <= s.index.LastSeq() {
+		s.markReady()
+	}
+	s.logger.Info("journey index consuming stream",
+		zap.String("stream", s.cfg.JourneyStream),
+		zap.Uint64("messages", info.State.Msgs),
+		zap.Uint64("from_seq", s.index.LastSeq()+1),
+	)
+
+	for {
+		msg, err := msgs.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		seq := s.handle(ctx, msg)
+		if seq >= replayTo {
+			s.markReady()
+		}
+	}
+}
+
+func (s *Service) markReady() {
+	if !s.index.Stats().Ready {
+		s.index.SetReady()
+		stats := s.index.Stats()
+		s.logger.Info("journey index is ready",
+			zap.Int("correlation_ids", stats.CorrelationIDs),
+			zap.Int("events", stats.Events),
+		)
+	}
+}
+
+// handle индексирует сообщение и возвращает его номер в стриме
+func (s *Service) handle(ctx context.Context, msg jetstream.Msg) uint64 {
+	_, span := tracing.StartConsume(ctx, msg.Subject(), msg.Headers(), msg.Data())
+	defer span.End()
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		s.logger.Warn("failed to read message metadata", zap.Error(err))
+		return 0
+	}
+
+	env, err := domain.ParseEnvelope(msg.Data())
+	if err != nil {
+		s.logger.Debug("skipping non-event message",
+			zap.String("subject", msg.Subject()),
+			zap.Uint64("seq", meta.Sequence.Stream),
+		)
+		return meta.Sequence.Stream
+	}
+	correlationID, ok := env.CorrelationID()
+	if !ok {
+		return meta.Sequence.Stream
+	}
+
+	ev := domain.JourneyEvent{
+		Seq:       meta.Sequence.Stream,
+		Subject:   msg.Subject(),
+		Stage:     domain.StageBySubject(msg.Subject()),
+		Timestamp: meta.Timestamp,
+		StoredAt:  meta.Timestamp,
+		Payload:   env.Payload,
+	}
+	if t, ok := env.EventTime(); ok {
+		ev.Timestamp = t
+	}
+	if env.Header != nil {
+		ev.EventID = env.Header.EventID
+		ev.EventType = env.Header.EventType
+		ev.Source = env.Header.Source
+	}
+
+	s.index.Add(correlationID, ev)
+	return meta.Sequence.Stream
+}
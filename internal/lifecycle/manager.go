@@ -0,0 +1,307 @@
+package lifecycle
+
+import (
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultStartTimeout = 30 * time.Second
+	DefaultStopTimeout  = 15 * time.Second
+)
+
+// Component описывает управляемую часть сервиса: NATS сервер, HTTP сервер, провижинеры, фоновые задачи
+type Component struct {
+	Name      string
+	DependsOn []string
+	// Critical - ошибка запуска останавливает весь сервис
+	Critical     bool
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+	Start        func(ctx context.Context) error
+	Stop         func(ctx context.Context) error
+}
+
+type Manager struct {
+	logger     *utils.Logger
+	mu         sync.Mutex
+	components []*Component
+	byName     map[string]*Component
+	started    []startedComponent
+}
+
+// startedComponent - компонент, для которого при остановке вызывается Stop.
+// starting не nil, если Start не уложился в таймаут и еще выполняется.
+type startedComponent struct {
+	*Component
+	starting <-chan struct{}
+}
+
+func NewManager(logger *utils.Logger) *Manager {
+	return &Manager{
+		logger: logger,
+		byName: make(map[string]*Component),
+	}
+}
+
+func (m *Manager) Register(c Component) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c.Name == "" {
+		return fmt.Errorf("component name is required")
+	}
+	if _, ok := m.byName[c.Name]; ok {
+		return fmt.Errorf("component %q already registered", c.Name)
+	}
+
+	if c.StartTimeout <= 0 {
+		c.StartTimeout = DefaultStartTimeout
+	}
+	if c.StopTimeout <= 0 {
+		c.StopTimeout = DefaultStopTimeout
+	}
+
+	m.components = append(m.components, &c)
+	m.byName[c.Name] = &c
+	return nil
+}
+
+// order возвращает компоненты в порядке запуска: зависимости раньше зависимых, иначе - порядок регистрации
+func (m *Manager) order() ([]*Component, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(m.components))
+	ordered := make([]*Component, 0, len(m.components))
+
+	var visit func(c *Component, path []string) error
+	visit = func(c *Component, path []string) error {
+		switch state[c.Name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v -> %s", path, c.Name)
+		}
+
+		state[c.Name] = visiting
+		for _, dep := range c.DependsOn {
+			d, ok := m.byName[dep]
+			if !ok {
+				return fmt.Errorf("component %q depends on unknown component %q", c.Name, dep)
+			}
+			if err := visit(d, append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		state[c.Name] = visited
+		ordered = append(ordered, c)
+		return nil
+	}
+
+	for _, c := range m.components {
+		if err := visit(c, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// Start запускает компоненты по порядку. При ошибке критичного компонента уже запущенные
+// компоненты останавливаются в обратном порядке и возвращается ошибка.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	ordered, err := m.order()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool)
+
+	for _, c := range ordered {
+		if dep := failedDependency(c, failed); dep != "" {
+			m.logger.Warn("skipping component, dependency failed",
+				zap.String("component", c.Name),
+				zap.String("dependency", dep),
+			)
+			failed[c.Name] = true
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			m.stopStarted()
+			return fmt.Errorf("startup interrupted before %s: %w", c.Name, err)
+		}
+
+		start := time.Now()
+		m.logger.Info("starting component", zap.String("component", c.Name))
+
+		if err := m.start(ctx, c); err != nil {
+			if ctx.Err() != nil {
+				m.stopStarted()
+				return fmt.Errorf("startup interrupted during %s: %w", c.Name, ctx.Err())
+			}
+			if c.Critical {
+				m.logger.Error("critical component failed to start",
+					zap.String("component", c.Name),
+					zap.Error(err),
+				)
+				m.stopStarted()
+				return fmt.Errorf("failed to start %s: %w", c.Name, err)
+			}
+
+			m.logger.Warn("component failed to start",
+				zap.String("component", c.Name),
+				zap.Error(err),
+			)
+			failed[c.Name] = true
+			continue
+		}
+
+		m.logger.Info("component started",
+			zap.String("component", c.Name),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+
+	return nil
+}
+
+// start запускает компонент и запоминает его для остановки. Компонент, чей Start не вернулся
+// к таймауту, тоже запоминается: он мог успеть открыть подключения или запустить горутины.
+func (m *Manager) start(ctx context.Context, c *Component) error {
+	if c.Start == nil {
+		m.mu.Lock()
+		m.started = append(m.started, startedComponent{Component: c})
+		m.mu.Unlock()
+		return nil
+	}
+
+	starting := make(chan struct{})
+	err := call(ctx, c.StartTimeout, func(ctx context.Context) error {
+		defer close(starting)
+		return c.Start(ctx)
+	})
+
+	entry := startedComponent{Component: c}
+	if err != nil {
+		select {
+		case <-starting:
+			// Start завершился с ошибкой - останавливать нечего
+			return err
+		default:
+			entry.starting = starting
+		}
+	}
+
+	m.mu.Lock()
+	m.started = append(m.started, entry)
+	m.mu.Unlock()
+	return err
+}
+
+// Stop останавливает запущенные компоненты в обратном порядке, собирая все ошибки
+func (m *Manager) Stop() error {
+	return m.stopStarted()
+}
+
+func (m *Manager) stopStarted() error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		start := time.Now()
+		m.logger.Info("stopping component", zap.String("component", c.Name))
+
+		if c.starting != nil {
+			select {
+			case <-c.starting:
+			case <-time.After(c.StopTimeout):
+				m.logger.Warn("component is still starting, stopping it anyway", zap.String("component", c.Name))
+			}
+		}
+
+		if err := call(context.Background(), c.StopTimeout, c.Stop); err != nil {
+			m.logger.Error("component failed to stop",
+				zap.String("component", c.Name),
+				zap.Error(err),
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name, err))
+			continue
+		}
+
+		m.logger.Info("component stopped",
+			zap.String("component", c.Name),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run запускает компоненты, ждет отмены контекста и останавливает их
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return m.Stop()
+}
+
+func failedDependency(c *Component, failed map[string]bool) string {
+	for _, dep := range c.DependsOn {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// call выполняет fn с таймаутом. После таймаута fn получает отмену ctx; если fn ее игнорирует,
+// его горутина доработает в фоне, а результат уйдет в буферизованный канал без блокировки.
+func call(parent context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if fn == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// fn мог успешно завершиться одновременно с отменой - компонент тогда запущен
+		select {
+		case err := <-done:
+			if err == nil {
+				return nil
+			}
+		default:
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		return ctx.Err()
+	}
+}
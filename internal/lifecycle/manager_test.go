@@ -0,0 +1,243 @@
+package lifecycle
+
+import (
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder записывает порядок вызовов Start и Stop
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) component(name string, deps ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: deps,
+		Start: func(context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.calls, ", ")
+}
+
+func newManager(t *testing.T, components ...Component) *Manager {
+	t.Helper()
+	core, _ := observer.New(zapcore.DebugLevel)
+	m := NewManager(utils.NewLoggerWithCore(core, zap.NewAtomicLevel()))
+	for _, c := range components {
+		if err := m.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestStartOrderAndReverseStop(t *testing.T) {
+	r := &recorder{}
+	// зависимые зарегистрированы раньше зависимостей
+	m := newManager(t,
+		r.component("journey", "nats"),
+		r.component("http", "config"),
+		r.component("nats", "config"),
+		r.component("config"),
+	)
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "start config, start nats, start journey, start http, " +
+		"stop http, stop journey, stop nats, stop config"
+	if got := r.String(); got != want {
+		t.Errorf("calls:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRegisterAndOrderErrors(t *testing.T) {
+	r := &recorder{}
+	m := newManager(t, r.component("a", "b"), r.component("b", "c"), r.component("c", "a"))
+	if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("cycle: %v", err)
+	}
+
+	m = newManager(t, r.component("a", "missing"))
+	if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown component") {
+		t.Errorf("unknown dependency: %v", err)
+	}
+
+	if err := m.Register(r.component("a")); err == nil {
+		t.Error("duplicate name was registered")
+	}
+	if err := m.Register(Component{}); err == nil {
+		t.Error("component without name was registered")
+	}
+	if r.String() != "" {
+		t.Errorf("components were started: %s", r)
+	}
+}
+
+func TestCriticalFailureRollsBack(t *testing.T) {
+	r := &recorder{}
+	broken := r.component("nats", "config")
+	broken.Critical = true
+	broken.Start = func(context.Context) error { return errors.New("port in use") }
+
+	m := newManager(t, r.component("config"), r.component("tracing"), broken, r.component("http", "nats"))
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to start nats: port in use") {
+		t.Fatalf("Start = %v", err)
+	}
+
+	if want := "start config, start tracing, stop tracing, stop config"; r.String() != want {
+		t.Errorf("calls:\n got %s\nwant %s", r, want)
+	}
+	// после отката останавливать нечего
+	if err := m.Stop(); err != nil || strings.Count(r.String(), "stop") != 2 {
+		t.Errorf("second Stop = %v, calls %s", err, r)
+	}
+}
+
+func TestNonCriticalFailureSkipsDependents(t *testing.T) {
+	r := &recorder{}
+	optional := r.component("tracing")
+	optional.Start = func(context.Context) error { return errors.New("exporter unavailable") }
+
+	m := newManager(t, optional, r.component("exporter-metrics", "tracing"), r.component("http"))
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "start http, stop http"; r.String() != want {
+		t.Errorf("calls:\n got %s\nwant %s", r, want)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	exited := make(chan struct{})
+	slow := Component{
+		Name:         "slow",
+		Critical:     true,
+		StartTimeout: 50 * time.Millisecond,
+		// компонент уважает ctx: после таймаута его горутина должна завершиться
+		Start: func(ctx context.Context) error {
+			<-ctx.Done()
+			close(exited)
+			return ctx.Err()
+		},
+	}
+
+	m := newManager(t, slow)
+	started := time.Now()
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("Start = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Start took %v", elapsed)
+	}
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Error("start goroutine was left running after timeout")
+	}
+
+	r := &recorder{}
+	hanging := r.component("hanging")
+	hanging.StopTimeout = 50 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	// Stop игнорирует ctx: ошибка таймаута возвращается, а остальные компоненты все равно останавливаются
+	hanging.Stop = func(context.Context) error {
+		<-release
+		return nil
+	}
+
+	m = newManager(t, r.component("config"), hanging)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	err = m.Stop()
+	if err == nil || !strings.Contains(err.Error(), "hanging: timed out") {
+		t.Errorf("Stop = %v", err)
+	}
+	if !strings.HasSuffix(r.String(), "stop config") {
+		t.Errorf("calls: %s", r)
+	}
+}
+
+func TestStopAfterStartTimeout(t *testing.T) {
+	r := &recorder{}
+	stuck := r.component("stuck")
+	stuck.StartTimeout = 50 * time.Millisecond
+	release := make(chan struct{})
+	// Start игнорирует ctx и успевает открыть ресурсы уже после таймаута
+	stuck.Start = func(context.Context) error {
+		<-release
+		r.add("start stuck")
+		return nil
+	}
+
+	m := newManager(t, r.component("config"), stuck)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("non-critical timeout: Start = %v", err)
+	}
+
+	close(release)
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	// Stop ждет завершения зависшего Start и только потом освобождает ресурсы
+	if got := r.String(); got != "start config, start stuck, stop stuck, stop config" {
+		t.Errorf("calls: %s", got)
+	}
+}
+
+func TestStartInterrupted(t *testing.T) {
+	r := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	first := r.component("config")
+	first.Start = func(context.Context) error {
+		r.add("start config")
+		cancel()
+		return nil
+	}
+
+	m := newManager(t, first, r.component("nats", "config"))
+	if err := m.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start = %v", err)
+	}
+	if want := "start config, stop config"; r.String() != want {
+		t.Errorf("calls:\n got %s\nwant %s", r, want)
+	}
+}
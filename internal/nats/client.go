@@ -0,0 +1,40 @@
+package nats
+
+import (
+	"fmt"
+	natsgo "github.com/nats-io/nats.go"
+	"net"
+	"time"
+)
+
+// InProcessConn реализует nats.InProcessConnProvider: соединение через net.Pipe с текущим
+// встроенным сервером, без TCP. После ReloadConfig клиент переподключается уже к новому серверу.
+func (s *Server) InProcessConn() (net.Conn, error) {
+	srv := s.inProcess.Load()
+	if srv == nil || !srv.Running() {
+		return nil, fmt.Errorf("NATS server is not running")
+	}
+	return srv.InProcessConn()
+}
+
+// ClientConn подключает внутренний компонент сервиса (индекс, мониторинг) к встроенному серверу
+// in-process, поэтому работает и при NATS_NO_LISTEN. Переподключается бесконечно, чтобы пережить ReloadConfig.
+// При включенной авторизации подключается как internalUser, а не с паролем из конфигурации.
+func (s *Server) ClientConn(name string) (*natsgo.Conn, error) {
+	opts := []natsgo.Option{
+		natsgo.InProcessServer(s),
+		natsgo.Name(fmt.Sprintf("%s-%s", s.config.AppName, name)),
+		natsgo.MaxReconnects(-1),
+		natsgo.ReconnectWait(time.Second),
+	}
+
+	if s.config.NATSHasAuth() {
+		opts = append(opts, natsgo.UserInfo(internalUser, s.internalPass))
+	}
+
+	nc, err := natsgo.Connect(s.config.GetNATSURL(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect internal client %s: %w", name, err)
+	}
+	return nc, nil
+}
@@ -0,0 +1,151 @@
+package nats
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// уровни встроенного сервера, по которым считаются отброшенные строки
+var serverLogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// natsLoggerAdapter передает логи встроенного сервера в zap с собственным уровнем
+// и сэмплированием по шаблону сообщения
+type natsLoggerAdapter struct {
+	logger  *utils.Logger
+	sampler *logSampler
+	dropped map[string]*atomic.Uint64
+}
+
+func newNATSLoggerAdapter(cfg *config.Config, logger *utils.Logger) (*natsLoggerAdapter, error) {
+	if cfg.NATSLogLevel != "" {
+		level, err := zap.ParseAtomicLevel(cfg.NATSLogLevel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NATS log level: %w", err)
+		}
+		logger = logger.WithLevel(level)
+	}
+
+	n := &natsLoggerAdapter{
+		logger:  logger.WithFields(zap.String("component", "nats-server")),
+		sampler: newLogSampler(cfg.NATSLogSampleInitial, cfg.NATSLogSampleThereafter, cfg.NATSLogSampleInterval),
+		dropped: make(map[string]*atomic.Uint64, len(serverLogLevels)),
+	}
+	for _, name := range serverLogLevels {
+		n.dropped[name] = &atomic.Uint64{}
+	}
+	return n, nil
+}
+
+func (n *natsLoggerAdapter) Noticef(format string, v ...interface{}) {
+	n.log(zapcore.InfoLevel, "info", format, v)
+}
+
+func (n *natsLoggerAdapter) Errorf(format string, v ...interface{}) {
+	n.log(zapcore.ErrorLevel, "error", format, v)
+}
+
+func (n *natsLoggerAdapter) Fatalf(format string, v ...interface{}) {
+	n.logger.Fatal(fmt.Sprintf(format, v...))
+}
+
+func (n *natsLoggerAdapter) Warnf(format string, v ...interface{}) {
+	n.log(zapcore.WarnLevel, "warn", format, v)
+}
+
+func (n *natsLoggerAdapter) Debugf(format string, v ...interface{}) {
+	n.log(zapcore.DebugLevel, "debug", format, v)
+}
+
+func (n *natsLoggerAdapter) Tracef(format string, v ...interface{}) {
+	n.log(zapcore.DebugLevel, "trace", format, v)
+}
+
+func (n *natsLoggerAdapter) log(level zapcore.Level, name, format string, v []interface{}) {
+	if !n.logger.Core().Enabled(level) {
+		return
+	}
+	if !n.sampler.allow(name + ":" + templateKey(format)) {
+		n.dropped[name].Add(1)
+		return
+	}
+	msg, fields := parseServerLine(fmt.Sprintf(format, v...))
+	n.logger.Log(level, msg, fields...)
+}
+
+// templateKey - ключ сэмплирования. Сервер вписывает префикс клиента ("127.0.0.1:53422 - cid:7 - ")
+// прямо в format, поэтому числа заменяются на #, чтобы строки разных клиентов попадали в один шаблон.
+func templateKey(format string) string {
+	key := make([]byte, 0, len(format))
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c < '0' || c > '9' {
+			key = append(key, c)
+			continue
+		}
+		if len(key) == 0 || key[len(key)-1] != '#' {
+			key = append(key, '#')
+		}
+	}
+	return string(key)
+}
+
+func (n *natsLoggerAdapter) droppedCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(n.dropped))
+	for name, c := range n.dropped {
+		counts[name] = c.Load()
+	}
+	return counts
+}
+
+// logSampler пропускает первые initial строк каждого шаблона за interval, затем каждую thereafter-ю
+type logSampler struct {
+	initial    uint64
+	thereafter uint64
+	interval   time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]uint64
+	now         func() time.Time
+}
+
+// newLogSampler возвращает nil, если сэмплирование выключено (initial == 0)
+func newLogSampler(initial, thereafter int, interval time.Duration) *logSampler {
+	if initial <= 0 {
+		return nil
+	}
+	return &logSampler{
+		initial:    uint64(initial),
+		thereafter: uint64(max(thereafter, 0)),
+		interval:   interval,
+		counts:     make(map[string]uint64),
+		now:        time.Now,
+	}
+}
+
+func (s *logSampler) allow(key string) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.windowStart) >= s.interval {
+		s.windowStart = now
+		clear(s.counts)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,142 @@
+package nats
+
+import (
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+func TestLogSampler(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := newLogSampler(2, 3, time.Second)
+	s.now = func() time.Time { return now }
+
+	var got []bool
+	for i := 0; i < 8; i++ {
+		got = append(got, s.allow("client connected"))
+	}
+	want := []bool{true, true, false, false, true, false, false, true}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("allow #%d = %v, want %v (all: %v)", i+1, got[i], want[i], got)
+		}
+	}
+
+	if !s.allow("other template") {
+		t.Fatal("templates must be sampled independently")
+	}
+
+	now = now.Add(time.Second)
+	if !s.allow("client connected") {
+		t.Fatal("counter must reset after the interval")
+	}
+}
+
+func TestLogSamplerDisabled(t *testing.T) {
+	s := newLogSampler(0, 100, time.Second)
+	for i := 0; i < 1000; i++ {
+		if !s.allow("x") {
+			t.Fatal("disabled sampler dropped a line")
+		}
+	}
+}
+
+func TestTemplateKey(t *testing.T) {
+	a := templateKey(`127.0.0.1:53422 - cid:7 - "v1.48.0:go" - Client connection created`)
+	b := templateKey(`10.0.0.12:40100 - cid:12 - "v1.48.0:go" - Client connection created`)
+	if a != b {
+		t.Fatalf("client prefixes produce different keys: %q != %q", a, b)
+	}
+	if templateKey("Slow consumer") == templateKey("Client connection closed") {
+		t.Fatal("different messages share a key")
+	}
+}
+
+func TestParseServerLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		msg    string
+		fields map[string]interface{}
+	}{
+		{
+			line: `127.0.0.1:45864 - cid:5 - Client connection created`,
+			msg:  "Client connection created",
+			fields: map[string]interface{}{
+				"remote_addr": "127.0.0.1:45864", "conn_kind": "client", "client_id": uint64(5), "event": "client_connect",
+			},
+		},
+		{
+			line: `10.0.0.7:51200 - cid:12 - "v1.48.0:go:arbitrage" - "$G/user:bob" - Client connection closed: Client Closed`,
+			msg:  "Client connection closed: Client Closed",
+			fields: map[string]interface{}{
+				"remote_addr": "10.0.0.7:51200", "client_id": uint64(12), "client": "v1.48.0:go:arbitrage",
+				"account": "$G", "user": "user:bob", "event": "client_disconnect", "reason": "Client Closed",
+			},
+		},
+		{
+			line:   `10.0.0.7:51200 - cid:13 - authentication error - User "bob"`,
+			msg:    `authentication error - User "bob"`,
+			fields: map[string]interface{}{"client_id": uint64(13), "event": "auth_failure"},
+		},
+		{
+			line:   `10.0.0.7:51200 - cid:14 - "v1.48.0:go" - Slow Consumer Detected: WriteDeadline of 10s exceeded with 3 chunks of 1024 total bytes.`,
+			msg:    `Slow Consumer Detected: WriteDeadline of 10s exceeded with 3 chunks of 1024 total bytes.`,
+			fields: map[string]interface{}{"client_id": uint64(14), "event": "slow_consumer"},
+		},
+		{
+			line:   `JetStream cluster new consumer leader for '$G > EVENTS > arbitrage'`,
+			msg:    `JetStream cluster new consumer leader for '$G > EVENTS > arbitrage'`,
+			fields: map[string]interface{}{"event": "consumer_leader", "account": "$G", "stream": "EVENTS", "consumer": "arbitrage"},
+		},
+		{
+			line:   `JetStream cluster new metadata leader: nats-1/cluster`,
+			fields: map[string]interface{}{"event": "meta_leader", "leader": "nats-1/cluster"},
+		},
+		{
+			line:   `127.0.0.1:40000 - cid:9 - "v1.48.0:go" - <<- [PUB $JS.API.CONSUMER.CREATE.EVENTS.monitor.events.match.monitoring _INBOX.x 120]`,
+			fields: map[string]interface{}{"event": "consumer_create", "stream": "EVENTS", "consumer": "monitor"},
+		},
+		{
+			line:   `127.0.0.1:40000 - cid:9 - <<- [PUB $JS.API.STREAM.CREATE.EVENTS _INBOX.x 300]`,
+			fields: map[string]interface{}{"event": "stream_create", "stream": "EVENTS"},
+		},
+		{
+			line:   `SYSTEM - System connection closed: Client Closed`,
+			msg:    "System connection closed: Client Closed",
+			fields: map[string]interface{}{"conn_kind": "system", "event": "connection_closed", "reason": "Client Closed"},
+		},
+		{
+			line:   `Enabled JetStream for account "$G"`,
+			msg:    `Enabled JetStream for account "$G"`,
+			fields: map[string]interface{}{"account": "$G"},
+		},
+		{
+			line:   `Server is ready`,
+			msg:    `Server is ready`,
+			fields: map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		msg, fields := parseServerLine(tt.line)
+		if tt.msg != "" && msg != tt.msg {
+			t.Errorf("%s: msg = %q, want %q", tt.line, msg, tt.msg)
+		}
+
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+		if len(enc.Fields) != len(fields) {
+			t.Errorf("%s: duplicate field keys in %v", tt.line, enc.Fields)
+		}
+		for k, v := range tt.fields {
+			if enc.Fields[k] != v {
+				t.Errorf("%s: field %s = %v, want %v", tt.line, k, enc.Fields[k], v)
+			}
+		}
+		if len(tt.fields) == 0 && len(fields) != 0 {
+			t.Errorf("%s: unexpected fields %v", tt.line, enc.Fields)
+		}
+	}
+}
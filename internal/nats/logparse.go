@@ -0,0 +1,152 @@
+package nats
+
+import (
+	"go.uber.org/zap"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// "127.0.0.1:53422 - cid:7 - "v1.48.0:go:name" - "$G/user:bob" - <сообщение>"
+	clientPrefixRe = regexp.MustCompile(`^(\S+) - (cid|wid|rid|gid|lid|lid_ws|mid|mid_ws):(\d+)((?: - "[^"]*")*) - (.*)$`)
+	quotedRe       = regexp.MustCompile(`"([^"]*)"`)
+	// внутренние соединения сервера
+	internalPrefixRe = regexp.MustCompile(`^(SYSTEM|JETSTREAM|ACCOUNT) - (.*)$`)
+
+	consumerRefRe = regexp.MustCompile(`'([^'\s]+) > ([^'\s]+) > ([^'\s]+)'`)
+	streamRefRe   = regexp.MustCompile(`'([^'\s]+) > ([^'\s]+)'`)
+	accountRefRe  = regexp.MustCompile(`(?:account "|Account:)([^"\s]+)`)
+
+	streamAPIRe   = regexp.MustCompile(`\$JS\.API\.STREAM\.(CREATE|UPDATE|DELETE|PURGE)\.([^.\s\]]+)`)
+	consumerAPIRe = regexp.MustCompile(`\$JS\.API\.CONSUMER\.(?:DURABLE\.)?(CREATE|DELETE)\.([^.\s\]]+)(?:\.([^.\s\]]+))?`)
+)
+
+var connKinds = map[string]string{
+	"cid":    "client",
+	"wid":    "websocket",
+	"rid":    "route",
+	"gid":    "gateway",
+	"lid":    "leafnode",
+	"lid_ws": "leafnode",
+	"mid":    "mqtt",
+	"mid_ws": "mqtt",
+}
+
+// serverEvent - узнаваемое сообщение сервера и имя события для поля event
+type serverEvent struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// порядок важен: берется первое совпадение
+var serverEvents = []serverEvent{
+	{"client_connect", regexp.MustCompile(`^Client connection created`)},
+	{"client_disconnect", regexp.MustCompile(`^Client connection closed: (.*)$`)},
+	{"connection_closed", regexp.MustCompile(`^\w+ connection closed: (.*)$`)},
+	{"slow_consumer_recovered", regexp.MustCompile(`^Slow Consumer Recovered`)},
+	{"slow_consumer", regexp.MustCompile(`^Slow Consumer`)},
+	{"auth_failure", regexp.MustCompile(`(?i)authentication error|authorization violation|authentication timeout`)},
+	{"stream_create_failed", regexp.MustCompile(`^Stream create failed`)},
+	{"stream_leader", regexp.MustCompile(`^JetStream cluster new stream leader`)},
+	{"consumer_leader", regexp.MustCompile(`^JetStream cluster new consumer leader`)},
+	{"meta_leader", regexp.MustCompile(`^JetStream cluster new metadata leader: (\S+)`)},
+}
+
+// parseServerLine разбирает строку встроенного сервера: префикс клиента превращается в поля,
+// узнаваемые сообщения получают поле event, ссылки на аккаунт/стрим/консюмер - свои поля.
+// Возвращает сообщение без префикса клиента.
+func parseServerLine(line string) (string, []zap.Field) {
+	var fields []zap.Field
+	msg := line
+
+	if m := clientPrefixRe.FindStringSubmatch(line); m != nil {
+		msg = m[5]
+		fields = append(fields,
+			zap.String("remote_addr", m[1]),
+			zap.String("conn_kind", connKinds[m[2]]),
+		)
+		if id, err := strconv.ParseUint(m[3], 10, 64); err == nil {
+			fields = append(fields, zap.Uint64("client_id", id))
+		}
+		for _, q := range quotedRe.FindAllStringSubmatch(m[4], -1) {
+			fields = append(fields, parseClientLabel(q[1])...)
+		}
+	} else if m := internalPrefixRe.FindStringSubmatch(line); m != nil {
+		msg = m[2]
+		fields = append(fields, zap.String("conn_kind", strings.ToLower(m[1])))
+	}
+
+	for _, ev := range serverEvents {
+		m := ev.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		fields = append(fields, zap.String("event", ev.name))
+		switch ev.name {
+		case "client_disconnect", "connection_closed":
+			fields = append(fields, zap.String("reason", m[1]))
+		case "meta_leader":
+			fields = append(fields, zap.String("leader", m[1]))
+		}
+		break
+	}
+
+	fields = append(fields, parseJetStreamRefs(msg)...)
+	return msg, uniqueFields(fields)
+}
+
+// uniqueFields оставляет первое поле с каждым ключом (аккаунт может прийти и из префикса, и из текста)
+func uniqueFields(fields []zap.Field) []zap.Field {
+	seen := make(map[string]bool, len(fields))
+	result := fields[:0]
+	for _, f := range fields {
+		if seen[f.Key] {
+			continue
+		}
+		seen[f.Key] = true
+		result = append(result, f)
+	}
+	return result
+}
+
+// parseClientLabel разбирает подпись из префикса: "$G/user:bob" (аккаунт и пользователь) или "v1.48.0:go:name"
+func parseClientLabel(label string) []zap.Field {
+	if acc, user, ok := strings.Cut(label, "/"); ok && isAuthLabel(user) {
+		return []zap.Field{zap.String("account", acc), zap.String("user", user)}
+	}
+	return []zap.Field{zap.String("client", label)}
+}
+
+func isAuthLabel(s string) bool {
+	return s == "token" || strings.HasPrefix(s, "user:") || strings.HasPrefix(s, "nkey:") || strings.HasPrefix(s, "jwt:")
+}
+
+func parseJetStreamRefs(msg string) []zap.Field {
+	if m := consumerAPIRe.FindStringSubmatch(msg); m != nil {
+		fields := []zap.Field{
+			zap.String("event", "consumer_"+strings.ToLower(m[1])),
+			zap.String("stream", m[2]),
+		}
+		if m[3] != "" {
+			fields = append(fields, zap.String("consumer", m[3]))
+		}
+		return fields
+	}
+	if m := streamAPIRe.FindStringSubmatch(msg); m != nil {
+		return []zap.Field{
+			zap.String("event", "stream_"+strings.ToLower(m[1])),
+			zap.String("stream", m[2]),
+		}
+	}
+	if m := consumerRefRe.FindStringSubmatch(msg); m != nil {
+		return []zap.Field{zap.String("account", m[1]), zap.String("stream", m[2]), zap.String("consumer", m[3])}
+	}
+	if m := streamRefRe.FindStringSubmatch(msg); m != nil {
+		return []zap.Field{zap.String("account", m[1]), zap.String("stream", m[2])}
+	}
+	if m := accountRefRe.FindStringSubmatch(msg); m != nil {
+		return []zap.Field{zap.String("account", m[1])}
+	}
+	return nil
+}
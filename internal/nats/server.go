@@ -2,24 +2,40 @@ package nats
 
 import (
 	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
 	"NATS_TIRE_SERVICE/pkg/utils"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"github.com/nats-io/nats-server/v2/server"
 	"go.uber.org/zap"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// readyCheckTimeout - ReadyForConnections с нулевым таймаутом всегда возвращает false
+const readyCheckTimeout = 10 * time.Millisecond
+
+// internalUser - пользователь внутренних компонентов сервиса. Его пароль случайный и живет только в памяти,
+// поэтому внутренние клиенты работают и тогда, когда NATS_PASSWORD задан bcrypt хешем.
+const internalUser = "_internal"
+
 type Server struct {
 	config         *config.Config
 	logger         *utils.Logger
 	natsServer     *server.Server
 	serverOpts     *server.Options
-	running        bool
+	internalPass   string
+	running        atomic.Bool
+	draining       atomic.Bool
+	serverDebug    atomic.Bool
+	serverTrace    atomic.Bool
+	serverLog      *natsLoggerAdapter
+	inProcess      atomic.Pointer[server.Server] // текущий сервер для in-process подключений, меняется при ReloadConfig
 	startTime      time.Time
 	shutdownCtx    context.Context
 	shutdownCancel context.CancelFunc
@@ -27,25 +43,48 @@ type Server struct {
 
 func NewServer(cfg *config.Config, logger *utils.Logger) (*Server, error) {
 
-	ctx, cancel := context.WithCancel(context.Background())
+	internalPass, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
 
-	opts, err := prepareOptions(cfg)
+	opts, err := prepareOptions(cfg, internalPass)
 	if err != nil {
 		return nil, fmt.Errorf("error preparing NATS options: %w", err)
 	}
 
-	return &Server{
+	serverLog, err := newNATSLoggerAdapter(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Server{
 		config:         cfg,
 		logger:         logger,
 		serverOpts:     opts,
-		running:        false,
+		internalPass:   internalPass,
+		serverLog:      serverLog,
 		shutdownCtx:    ctx,
 		shutdownCancel: cancel,
-	}, nil
+	}
+	s.serverDebug.Store(cfg.NATSDebug)
+	s.serverTrace.Store(cfg.NATSTrace)
+
+	return s, nil
+
+}
 
+func randomPassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate internal client password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
 }
 
-func prepareOptions(cfg *config.Config) (*server.Options, error) {
+func prepareOptions(cfg *config.Config, internalPass string) (*server.Options, error) {
 
 	opts := &server.Options{
 		Host:          "0.0.0.0",
@@ -60,16 +99,37 @@ func prepareOptions(cfg *config.Config) (*server.Options, error) {
 		MaxPingsOut:   2,
 		WriteDeadline: 10 * time.Second,
 		HTTPHost:      "0.0.0.0",
+		NoSigs:        true,
+		DontListen:    cfg.NATSNoListen,
+	}
+
+	if cfg.LameDuckEnabled() {
+		opts.LameDuckDuration = cfg.LameDuckDuration
+		opts.LameDuckGracePeriod = cfg.LameDuckGracePeriod
 	}
 
 	if cfg.JetStreamEnabled {
-		opts.JetStreamMaxMemory = parseMemorySize(cfg.MaxMemoryStore)
-		opts.JetStreamMaxStore = parseMemorySize(cfg.MaxFileStore)
+		opts.JetStreamMaxMemory = cfg.MaxMemoryStore.Bytes()
+		opts.JetStreamMaxStore = cfg.MaxFileStore.Bytes()
 	}
 
 	if cfg.NATSHasAuth() {
-		opts.Username = cfg.NATSUsername
-		opts.Password = cfg.NATSPassword
+		users, err := prepareUsers(cfg, internalPass)
+		if err != nil {
+			return nil, err
+		}
+		opts.Users = users
+	}
+
+	if cfg.ClusterEnabled() {
+		opts.Cluster = server.ClusterOpts{
+			Name: cfg.ClusterName,
+			Host: cfg.ClusterHost,
+			Port: cfg.ClusterPort,
+		}
+		if len(cfg.ClusterRoutes) > 0 {
+			opts.Routes = server.RoutesFromStr(strings.Join(cfg.ClusterRoutes, ","))
+		}
 	}
 
 	if err := prepareDataDirs(cfg); err != nil {
@@ -79,30 +139,33 @@ func prepareOptions(cfg *config.Config) (*server.Options, error) {
 	return opts, nil
 }
 
-func parseMemorySize(sizeStr string) int64 {
-
-	sizeStr = strings.ToUpper(strings.TrimSpace(sizeStr))
-
-	var multiplier int64 = 1
-	if strings.HasSuffix(sizeStr, "KB") {
-		multiplier = 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "KB")
-	} else if strings.HasSuffix(sizeStr, "MB") {
-		multiplier = 1024 * 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "MB")
-	} else if strings.HasSuffix(sizeStr, "GB") {
-		multiplier = 1024 * 1024 * 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "GB")
-	} else if strings.HasSuffix(sizeStr, "TB") {
-		multiplier = 1024 * 1024 * 1024 * 1024
-		sizeStr = strings.TrimSuffix(sizeStr, "TB")
+// prepareUsers собирает пользователей сервера: NATS_USERNAME, security.users из файла и internalUser
+func prepareUsers(cfg *config.Config, internalPass string) ([]*server.User, error) {
+	var users []*server.User
+	if cfg.NATSUsername != "" {
+		users = append(users, &server.User{Username: cfg.NATSUsername, Password: cfg.Secret(&cfg.NATSPassword).Value()})
+	}
+	for i := range cfg.NATSUsers {
+		u := &cfg.NATSUsers[i]
+		user := &server.User{Username: u.Username, Password: cfg.Secret(&u.Password).Value()}
+		if len(u.Publish) > 0 || len(u.Subscribe) > 0 {
+			user.Permissions = &server.Permissions{}
+			if len(u.Publish) > 0 {
+				user.Permissions.Publish = &server.SubjectPermission{Allow: u.Publish}
+			}
+			if len(u.Subscribe) > 0 {
+				user.Permissions.Subscribe = &server.SubjectPermission{Allow: u.Subscribe}
+			}
+		}
+		users = append(users, user)
 	}
 
-	var size int64
-	fmt.Sscanf(sizeStr, "%d", &size)
-
-	return size * multiplier
-
+	for _, u := range users {
+		if u.Username == internalUser {
+			return nil, fmt.Errorf("username %q is reserved for internal clients", internalUser)
+		}
+	}
+	return append(users, &server.User{Username: internalUser, Password: internalPass}), nil
 }
 
 func prepareDataDirs(cfg *config.Config) error {
@@ -123,11 +186,14 @@ func prepareDataDirs(cfg *config.Config) error {
 func (s *Server) Start() error {
 	s.logger.Infof("starting NATS server...")
 	s.startTime = time.Now()
+	// Stop отменяет контекст, поэтому после перезапуска (ReloadConfig) нужен новый
+	s.shutdownCtx, s.shutdownCancel = context.WithCancel(context.Background())
 
 	s.natsServer = server.New(s.serverOpts)
 	if s.natsServer == nil {
 		return fmt.Errorf("failed to create NATS server")
 	}
+	s.inProcess.Store(s.natsServer)
 
 	s.configureNATSLogger()
 
@@ -136,20 +202,19 @@ func (s *Server) Start() error {
 		zap.Int("port", s.serverOpts.Port),
 		zap.Int("http_port", s.serverOpts.HTTPPort),
 		zap.Bool("jetstream", s.serverOpts.JetStream),
-		zap.Bool("auth_required", s.serverOpts.Username != ""),
+		zap.Bool("auth_required", s.config.NATSHasAuth()),
 		zap.String("data_dir", s.serverOpts.StoreDir),
 	)
 
-	go func() {
-		s.natsServer.ConfigureLogger()
-		s.natsServer.Start()
-	}()
+	// ConfigureLogger не вызываем: он заменил бы natsLoggerAdapter стандартным логгером сервера
+	go s.natsServer.Start()
 
 	if err := s.waitForStart(30 * time.Second); err != nil {
 		return fmt.Errorf("failed to start NATS server: %w", err)
 	}
 
-	s.running = true
+	s.running.Store(true)
+	s.draining.Store(false)
 	s.logServerInfo()
 
 	s.logger.Infof("NATS server started")
@@ -161,63 +226,36 @@ func (s *Server) configureNATSLogger() {
 		return
 	}
 
-	natsLogger := &natsLoggerAdapter{logger: s.logger}
-	s.natsServer.SetLogger(natsLogger, s.config.IsDevelopment(), s.config.IsDevelopment())
-}
-
-type natsLoggerAdapter struct {
-	logger *utils.Logger
-}
-
-func (n *natsLoggerAdapter) Noticef(format string, v ...interface{}) {
-	n.logger.Info(fmt.Sprintf(format, v...))
-}
-
-func (n *natsLoggerAdapter) Errorf(format string, v ...interface{}) {
-	n.logger.Error(fmt.Sprintf(format, v...))
-}
-
-func (n *natsLoggerAdapter) Fatalf(format string, v ...interface{}) {
-	n.logger.Fatal(fmt.Sprintf(format, v...))
+	s.natsServer.SetLogger(s.serverLog, s.serverDebug.Load(), s.serverTrace.Load())
 }
 
-func (n *natsLoggerAdapter) Warnf(format string, v ...interface{}) {
-	n.logger.Warn(fmt.Sprintf(format, v...))
+// ServerLogging возвращает состояние debug/trace логов встроенного сервера
+func (s *Server) ServerLogging() (debug, trace bool) {
+	return s.serverDebug.Load(), s.serverTrace.Load()
 }
 
-func (n *natsLoggerAdapter) Debugf(format string, v ...interface{}) {
-	n.logger.Debug(fmt.Sprintf(format, v...))
-}
+// SetServerLogging включает debug/trace логи встроенного сервера без перезапуска.
+// Trace применяется к новым клиентским подключениям.
+func (s *Server) SetServerLogging(debug, trace bool) {
+	s.serverDebug.Store(debug)
+	s.serverTrace.Store(trace)
+	s.configureNATSLogger()
 
-func (n *natsLoggerAdapter) Tracef(format string, v ...interface{}) {
-	n.logger.Debug(fmt.Sprintf(format, v...))
+	s.logger.Info("embedded server logging changed",
+		zap.Bool("debug", debug),
+		zap.Bool("trace", trace),
+	)
 }
 
+// waitForStart ждет готовности сервера без сетевых запросов: при NATS_NO_LISTEN
+// ReadyForConnections дожидается окончания запуска, а не TCP листенера
 func (s *Server) waitForStart(timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	monitoringURL := fmt.Sprintf("http://localhost:%d", s.serverOpts.HTTPPort)
-
-	for time.Now().Before(deadline) {
-		resp, err := http.Get(monitoringURL + "/varz")
-		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				s.logger.Info("NATS server monitoring is ready",
-					zap.String("url", monitoringURL),
-				)
-				return nil
-			}
-		}
-
-		if s.natsServer != nil && s.natsServer.ReadyForConnections(100*time.Millisecond) {
-			s.logger.Info("NATS server is ready for connections")
-			return nil
-		}
-
-		time.Sleep(500 * time.Millisecond)
+	if !s.natsServer.ReadyForConnections(timeout) {
+		return fmt.Errorf("server failed to start within %v", timeout)
 	}
 
-	return fmt.Errorf("server failed to start within %v", timeout)
+	s.logger.Info("NATS server is ready for connections", zap.Bool("client_listener", !s.serverOpts.DontListen))
+	return nil
 }
 
 func (s *Server) logServerInfo() {
@@ -232,7 +270,7 @@ func (s *Server) logServerInfo() {
 		zap.String("client_url", s.config.GetNATSURL()),
 		zap.String("monitoring_url", s.config.GetMonitoringURL()),
 		zap.Bool("jetstream", s.config.JetStreamEnabled),
-		zap.Bool("auth_required", s.serverOpts.Username != ""),
+		zap.Bool("auth_required", s.config.NATSHasAuth()),
 	)
 }
 
@@ -246,8 +284,32 @@ func (s *Server) Stop() error {
 		return nil
 	}
 
-	s.natsServer.Shutdown()
+	stopStart := time.Now()
+	s.draining.Store(true)
+
+	if s.natsServer.Running() && s.config.ShutdownDrainDelay > 0 {
+		s.logger.Info("draining NATS server before shutdown",
+			zap.Duration("drain_delay", s.config.ShutdownDrainDelay),
+			zap.Int("clients", s.natsServer.NumClients()),
+		)
+		time.Sleep(s.config.ShutdownDrainDelay)
+	}
+	drainElapsed := time.Since(stopStart)
+
+	lameDuckStart := time.Now()
+	if s.natsServer.Running() && s.config.LameDuckEnabled() {
+		s.logger.Info("entering lame duck mode",
+			zap.Duration("duration", s.config.LameDuckDuration),
+			zap.Duration("grace_period", s.config.LameDuckGracePeriod),
+			zap.Int("clients", s.natsServer.NumClients()),
+		)
+		s.natsServer.LameDuckShutdown()
+	} else {
+		s.natsServer.Shutdown()
+	}
+	lameDuckElapsed := time.Since(lameDuckStart)
 
+	shutdownStart := time.Now()
 	timeout := 10 * time.Second
 	deadline := time.Now().Add(timeout)
 
@@ -260,16 +322,27 @@ func (s *Server) Stop() error {
 		return fmt.Errorf("NATS server shotdown timeout")
 	}
 
-	s.running = false
-	s.logger.Infof("NATS server stopped gracefully",
+	s.running.Store(false)
+	s.logger.Info("NATS server stopped gracefully",
 		zap.Duration("uptime", time.Since(s.startTime)),
+		zap.Duration("drain", drainElapsed),
+		zap.Duration("lame_duck", lameDuckElapsed),
+		zap.Duration("shutdown", time.Since(shutdownStart)),
+		zap.Duration("total", time.Since(stopStart)),
 	)
 
 	return nil
 }
 
 func (s *Server) IsRunning() bool {
-	return s.running && s.natsServer != nil && s.natsServer.Running()
+	// inProcess, а не natsServer: IsRunning вызывают из HTTP обработчиков параллельно с ReloadConfig
+	srv := s.inProcess.Load()
+	return s.running.Load() && srv != nil && srv.Running()
+}
+
+// IsDraining сообщает, что сервер находится в фазе остановки и не должен получать новый трафик
+func (s *Server) IsDraining() bool {
+	return s.draining.Load()
 }
 
 func (s *Server) GetInfo() map[string]interface{} {
@@ -280,26 +353,41 @@ func (s *Server) GetInfo() map[string]interface{} {
 	}
 
 	info := map[string]interface{}{
-		"running":        s.IsRunning(),
-		"uptime":         time.Since(s.startTime).String(),
-		"server_name":    s.serverOpts.ServerName,
-		"port":           s.serverOpts.Port,
-		"http_port":      s.serverOpts.HTTPPort,
-		"client_url":     s.config.GetNATSURL(),
-		"monitoring_url": s.config.GetMonitoringURL(),
-		"jetstream":      s.config.JetStreamEnabled,
-		"auth_required":  s.serverOpts.Username != "",
-		"data_dir":       s.serverOpts.StoreDir,
+		"running":         s.IsRunning(),
+		"draining":        s.IsDraining(),
+		"uptime":          time.Since(s.startTime).String(),
+		"server_name":     s.serverOpts.ServerName,
+		"port":            s.serverOpts.Port,
+		"http_port":       s.serverOpts.HTTPPort,
+		"client_url":      s.config.GetNATSURL(),
+		"client_listener": !s.serverOpts.DontListen,
+		"monitoring_url":  s.config.GetMonitoringURL(),
+		"jetstream":       s.config.JetStreamEnabled,
+		"auth_required":   s.config.NATSHasAuth(),
+		"auth_mode":       s.authMode(),
+		"data_dir":        s.serverOpts.StoreDir,
 	}
 
 	if s.natsServer.Running() {
-		info["ready"] = s.natsServer.ReadyForConnections(0)
+		info["ready"] = s.natsServer.ReadyForConnections(readyCheckTimeout)
 		info["clients"] = s.getClientCount()
 	}
 
 	return info
 }
 
+// authMode описывает способ аутентификации, не раскрывая сам пароль
+func (s *Server) authMode() string {
+	switch {
+	case !s.config.NATSHasAuth():
+		return "none"
+	case s.config.Secret(&s.config.NATSPassword).IsBcrypt():
+		return "bcrypt"
+	default:
+		return "plain"
+	}
+}
+
 // TODO: реализовать получения количество подключений через мониторинг
 func (s *Server) getClientCount() int {
 	return 1
@@ -315,32 +403,47 @@ func (s *Server) GetStats() map[string]interface{} {
 		"port":            s.serverOpts.Port,
 		"http_port":       s.serverOpts.HTTPPort,
 		"jetstream":       s.config.JetStreamEnabled,
-		"auth":            s.serverOpts.Username != "",
+		"auth":            s.config.NATSHasAuth(),
 		"uptime":          time.Since(s.startTime).String(),
 		"running":         s.IsRunning(),
-		"ready":           s.natsServer.ReadyForConnections(0),
+		"ready":           s.natsServer.ReadyForConnections(readyCheckTimeout),
 		"max_connections": s.serverOpts.MaxConn,
-		"max_payload":     s.serverOpts.MaxPayload,
+		"max_payload":     domain.ByteSize(s.serverOpts.MaxPayload).String(),
+		"max_memory":      s.config.MaxMemoryStore.String(),
+		"max_store":       s.config.MaxFileStore.String(),
 		"ping_interval":   s.serverOpts.PingInterval.String(),
 		"write_deadline":  s.serverOpts.WriteDeadline.String(),
+		"lame_duck":       s.serverOpts.LameDuckDuration.String(),
+		"draining":        s.IsDraining(),
+		"log_dropped":     s.LogDropped(),
 	}
 }
 
+// LogDropped возвращает число строк встроенного сервера, отброшенных сэмплированием, по уровням
+func (s *Server) LogDropped() map[string]uint64 {
+	return s.serverLog.droppedCounts()
+}
+
 func (s *Server) ReloadConfig() error {
 	s.logger.Infof("reloading NATS server configurations...")
 	s.logger.Warnf("configurations reload requires server restart")
 
-	if err := s.Stop(); err != nil {
-		return fmt.Errorf("failed to stop NATS server while reloading configuirations: %w", err)
+	// секреты и опции готовятся до остановки: при ошибке старый сервер продолжает работать
+	if err := s.config.RefreshSecrets(); err != nil {
+		return fmt.Errorf("failed to refresh secrets while reloading configurations: %w", err)
 	}
 
-	time.Sleep(1 * time.Second)
-
-	opts, err := prepareOptions(s.config)
+	opts, err := prepareOptions(s.config, s.internalPass)
 	if err != nil {
 		return fmt.Errorf("failed to prepare new NATS options for reloading options: %w", err)
 	}
 
+	if err := s.Stop(); err != nil {
+		return fmt.Errorf("failed to stop NATS server while reloading configuirations: %w", err)
+	}
+
+	time.Sleep(1 * time.Second)
+
 	s.serverOpts = opts
 
 	if err := s.Start(); err != nil {
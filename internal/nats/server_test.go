@@ -0,0 +1,245 @@
+package nats_test
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/natstest"
+	"context"
+	"errors"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func TestServerStartStop(t *testing.T) {
+	h := natstest.Start(t, natstest.WithStreams(natstest.EventsStream()))
+
+	if !h.Server.IsRunning() || !h.Server.ReadyForConnections(time.Second) {
+		t.Fatal("server is not running after Start")
+	}
+	if h.Server.IsDraining() {
+		t.Fatal("server is draining after Start")
+	}
+
+	h.Publish(domain.SubjectBundleMatch, []byte(`{"payload":{"CorrelationID":1}}`))
+
+	if err := h.Server.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Server.IsRunning() {
+		t.Fatal("server is running after Stop")
+	}
+	if !h.Server.IsDraining() {
+		t.Fatal("stopped server is not reported as draining")
+	}
+	if stats := h.Server.GetStats(); stats != nil {
+		t.Fatalf("stats of stopped server = %v, want nil", stats)
+	}
+}
+
+func TestServerDrain(t *testing.T) {
+	h := natstest.Start(t, natstest.WithConfig(func(cfg *config.Config) {
+		cfg.ShutdownDrainDelay = 500 * time.Millisecond
+	}))
+
+	// после перезапуска остановка снова проходит все фазы
+	if err := h.Server.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Server.IsDraining() {
+		t.Fatal("server is draining after reload")
+	}
+
+	stopped := make(chan error, 1)
+	start := time.Now()
+	go func() { stopped <- h.Server.Stop() }()
+
+	// во время паузы сервер уже сообщает о draining, но еще принимает клиентов
+	natstest.Eventually(t, time.Second, h.Server.IsDraining, "server is not draining during stop")
+	if !h.Server.IsRunning() {
+		t.Error("server stopped before the drain delay")
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Stop took %v, want at least the drain delay", elapsed)
+	}
+	if h.Server.IsRunning() {
+		t.Fatal("server is running after Stop")
+	}
+}
+
+func TestServerGetInfo(t *testing.T) {
+	h := natstest.Start(t)
+
+	info := h.Server.GetInfo()
+	want := map[string]interface{}{
+		"running":    true,
+		"draining":   false,
+		"port":       h.Config.NATSPort,
+		"http_port":  h.Config.NATSHTTPPort,
+		"client_url": h.Config.GetNATSURL(),
+		"jetstream":  true,
+		"auth_mode":  "none",
+		"data_dir":   h.Config.DataDir,
+		"ready":      true,
+	}
+	for key, value := range want {
+		if info[key] != value {
+			t.Errorf("GetInfo()[%s] = %v, want %v", key, info[key], value)
+		}
+	}
+
+	stats := h.Server.GetStats()
+	if stats["max_memory"] != "64MiB" || stats["max_store"] != "256MiB" {
+		t.Errorf("stats limits = %v/%v", stats["max_memory"], stats["max_store"])
+	}
+	if _, ok := stats["log_dropped"]; !ok {
+		t.Error("stats have no log_dropped")
+	}
+}
+
+func TestServerReloadConfig(t *testing.T) {
+	h := natstest.Start(t, natstest.WithStreams(natstest.EventsStream()))
+	h.Publish(domain.SubjectBundleMatch, []byte(`{"payload":{"CorrelationID":1}}`))
+
+	if err := h.Server.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if !h.Server.IsRunning() || h.Server.IsDraining() {
+		t.Fatal("server is not serving after ReloadConfig")
+	}
+
+	// сообщения стрима на диске переживают перезапуск
+	js, err := jetstream.New(h.Connect("after-reload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := js.Stream(ctx, domain.StreamEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs := stream.CachedInfo().State.Msgs; msgs != 1 {
+		t.Fatalf("stream has %d messages after reload, want 1", msgs)
+	}
+
+	// клиент, подключенный до перезапуска, переподключается сам
+	natstest.Eventually(t, 5*time.Second, h.Conn.IsConnected, "harness client did not reconnect")
+}
+
+func TestServerAuth(t *testing.T) {
+	h := natstest.Start(t, natstest.WithAuth("scanner", "secret"))
+
+	if mode := h.Server.GetInfo()["auth_mode"]; mode != "plain" {
+		t.Fatalf("auth_mode = %v, want plain", mode)
+	}
+	if nc, err := natsgo.Connect(h.Config.GetNATSURL()); err == nil {
+		nc.Close()
+		t.Fatal("connected without credentials")
+	}
+	nc, err := natsgo.Connect(h.Config.GetNATSURL(), natsgo.UserInfo("scanner", "secret"))
+	if err != nil {
+		t.Fatalf("connect with credentials: %v", err)
+	}
+	nc.Close()
+	if !h.Conn.IsConnected() {
+		t.Fatal("harness client is not connected")
+	}
+}
+
+func TestServerAuthBcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// внутренние клиенты не знают пароль, только его хеш, и подключаются своим пользователем
+	h := natstest.Start(t, natstest.WithAuth("scanner", string(hash)), natstest.WithStreams(natstest.EventsStream()))
+	h.Publish(domain.SubjectBundleMatch, []byte(`{"payload":{"CorrelationID":1}}`))
+
+	if mode := h.Server.GetInfo()["auth_mode"]; mode != "bcrypt" {
+		t.Fatalf("auth_mode = %v, want bcrypt", mode)
+	}
+	nc, err := natsgo.Connect(h.Config.GetNATSURL(), natsgo.UserInfo("scanner", "secret"))
+	if err != nil {
+		t.Fatalf("connect with plain password: %v", err)
+	}
+	nc.Close()
+	if nc, err := natsgo.Connect(h.Config.GetNATSURL(), natsgo.UserInfo("scanner", string(hash))); err == nil {
+		nc.Close()
+		t.Fatal("connected with the hash as password")
+	}
+
+	if err := h.Server.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	natstest.Eventually(t, 5*time.Second, h.Conn.IsConnected, "internal client did not reconnect after reload")
+}
+
+func TestServerUsersAndStreams(t *testing.T) {
+	h := natstest.Start(t, natstest.WithConfig(func(cfg *config.Config) {
+		cfg.NATSUsers = []domain.NATSUser{{Username: "reader", Password: "secret", Publish: []string{"_INBOX.>"}}}
+		cfg.Streams = []domain.StreamSettings{{Name: "JOBS", Subjects: []string{"jobs.>"}, Retention: "workqueue", MaxBytes: domain.MiB}}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Server.EnsureStreams(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := h.JetStream.Stream(ctx, "JOBS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc := stream.CachedInfo().Config; sc.Retention != jetstream.WorkQueuePolicy || sc.MaxBytes != int64(domain.MiB) {
+		t.Errorf("stream config: retention %v, max bytes %d", sc.Retention, sc.MaxBytes)
+	}
+
+	// права пользователя из файла конфигурации применяются сервером
+	nc, err := natsgo.Connect(h.Config.GetNATSURL(), natsgo.UserInfo("reader", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	denied := make(chan error, 1)
+	nc.SetErrorHandler(func(_ *natsgo.Conn, _ *natsgo.Subscription, err error) {
+		denied <- err
+	})
+	if err := nc.Publish("jobs.one", nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-denied:
+		if !errors.Is(err, natsgo.ErrPermissionViolation) {
+			t.Errorf("publish error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("publish outside of permissions was not rejected")
+	}
+}
+
+func TestServerWithoutListener(t *testing.T) {
+	h := natstest.Start(t, natstest.WithoutListener(), natstest.WithStreams(natstest.EventsStream()))
+
+	if info := h.Server.GetInfo(); info["ready"] != true || info["client_listener"] != false {
+		t.Fatalf("ready = %v, client_listener = %v", info["ready"], info["client_listener"])
+	}
+	if nc, err := natsgo.Connect(h.Config.GetNATSURL()); err == nil {
+		nc.Close()
+		t.Fatal("connected over TCP with NATS_NO_LISTEN")
+	}
+
+	// in-process клиент работает и переживает перезапуск сервера
+	h.Publish(domain.SubjectBundleMatch, []byte(`{"payload":{"CorrelationID":1}}`))
+	if err := h.Server.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	natstest.Eventually(t, 5*time.Second, h.Conn.IsConnected, "in-process client did not reconnect")
+	h.Publish(domain.SubjectBundleMatch, []byte(`{"payload":{"CorrelationID":2}}`))
+}
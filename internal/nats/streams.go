@@ -0,0 +1,67 @@
+package nats
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// EnsureStreams создает или обновляет стримы из jetstream.streams файла конфигурации.
+// Стримы, которых нет в конфигурации, не трогаются.
+func (s *Server) EnsureStreams(ctx context.Context) error {
+	if len(s.config.Streams) == 0 {
+		return nil
+	}
+
+	nc, err := s.ClientConn("streams")
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	for _, sc := range s.config.Streams {
+		stream, err := js.CreateOrUpdateStream(ctx, streamConfig(sc))
+		if err != nil {
+			return fmt.Errorf("failed to create or update stream %s: %w", sc.Name, err)
+		}
+		s.logger.Info("stream configured",
+			zap.String("stream", sc.Name),
+			zap.Strings("subjects", stream.CachedInfo().Config.Subjects),
+		)
+	}
+	return nil
+}
+
+func streamConfig(sc domain.StreamSettings) jetstream.StreamConfig {
+	cfg := jetstream.StreamConfig{
+		Name:     sc.Name,
+		Subjects: sc.Subjects,
+		MaxAge:   sc.MaxAge,
+		MaxBytes: -1,
+		MaxMsgs:  -1,
+		Replicas: sc.Replicas,
+	}
+	if sc.Storage == "memory" {
+		cfg.Storage = jetstream.MemoryStorage
+	}
+	switch sc.Retention {
+	case "interest":
+		cfg.Retention = jetstream.InterestPolicy
+	case "workqueue":
+		cfg.Retention = jetstream.WorkQueuePolicy
+	}
+	if sc.MaxBytes > 0 {
+		cfg.MaxBytes = sc.MaxBytes.Bytes()
+	}
+	if sc.MaxMsgs > 0 {
+		cfg.MaxMsgs = sc.MaxMsgs
+	}
+	return cfg
+}
@@ -0,0 +1,223 @@
+// Package natstest поднимает встроенный NATS сервер сервиса для интеграционных тестов:
+// свободные порты, временный DATA_DIR, заранее созданные стримы и подключенный клиент.
+package natstest
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/nats"
+	"NATS_TIRE_SERVICE/internal/server"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"fmt"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"net"
+	"testing"
+	"time"
+)
+
+// Harness - запущенный сервер и клиент к нему. Все ресурсы освобождаются в t.Cleanup.
+type Harness struct {
+	Config    *config.Config
+	Logger    *utils.Logger
+	Server    *nats.Server
+	Conn      *natsgo.Conn
+	JetStream jetstream.JetStream
+
+	t testing.TB
+}
+
+type options struct {
+	configure []func(*config.Config)
+	streams   []jetstream.StreamConfig
+}
+
+type Option func(*options)
+
+// WithConfig меняет конфигурацию перед запуском сервера
+func WithConfig(fn func(cfg *config.Config)) Option {
+	return func(o *options) {
+		o.configure = append(o.configure, fn)
+	}
+}
+
+// WithAuth включает авторизацию по логину и паролю. Клиент Harness, как и компоненты сервиса,
+// подключается внутренним пользователем через Server.ClientConn, поэтому сами учетные данные
+// тест проверяет своим подключением к GetNATSURL.
+func WithAuth(username, password string) Option {
+	return WithConfig(func(cfg *config.Config) {
+		cfg.NATSUsername = username
+		cfg.NATSPassword = domain.Secret(password)
+	})
+}
+
+// WithoutListener запускает сервер без TCP порта для клиентов (NATS_NO_LISTEN):
+// Harness и компоненты сервиса подключаются in-process
+func WithoutListener() Option {
+	return WithConfig(func(cfg *config.Config) {
+		cfg.NATSNoListen = true
+	})
+}
+
+// WithStreams создает стримы после запуска сервера
+func WithStreams(streams ...jetstream.StreamConfig) Option {
+	return func(o *options) {
+		o.streams = append(o.streams, streams...)
+	}
+}
+
+// EventsStream - стрим событий в том виде, в котором его создает NATS_TIRE_LIBRARY
+func EventsStream() jetstream.StreamConfig {
+	return jetstream.StreamConfig{
+		Name:     domain.StreamEvents,
+		Subjects: []string{"events.>"},
+		Storage:  jetstream.FileStorage,
+	}
+}
+
+// Start запускает сервер и подключает клиента. В режиме -short тест пропускается.
+func Start(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("integration test skipped in short mode")
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatalf("natstest: %v", err)
+	}
+	cfg.AppName = "natstest"
+	cfg.Version = "test"
+	cfg.Env = "development"
+	cfg.NATSPort = FreePort(t)
+	cfg.NATSHTTPPort = FreePort(t)
+	cfg.HTTPPort = FreePort(t)
+	cfg.DataDir = t.TempDir()
+	cfg.LameDuckDuration = 0
+	cfg.ShutdownDrainDelay = 0
+	cfg.MaxMemoryStore = 64 * domain.MiB
+	cfg.MaxFileStore = 256 * domain.MiB
+	cfg.LogLevel = "info"
+	for _, fn := range o.configure {
+		fn(cfg)
+	}
+
+	logger := NewLogger(t, cfg.LogLevel)
+
+	srv, err := nats.NewServer(cfg, logger)
+	if err != nil {
+		t.Fatalf("natstest: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("natstest: %v", err)
+	}
+	t.Cleanup(func() {
+		if srv.IsRunning() {
+			if err := srv.Stop(); err != nil {
+				t.Errorf("natstest: %v", err)
+			}
+		}
+	})
+
+	h := &Harness{
+		Config: cfg,
+		Logger: logger,
+		Server: srv,
+		t:      t,
+	}
+	h.Conn = h.Connect("harness")
+
+	h.JetStream, err = jetstream.New(h.Conn)
+	if err != nil {
+		t.Fatalf("natstest: failed to create JetStream context: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, sc := range o.streams {
+		if _, err := h.JetStream.CreateOrUpdateStream(ctx, sc); err != nil {
+			t.Fatalf("natstest: failed to create stream %s: %v", sc.Name, err)
+		}
+	}
+
+	return h
+}
+
+// Connect открывает еще одно клиентское подключение, которое закроется в конце теста
+func (h *Harness) Connect(name string) *natsgo.Conn {
+	h.t.Helper()
+
+	nc, err := h.Server.ClientConn(name)
+	if err != nil {
+		h.t.Fatalf("natstest: %v", err)
+	}
+	h.t.Cleanup(nc.Close)
+	return nc
+}
+
+// Publish публикует сообщение в JetStream и ждет подтверждения
+func (h *Harness) Publish(subject string, data []byte) *jetstream.PubAck {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ack, err := h.JetStream.Publish(ctx, subject, data)
+	if err != nil {
+		h.t.Fatalf("natstest: failed to publish to %s: %v", subject, err)
+	}
+	return ack
+}
+
+// StartHTTP запускает HTTPServer сервиса на свободном порту и возвращает его адрес.
+// setup вызывается до запуска, например чтобы подключить индекс или монитор.
+func (h *Harness) StartHTTP(setup ...func(*server.HTTPServer)) string {
+	h.t.Helper()
+
+	srv := server.NewHTTPServer(h.Config.HTTPPort, h.Logger, h.Server, h.Config)
+	for _, fn := range setup {
+		fn(srv)
+	}
+	if err := srv.Start(); err != nil {
+		h.t.Fatalf("natstest: %v", err)
+	}
+	h.t.Cleanup(func() {
+		if err := srv.Stop(); err != nil {
+			h.t.Errorf("natstest: %v", err)
+		}
+	})
+
+	return fmt.Sprintf("http://127.0.0.1:%d", h.Config.HTTPPort)
+}
+
+// FreePort возвращает свободный TCP порт. Порт может занять кто-то другой до запуска сервера,
+// но для локальных тестов этого достаточно.
+func FreePort(t testing.TB) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("natstest: failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// Eventually повторяет check, пока он не вернет true, и проваливает тест по истечении timeout
+func Eventually(t testing.TB, timeout time.Duration, check func() bool, format string, args ...interface{}) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
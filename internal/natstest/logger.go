@@ -0,0 +1,54 @@
+package natstest
+
+import (
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testWriter пишет логи через t.Log, чтобы они выводились только у упавших тестов (или с -v).
+// После завершения теста записи отбрасываются: горутины сервера могут логировать и позже,
+// а t.Log после окончания теста вызывает панику.
+type testWriter struct {
+	mu   sync.Mutex
+	t    testing.TB
+	done bool
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.done {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (w *testWriter) Sync() error {
+	return nil
+}
+
+// NewLogger - логгер сервиса, который пишет в лог теста
+func NewLogger(t testing.TB, level string) *utils.Logger {
+	w := &testWriter{t: t}
+	t.Cleanup(func() {
+		w.mu.Lock()
+		w.done = true
+		w.mu.Unlock()
+	})
+
+	atomic, err := zap.ParseAtomicLevel(level)
+	if err != nil {
+		t.Fatalf("natstest: %v", err)
+	}
+
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("15:04:05.000")
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), w, zapcore.DebugLevel)
+
+	return utils.NewLoggerWithCore(core, atomic)
+}
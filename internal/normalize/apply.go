@@ -0,0 +1,67 @@
+package normalize
+
+import "NATS_TIRE_SERVICE/internal/domain"
+
+// result собирает названия, которые стоит показать в payload: нечеткие совпадения и ненайденные
+type result struct {
+	d       *Dictionary
+	matches []domain.NameMatch
+	seen    map[domain.NameMatch]bool
+}
+
+func (r *result) resolve(kind, raw string) string {
+	m := r.d.Resolve(kind, raw)
+	if m.Confidence < 1 && !r.seen[m] {
+		if r.seen == nil {
+			r.seen = make(map[domain.NameMatch]bool)
+		}
+		r.seen[m] = true
+		r.matches = append(r.matches, m)
+	}
+	if m.Canonical == "" {
+		return raw
+	}
+	return m.Canonical
+}
+
+func (r *result) teams(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = r.resolve(domain.AliasKindTeam, name)
+	}
+	return out
+}
+
+// Monitoring нормализует команды и рынки. target_bet заменяется только при совпадении с командой:
+// в остальных рынках это число или исход, который словарь не описывает.
+func Monitoring(d *Dictionary, m domain.MatchMonitoring) domain.NormalizedMonitoring {
+	r := &result{d: d}
+	m.TeamNames = r.teams(m.TeamNames)
+
+	bets := make(map[string][]domain.MonitoringBet, len(m.Bets))
+	for bookmaker, list := range m.Bets {
+		normalized := make([]domain.MonitoringBet, len(list))
+		for i, bet := range list {
+			bet.BetMarket = r.resolve(domain.AliasKindMarket, bet.BetMarket)
+			if team := d.Resolve(domain.AliasKindTeam, bet.TargetBet); team.Canonical != "" {
+				bet.TargetBet = team.Canonical
+			}
+			normalized[i] = bet
+		}
+		bets[bookmaker] = normalized
+	}
+	if m.Bets != nil {
+		m.Bets = bets
+	}
+	return domain.NormalizedMonitoring{MatchMonitoring: m, Normalization: r.matches}
+}
+
+// Bundle нормализует команды связки матча
+func Bundle(d *Dictionary, b domain.MatchBundle) domain.NormalizedBundle {
+	r := &result{d: d}
+	b.TeamNames = r.teams(b.TeamNames)
+	return domain.NormalizedBundle{MatchBundle: b, Normalization: r.matches}
+}
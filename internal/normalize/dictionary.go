@@ -0,0 +1,189 @@
+// Package normalize приводит названия команд и рынков от разных букмекеров к каноническим
+// по словарю алиасов из KV бакета
+package normalize
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/pkg/levenshtein"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidEntry = errors.New("invalid alias entry")
+	ErrConflict     = errors.New("alias already belongs to another canonical name")
+)
+
+// maxCached - предел кэша результатов, после него кэш сбрасывается
+const maxCached = 10000
+
+// Dictionary - словарь алиасов в памяти. Потокобезопасен: его читает Normalizer и админский API.
+type Dictionary struct {
+	minConfidence float64
+
+	mu      sync.Mutex
+	entries map[string]domain.AliasEntry // по ключу KV
+	index   map[string]map[string]string // вид -> свернутое название -> каноническое
+	cache   map[string]domain.NameMatch  // вид + сырое название -> результат
+}
+
+func NewDictionary(minConfidence float64) *Dictionary {
+	return &Dictionary{
+		minConfidence: minConfidence,
+		entries:       make(map[string]domain.AliasEntry),
+		index:         make(map[string]map[string]string),
+		cache:         make(map[string]domain.NameMatch),
+	}
+}
+
+// EntryKey - ключ KV для канонического названия. Названия бывают не ASCII, поэтому кодируются base64url.
+func EntryKey(kind, canonical string) string {
+	return kind + "." + base64.RawURLEncoding.EncodeToString([]byte(fold(canonical)))
+}
+
+// Clean проверяет запись и убирает пустые и повторяющиеся алиасы
+func Clean(e domain.AliasEntry) (domain.AliasEntry, error) {
+	if e.Kind != domain.AliasKindTeam && e.Kind != domain.AliasKindMarket {
+		return e, fmt.Errorf("%w: kind must be %s or %s, got %q", ErrInvalidEntry, domain.AliasKindTeam, domain.AliasKindMarket, e.Kind)
+	}
+	e.Canonical = strings.TrimSpace(e.Canonical)
+	if fold(e.Canonical) == "" {
+		return e, fmt.Errorf("%w: canonical name must contain letters or digits", ErrInvalidEntry)
+	}
+
+	seen := map[string]bool{fold(e.Canonical): true}
+	aliases := make([]string, 0, len(e.Aliases))
+	for _, alias := range e.Aliases {
+		alias = strings.TrimSpace(alias)
+		if f := fold(alias); f != "" && !seen[f] {
+			seen[f] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	e.Aliases = aliases
+	return e, nil
+}
+
+// Conflict возвращает каноническое название, которому уже принадлежит одно из названий записи
+func (d *Dictionary) Conflict(e domain.AliasEntry) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	own := fold(e.Canonical)
+	for _, name := range append([]string{e.Canonical}, e.Aliases...) {
+		if canonical, ok := d.index[e.Kind][fold(name)]; ok && fold(canonical) != own {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+func (d *Dictionary) Put(key string, e domain.AliasEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries[key] = e
+	d.rebuild()
+}
+
+func (d *Dictionary) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.entries, key)
+	d.rebuild()
+}
+
+// Entries - записи словаря по виду и каноническому названию
+func (d *Dictionary) Entries() []domain.AliasEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make([]domain.AliasEntry, 0, len(d.entries))
+	for _, e := range d.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Canonical < entries[j].Canonical
+	})
+	return entries
+}
+
+// Resolve ищет каноническое название: точное совпадение с алиасом без учета регистра, пробелов
+// и знаков дает уверенность 1, иначе берется самое похожее название по расстоянию Левенштейна.
+// Если уверенность ниже порога, Canonical пустой, а Confidence - лучшая найденная.
+func (d *Dictionary) Resolve(kind, raw string) domain.NameMatch {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cacheKey := kind + "\x00" + raw
+	if m, ok := d.cache[cacheKey]; ok {
+		return m
+	}
+
+	m := domain.NameMatch{Kind: kind, Raw: raw}
+	f := fold(raw)
+	if canonical, ok := d.index[kind][f]; ok && f != "" {
+		m.Canonical, m.Confidence = canonical, 1
+	} else if f != "" {
+		var best string
+		for name, canonical := range d.index[kind] {
+			score := similarity(f, name)
+			if score > m.Confidence || score == m.Confidence && score > 0 && canonical < best {
+				m.Confidence, best = score, canonical
+			}
+		}
+		m.Confidence = math.Round(m.Confidence*1000) / 1000
+		if m.Confidence >= d.minConfidence {
+			m.Canonical = best
+		}
+	}
+
+	if len(d.cache) >= maxCached {
+		d.cache = make(map[string]domain.NameMatch)
+	}
+	d.cache[cacheKey] = m
+	return m
+}
+
+// rebuild пересобирает индекс после изменения словаря. Вызывается под mu.
+func (d *Dictionary) rebuild() {
+	d.index = make(map[string]map[string]string)
+	for _, e := range d.entries {
+		if d.index[e.Kind] == nil {
+			d.index[e.Kind] = make(map[string]string)
+		}
+		for _, name := range append([]string{e.Canonical}, e.Aliases...) {
+			d.index[e.Kind][fold(name)] = e.Canonical
+		}
+	}
+	d.cache = make(map[string]domain.NameMatch)
+}
+
+// fold оставляет только буквы и цифры в нижнем регистре: "Natus Vincere" и "natus-vincere" совпадают
+func fold(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// similarity - 1 минус расстояние Левенштейна, деленное на длину большей строки
+func similarity(a, b string) float64 {
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein.Distance(a, b))/float64(longest)
+}
@@ -0,0 +1,107 @@
+package normalize
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"errors"
+	"testing"
+)
+
+func dictionary(t *testing.T) *Dictionary {
+	d := NewDictionary(0.8)
+	for _, e := range []domain.AliasEntry{
+		{Kind: domain.AliasKindTeam, Canonical: "Natus Vincere", Aliases: []string{"NaVi", "НАВИ"}},
+		{Kind: domain.AliasKindTeam, Canonical: "G2 Esports", Aliases: []string{"G2"}},
+		{Kind: domain.AliasKindMarket, Canonical: "match-winner", Aliases: []string{"Победитель", "1x2 maps"}},
+	} {
+		e, err := Clean(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Put(EntryKey(e.Kind, e.Canonical), e)
+	}
+	return d
+}
+
+func TestResolve(t *testing.T) {
+	d := dictionary(t)
+
+	for _, tc := range []struct {
+		kind, raw, canonical string
+		exact                bool
+	}{
+		{domain.AliasKindTeam, "navi", "Natus Vincere", true},
+		{domain.AliasKindTeam, "natus-vincere", "Natus Vincere", true},
+		{domain.AliasKindTeam, "Нави", "Natus Vincere", true},
+		{domain.AliasKindTeam, "Natus Vinsere", "Natus Vincere", false},
+		{domain.AliasKindTeam, "Vitality", "", false},
+		{domain.AliasKindMarket, "ПОБЕДИТЕЛЬ", "match-winner", true},
+		// вид учитывается: команда не совпадает с рынком
+		{domain.AliasKindMarket, "NaVi", "", false},
+	} {
+		m := d.Resolve(tc.kind, tc.raw)
+		if m.Canonical != tc.canonical || (m.Confidence == 1) != tc.exact {
+			t.Errorf("Resolve(%s, %q) = %+v, want %q exact=%v", tc.kind, tc.raw, m, tc.canonical, tc.exact)
+		}
+	}
+
+	if m := d.Resolve(domain.AliasKindTeam, "Natus Vinsere"); m.Confidence < 0.8 || m.Confidence >= 1 {
+		t.Errorf("fuzzy confidence = %v", m.Confidence)
+	}
+}
+
+func TestDictionaryChanges(t *testing.T) {
+	d := dictionary(t)
+
+	if other, ok := d.Conflict(domain.AliasEntry{Kind: domain.AliasKindTeam, Canonical: "Vitality", Aliases: []string{"navi"}}); !ok || other != "Natus Vincere" {
+		t.Errorf("conflict = %q %v", other, ok)
+	}
+	// своя запись с другим регистром - не конфликт
+	if _, ok := d.Conflict(domain.AliasEntry{Kind: domain.AliasKindTeam, Canonical: "natus vincere", Aliases: []string{"NaVi"}}); ok {
+		t.Error("own entry reported as conflict")
+	}
+
+	if m := d.Resolve(domain.AliasKindTeam, "G2"); m.Canonical != "G2 Esports" {
+		t.Fatalf("before delete: %+v", m)
+	}
+	d.Delete(EntryKey(domain.AliasKindTeam, "g2 esports"))
+	if m := d.Resolve(domain.AliasKindTeam, "G2"); m.Canonical != "" {
+		t.Errorf("after delete: %+v", m)
+	}
+
+	if _, err := Clean(domain.AliasEntry{Kind: "league", Canonical: "x"}); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("unknown kind: %v", err)
+	}
+	e, err := Clean(domain.AliasEntry{Kind: domain.AliasKindTeam, Canonical: " Spirit ", Aliases: []string{"spirit", "", "Team Spirit", "team-spirit"}})
+	if err != nil || e.Canonical != "Spirit" || len(e.Aliases) != 1 || e.Aliases[0] != "Team Spirit" {
+		t.Errorf("Clean = %+v, %v", e, err)
+	}
+}
+
+func TestMonitoring(t *testing.T) {
+	d := dictionary(t)
+
+	m := domain.MatchMonitoring{
+		TeamNames: []string{"NaVi", "G2"},
+		Bets: map[string][]domain.MonitoringBet{
+			"fonbet":     {{BetMarket: "Победитель", TargetBet: "NaVi", Less: 2.1, More: 1.7}},
+			"parivision": {{BetMarket: "total maps", TargetBet: "2.5", Less: 1.8, More: 1.9}},
+		},
+	}
+	out := Monitoring(d, m)
+
+	if out.TeamNames[0] != "Natus Vincere" || out.TeamNames[1] != "G2 Esports" {
+		t.Errorf("teams = %v", out.TeamNames)
+	}
+	if bet := out.Bets["fonbet"][0]; bet.BetMarket != "match-winner" || bet.TargetBet != "Natus Vincere" {
+		t.Errorf("fonbet bet = %+v", bet)
+	}
+	if bet := out.Bets["parivision"][0]; bet.BetMarket != "total maps" || bet.TargetBet != "2.5" {
+		t.Errorf("parivision bet = %+v", bet)
+	}
+	if len(out.Normalization) != 1 || out.Normalization[0].Raw != "total maps" || out.Normalization[0].Canonical != "" {
+		t.Errorf("normalization = %+v", out.Normalization)
+	}
+	if m.TeamNames[0] != "NaVi" || m.Bets["fonbet"][0].BetMarket != "Победитель" {
+		t.Error("source monitoring was modified")
+	}
+}
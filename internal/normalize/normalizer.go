@@ -0,0 +1,447 @@
+package normalize
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	retryDelay = 5 * time.Second
+	// maxUnmatched - сколько разных ненайденных названий помнить для /admin/aliases
+	maxUnmatched = 200
+)
+
+var ErrNotFound = errors.New("alias entry not found")
+
+type unmatchedKey struct {
+	kind string
+	name string
+}
+
+// Normalizer держит словарь алиасов из KV бакета NORMALIZE_BUCKET в памяти (изменения приходят через watch)
+// и при NORMALIZE_REPUBLISH публикует нормализованные events.bundle.match и events.match.monitoring
+// в параллельное дерево NORMALIZE_SUBJECT_PREFIX.
+type Normalizer struct {
+	cfg     *config.Config
+	logger  *utils.Logger
+	connect func(name string) (*nats.Conn, error)
+	dict    *Dictionary
+
+	mu        sync.Mutex
+	processed uint64
+	published uint64
+	unmatched map[unmatchedKey]uint64
+
+	nc     *nats.Conn
+	kv     jetstream.KeyValue
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewNormalizer(cfg *config.Config, logger *utils.Logger, connect func(name string) (*nats.Conn, error)) *Normalizer {
+	return &Normalizer{
+		cfg:       cfg,
+		logger:    logger.WithFields(zap.String("component", "normalizer")),
+		connect:   connect,
+		dict:      NewDictionary(cfg.NormalizeMinConfidence),
+		unmatched: make(map[unmatchedKey]uint64),
+	}
+}
+
+// Start создает бакет, если его нет, и загружает словарь до начала обработки событий
+func (n *Normalizer) Start(ctx context.Context) error {
+	nc, err := n.connect("normalizer")
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	kvCtx, cancelKV := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelKV()
+	kv, err := js.CreateOrUpdateKeyValue(kvCtx, jetstream.KeyValueConfig{
+		Bucket:      n.cfg.NormalizeBucket,
+		Description: "team and market aliases",
+		History:     5,
+	})
+	if err != nil {
+		nc.Close()
+		return fmt.Errorf("failed to open alias bucket %s: %w", n.cfg.NormalizeBucket, err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	watcher, err := kv.WatchAll(runCtx)
+	if err != nil {
+		cancel()
+		nc.Close()
+		return fmt.Errorf("failed to watch alias bucket: %w", err)
+	}
+	// до nil в канале приходят текущие значения бакета
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		n.apply(entry)
+	}
+	n.logger.Info("alias dictionary loaded",
+		zap.String("bucket", n.cfg.NormalizeBucket),
+		zap.Int("entries", len(n.dict.Entries())),
+	)
+
+	n.nc, n.kv, n.cancel = nc, kv, cancel
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.watch(runCtx, watcher)
+	}()
+	if n.cfg.NormalizeRepublish {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.run(runCtx, js)
+		}()
+	}
+	return nil
+}
+
+func (n *Normalizer) Stop(ctx context.Context) error {
+	if n.cancel == nil {
+		return nil
+	}
+	n.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	n.nc.Close()
+	return nil
+}
+
+// Aliases - словарь, самые частые ненайденные названия и счетчики республикации
+func (n *Normalizer) Aliases() domain.AliasesResponse {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	resp := domain.AliasesResponse{
+		Bucket:    n.cfg.NormalizeBucket,
+		Entries:   n.dict.Entries(),
+		Unmatched: make([]domain.UnmatchedName, 0, len(n.unmatched)),
+		Processed: n.processed,
+		Published: n.published,
+	}
+	for key, count := range n.unmatched {
+		resp.Unmatched = append(resp.Unmatched, domain.UnmatchedName{Kind: key.kind, Name: key.name, Count: count})
+	}
+	sort.Slice(resp.Unmatched, func(i, j int) bool {
+		if resp.Unmatched[i].Count != resp.Unmatched[j].Count {
+			return resp.Unmatched[i].Count > resp.Unmatched[j].Count
+		}
+		return resp.Unmatched[i].Name < resp.Unmatched[j].Name
+	})
+	return resp
+}
+
+func (n *Normalizer) Resolve(kind, name string) domain.NameMatch {
+	return n.dict.Resolve(kind, name)
+}
+
+// PutAlias создает или заменяет запись словаря. Алиас, который уже принадлежит другому
+// каноническому названию, - ErrConflict.
+func (n *Normalizer) PutAlias(ctx context.Context, e domain.AliasEntry) (domain.AliasEntry, error) {
+	e, err := Clean(e)
+	if err != nil {
+		return e, err
+	}
+	if other, ok := n.dict.Conflict(e); ok {
+		return e, fmt.Errorf("%w: %s", ErrConflict, other)
+	}
+
+	e.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	key := EntryKey(e.Kind, e.Canonical)
+	if _, err := n.kv.Put(ctx, key, data); err != nil {
+		return e, fmt.Errorf("failed to store alias entry: %w", err)
+	}
+	// watch применит то же значение, но ответ API должен сразу учитывать изменение
+	n.dict.Put(key, e)
+	n.resetUnmatched()
+
+	n.logger.Info("alias entry stored",
+		zap.String("kind", e.Kind),
+		zap.String("canonical", e.Canonical),
+		zap.Strings("aliases", e.Aliases),
+	)
+	return e, nil
+}
+
+func (n *Normalizer) DeleteAlias(ctx context.Context, kind, canonical string) error {
+	key := EntryKey(kind, canonical)
+	if _, err := n.kv.Get(ctx, key); err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to read alias entry: %w", err)
+	}
+	if err := n.kv.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete alias entry: %w", err)
+	}
+	n.dict.Delete(key)
+	n.resetUnmatched()
+
+	n.logger.Info("alias entry deleted", zap.String("kind", kind), zap.String("canonical", canonical))
+	return nil
+}
+
+// resetUnmatched забывает ненайденные названия: после изменения словаря часть из них уже найдется
+func (n *Normalizer) resetUnmatched() {
+	n.mu.Lock()
+	n.unmatched = make(map[unmatchedKey]uint64)
+	n.mu.Unlock()
+}
+
+func (n *Normalizer) watch(ctx context.Context, watcher jetstream.KeyWatcher) {
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return
+			}
+			if entry != nil {
+				n.apply(entry)
+			}
+		}
+	}
+}
+
+func (n *Normalizer) apply(entry jetstream.KeyValueEntry) {
+	switch entry.Operation() {
+	case jetstream.KeyValuePut:
+		var e domain.AliasEntry
+		if err := json.Unmarshal(entry.Value(), &e); err != nil {
+			n.logger.Warn("skipping invalid alias entry", zap.String("key", entry.Key()), zap.Error(err))
+			return
+		}
+		n.dict.Put(entry.Key(), e)
+	case jetstream.KeyValueDelete, jetstream.KeyValuePurge:
+		n.dict.Delete(entry.Key())
+	}
+}
+
+// run переподключает consumer при ошибках и ждет появления стрима, если его еще нет
+func (n *Normalizer) run(ctx context.Context, js jetstream.JetStream) {
+	waiting := false
+
+	for ctx.Err() == nil {
+		err := n.consume(ctx, js)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, jetstream.ErrStreamNotFound):
+			if !waiting {
+				n.logger.Warn("stream not found, waiting for it to be created", zap.String("stream", n.cfg.NormalizeStream))
+				waiting = true
+			}
+		case err != nil:
+			n.logger.Warn("normalizer consumer stopped, restarting", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (n *Normalizer) consume(ctx context.Context, js jetstream.JetStream) error {
+	stream, err := js.Stream(ctx, n.cfg.NormalizeStream)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:        n.cfg.NormalizeDurable,
+		Description:    "normalizer: events -> " + n.cfg.NormalizeSubjectPrefix,
+		FilterSubjects: []string{domain.SubjectBundleMatch, domain.SubjectMatchMonitoring},
+		DeliverPolicy:  jetstream.DeliverNewPolicy,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s: %w", n.cfg.NormalizeDurable, err)
+	}
+
+	msgs, err := consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to consume stream: %w", err)
+	}
+	defer msgs.Stop()
+
+	go func() {
+		<-ctx.Done()
+		msgs.Stop()
+	}()
+
+	n.logger.Info("normalizer republishing events",
+		zap.String("stream", n.cfg.NormalizeStream),
+		zap.String("durable", n.cfg.NormalizeDurable),
+		zap.String("prefix", n.cfg.NormalizeSubjectPrefix),
+	)
+
+	for {
+		msg, err := msgs.Next()
+		switch {
+		case errors.Is(err, jetstream.ErrMsgIteratorClosed):
+			return ctx.Err()
+		case err != nil:
+			return err
+		}
+		n.handle(ctx, js, msg)
+	}
+}
+
+func (n *Normalizer) handle(ctx context.Context, js jetstream.JetStream, msg jetstream.Msg) {
+	traceCtx, span := tracing.StartConsume(ctx, msg.Subject(), msg.Headers(), msg.Data())
+	defer span.End()
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		n.logger.Warn("failed to read message metadata", zap.Error(err))
+		_ = msg.Nak()
+		return
+	}
+
+	env, err := domain.ParseEnvelope(msg.Data())
+	var (
+		eventType string
+		payload   interface{}
+		matches   []domain.NameMatch
+	)
+	if err == nil {
+		eventType, payload, matches, err = n.normalize(msg.Subject(), env.Payload)
+	}
+	if err != nil {
+		n.logger.Debug("skipping invalid event", zap.Uint64("seq", meta.Sequence.Stream), zap.Error(err))
+		_ = msg.Ack()
+		return
+	}
+	n.count(matches)
+
+	id, _ := env.CorrelationID()
+	at := meta.Timestamp
+	if t, ok := env.EventTime(); ok {
+		at = t
+	}
+
+	subject := n.cfg.NormalizeSubjectPrefix + strings.TrimPrefix(msg.Subject(), "events")
+	if err := n.publish(traceCtx, js, subject, eventType, id, at, meta.Sequence.Stream, payload); err != nil {
+		n.logger.Warn("failed to publish normalized event",
+			zap.String("subject", subject),
+			zap.Uint64("seq", meta.Sequence.Stream),
+			zap.Error(err),
+		)
+		_ = msg.Nak()
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		n.logger.Warn("failed to ack event", zap.Uint64("seq", meta.Sequence.Stream), zap.Error(err))
+	}
+
+	n.mu.Lock()
+	n.published++
+	n.mu.Unlock()
+}
+
+// normalize разбирает payload по subject и возвращает тип события, нормализованный payload и неточные совпадения
+func (n *Normalizer) normalize(subject string, raw json.RawMessage) (string, interface{}, []domain.NameMatch, error) {
+	if len(raw) == 0 {
+		return "", nil, nil, errors.New("event has no payload")
+	}
+
+	switch subject {
+	case domain.SubjectBundleMatch:
+		var bundle domain.MatchBundle
+		if err := json.Unmarshal(raw, &bundle); err != nil {
+			return "", nil, nil, err
+		}
+		normalized := Bundle(n.dict, bundle)
+		return domain.EventTypeMatchBundle, normalized, normalized.Normalization, nil
+	default:
+		var monitoring domain.MatchMonitoring
+		if err := json.Unmarshal(raw, &monitoring); err != nil {
+			return "", nil, nil, err
+		}
+		normalized := Monitoring(n.dict, monitoring)
+		return domain.EventTypeMatchMonitoring, normalized, normalized.Normalization, nil
+	}
+}
+
+func (n *Normalizer) count(matches []domain.NameMatch) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.processed++
+	for _, m := range matches {
+		if m.Canonical != "" {
+			continue
+		}
+		key := unmatchedKey{kind: m.Kind, name: m.Raw}
+		if _, ok := n.unmatched[key]; ok || len(n.unmatched) < maxUnmatched {
+			n.unmatched[key]++
+		}
+	}
+}
+
+// publish сохраняет время исходного события, как и фильтр коэффициентов
+func (n *Normalizer) publish(ctx context.Context, js jetstream.JetStream, subject, eventType string, id int64,
+	at time.Time, seq uint64, payload interface{}) (err error) {
+	event := domain.NewEvent(eventType, n.cfg.AppName, id, payload)
+	event.Header.Timestamp = at.UTC()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
+	msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("normalize:%d", seq))
+
+	ctx, span := tracing.StartPublish(ctx, msg)
+	defer func() { tracing.End(span, err) }()
+
+	pubCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = js.PublishMsg(pubCtx, msg)
+	return err
+}
@@ -0,0 +1,108 @@
+package normalize_test
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/natstest"
+	"NATS_TIRE_SERVICE/internal/normalize"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNormalizerRepublishes(t *testing.T) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.NormalizeEnabled = true
+			cfg.NormalizeRepublish = true
+		}),
+	)
+
+	normalizer := normalize.NewNormalizer(h.Config, h.Logger, h.Server.ClientConn)
+	if err := normalizer.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { normalizer.Stop(context.Background()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := normalizer.PutAlias(ctx, domain.AliasEntry{
+		Kind: domain.AliasKindTeam, Canonical: "Natus Vincere", Aliases: []string{"NaVi"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := normalizer.PutAlias(ctx, domain.AliasEntry{Kind: domain.AliasKindTeam, Canonical: "NAVI Junior", Aliases: []string{"navi"}})
+	if !errors.Is(err, normalize.ErrConflict) {
+		t.Errorf("conflicting alias: %v", err)
+	}
+
+	// запись, добавленная в бакет в обход API, приходит через watch
+	kv, err := h.JetStream.KeyValue(ctx, h.Config.NormalizeBucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(domain.AliasEntry{Kind: domain.AliasKindMarket, Canonical: "match-winner", Aliases: []string{"Победитель"}})
+	if _, err := kv.Put(ctx, normalize.EntryKey(domain.AliasKindMarket, "match-winner"), data); err != nil {
+		t.Fatal(err)
+	}
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		return normalizer.Resolve(domain.AliasKindMarket, "победитель").Canonical == "match-winner"
+	}, "watched alias was not applied")
+
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		_, err := h.JetStream.Consumer(ctx, domain.StreamEvents, h.Config.NormalizeDurable)
+		return err == nil
+	}, "normalizer consumer was not created")
+
+	h.Publish(domain.SubjectMatchMonitoring, []byte(`{"payload":{"correlation_id":7,"team_names":["NaVi","Vitality"],`+
+		`"bets":{"fonbet":[{"bet_market":"Победитель","target_bet":"NaVi","less":2.1,"more":1.7}]},`+
+		`"timestamp":"2026-05-01T18:00:00Z"}}`))
+	natstest.Eventually(t, 5*time.Second, func() bool { return normalizer.Aliases().Published == 1 }, "event was not republished")
+
+	stream, err := h.JetStream.Stream(ctx, domain.StreamEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := stream.GetLastMsgForSubject(ctx, "events.normalized.match.monitoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event struct {
+		Header  domain.EventHeader          `json:"event_header"`
+		Payload domain.NormalizedMonitoring `json:"payload"`
+	}
+	if err := json.Unmarshal(raw.Data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Header.CorrelationID != 7 || !event.Header.Timestamp.Equal(time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("header = %+v", event.Header)
+	}
+	p := event.Payload
+	if p.TeamNames[0] != "Natus Vincere" || p.Bets["fonbet"][0].BetMarket != "match-winner" ||
+		p.Bets["fonbet"][0].TargetBet != "Natus Vincere" {
+		t.Errorf("payload = %+v", p)
+	}
+
+	aliases := normalizer.Aliases()
+	if len(aliases.Entries) != 2 || len(aliases.Unmatched) != 1 || aliases.Unmatched[0].Name != "Vitality" {
+		t.Errorf("aliases = %+v", aliases)
+	}
+
+	if err := normalizer.DeleteAlias(ctx, domain.AliasKindTeam, "natus vincere"); err != nil {
+		t.Fatal(err)
+	}
+	// после изменения словаря ненайденные названия собираются заново
+	if unmatched := normalizer.Aliases().Unmatched; len(unmatched) != 0 {
+		t.Errorf("unmatched after delete = %+v", unmatched)
+	}
+	if err := normalizer.DeleteAlias(ctx, domain.AliasKindTeam, "natus vincere"); !errors.Is(err, normalize.ErrNotFound) {
+		t.Errorf("second delete: %v", err)
+	}
+	if m := normalizer.Resolve(domain.AliasKindTeam, "NaVi"); m.Canonical != "" {
+		t.Errorf("deleted alias still resolves: %+v", m)
+	}
+}
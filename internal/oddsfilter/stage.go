@@ -0,0 +1,314 @@
+package oddsfilter
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/tracing"
+	"NATS_TIRE_SERVICE/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const retryDelay = 5 * time.Second
+
+// Stage читает events.match.monitoring durable consumer'ом, проверяет коэффициенты Tracker'ом
+// и публикует результат в events.match.monitoring.filtered. Сообщение подтверждается после публикации.
+type Stage struct {
+	cfg     *config.Config
+	logger  *utils.Logger
+	connect func(name string) (*nats.Conn, error)
+	tracker *Tracker
+
+	mu         sync.RWMutex
+	processed  uint64
+	published  uint64
+	tracked    int
+	bookmakers map[string]*domain.BookmakerOddsStats
+
+	nc     *nats.Conn
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewStage(cfg *config.Config, logger *utils.Logger, connect func(name string) (*nats.Conn, error)) *Stage {
+	return &Stage{
+		cfg:     cfg,
+		logger:  logger.WithFields(zap.String("component", "odds-filter")),
+		connect: connect,
+		tracker: NewTracker(Limits{
+			TTL:               cfg.OddsTTL,
+			FlickerWindow:     cfg.OddsFlickerWindow,
+			FlickerMaxChanges: cfg.OddsFlickerMaxChanges,
+		}),
+		bookmakers: make(map[string]*domain.BookmakerOddsStats),
+	}
+}
+
+func (s *Stage) Start(context.Context) error {
+	nc, err := s.connect("odds-filter")
+	if err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.nc, s.cancel, s.done = nc, cancel, make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		s.run(ctx, js)
+	}()
+	return nil
+}
+
+func (s *Stage) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.nc.Close()
+	return nil
+}
+
+// Stats - счетчики фильтра, в том числе отсеянные коэффициенты по букмекерам
+func (s *Stage) Stats() domain.OddsFilterResponse {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := domain.OddsFilterResponse{
+		Mode:       s.cfg.OddsFilterMode,
+		Processed:  s.processed,
+		Published:  s.published,
+		Tracked:    s.tracked,
+		Bookmakers: make(map[string]domain.BookmakerOddsStats, len(s.bookmakers)),
+	}
+	for name, st := range s.bookmakers {
+		resp.Bookmakers[name] = *st
+	}
+	return resp
+}
+
+// retention - сколько помнить коэффициент, которого нет в событиях
+func (s *Stage) retention() time.Duration {
+	if s.cfg.OddsTTL > s.cfg.OddsFlickerWindow {
+		return 2 * s.cfg.OddsTTL
+	}
+	return 2 * s.cfg.OddsFlickerWindow
+}
+
+// run переподключает consumer при ошибках и ждет появления стрима, если его еще нет
+func (s *Stage) run(ctx context.Context, js jetstream.JetStream) {
+	waiting := false
+
+	for ctx.Err() == nil {
+		err := s.consume(ctx, js)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, jetstream.ErrStreamNotFound):
+			if !waiting {
+				s.logger.Warn("stream not found, waiting for it to be created", zap.String("stream", s.cfg.OddsFilterStream))
+				waiting = true
+			}
+		case err != nil:
+			s.logger.Warn("odds filter consumer stopped, restarting", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (s *Stage) consume(ctx context.Context, js jetstream.JetStream) error {
+	stream, err := js.Stream(ctx, s.cfg.OddsFilterStream)
+	if err != nil {
+		return err
+	}
+
+	// новый consumer начинает с новых событий: история стрима для фильтра уже устарела
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       s.cfg.OddsFilterDurable,
+		Description:   "odds filter: " + domain.SubjectMatchMonitoring + " -> " + domain.SubjectMatchMonitoringFiltered,
+		FilterSubject: domain.SubjectMatchMonitoring,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s: %w", s.cfg.OddsFilterDurable, err)
+	}
+
+	msgs, err := consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("failed to consume stream: %w", err)
+	}
+	defer msgs.Stop()
+
+	go func() {
+		<-ctx.Done()
+		msgs.Stop()
+	}()
+
+	s.logger.Info("odds filter consuming monitoring events",
+		zap.String("stream", s.cfg.OddsFilterStream),
+		zap.String("durable", s.cfg.OddsFilterDurable),
+		zap.String("mode", s.cfg.OddsFilterMode),
+	)
+
+	sweep := s.retention() / 2
+	lastSweep := time.Now()
+
+	for {
+		msg, err := msgs.Next(jetstream.NextMaxWait(sweep))
+		switch {
+		case errors.Is(err, nats.ErrTimeout):
+		case errors.Is(err, jetstream.ErrMsgIteratorClosed):
+			return ctx.Err()
+		case err != nil:
+			return err
+		default:
+			s.handle(ctx, js, msg)
+		}
+
+		if time.Since(lastSweep) >= sweep {
+			s.tracker.Expire(time.Now(), s.retention())
+			s.mu.Lock()
+			s.tracked = s.tracker.Len()
+			s.mu.Unlock()
+			lastSweep = time.Now()
+		}
+	}
+}
+
+func (s *Stage) handle(ctx context.Context, js jetstream.JetStream, msg jetstream.Msg) {
+	traceCtx, span := tracing.StartConsume(ctx, msg.Subject(), msg.Headers(), msg.Data())
+	defer span.End()
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		s.logger.Warn("failed to read message metadata", zap.Error(err))
+		_ = msg.Nak()
+		return
+	}
+
+	env, err := domain.ParseEnvelope(msg.Data())
+	var monitoring domain.MatchMonitoring
+	if err == nil && len(env.Payload) > 0 {
+		err = json.Unmarshal(env.Payload, &monitoring)
+	}
+	id, ok := env.CorrelationID()
+	if err != nil || !ok {
+		s.logger.Debug("skipping invalid monitoring event", zap.Uint64("seq", meta.Sequence.Stream), zap.Error(err))
+		_ = msg.Ack()
+		return
+	}
+
+	at := meta.Timestamp
+	if t, ok := env.EventTime(); ok {
+		at = t
+	}
+	issues := s.tracker.Check(id, monitoring, at, time.Now())
+	filtered := Apply(monitoring, issues, s.cfg.OddsFilterMode == domain.OddsFilterSuppress)
+	s.count(monitoring, issues)
+
+	if err := s.publish(traceCtx, js, id, at, meta.Sequence.Stream, filtered); err != nil {
+		s.logger.Warn("failed to publish filtered monitoring",
+			zap.Int64("correlation_id", id),
+			zap.Uint64("seq", meta.Sequence.Stream),
+			zap.Error(err),
+		)
+		_ = msg.Nak()
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		s.logger.Warn("failed to ack monitoring event", zap.Uint64("seq", meta.Sequence.Stream), zap.Error(err))
+	}
+
+	s.mu.Lock()
+	s.published++
+	s.mu.Unlock()
+
+	if len(issues) > 0 {
+		s.logger.Debug("unreliable odds",
+			zap.Int64("correlation_id", id),
+			zap.Int("issues", len(issues)),
+			zap.String("mode", s.cfg.OddsFilterMode),
+		)
+	}
+}
+
+func (s *Stage) count(m domain.MatchMonitoring, issues []domain.OddsIssue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processed++
+	s.tracked = s.tracker.Len()
+	for bookmaker, bets := range m.Bets {
+		s.bookmaker(bookmaker).Checked += uint64(len(bets))
+	}
+	for _, issue := range issues {
+		st := s.bookmaker(issue.Bookmaker)
+		switch issue.Reason {
+		case domain.OddsStale:
+			st.Stale++
+		case domain.OddsFlicker:
+			st.Flicker++
+		}
+		if issue.Suppressed {
+			st.Suppressed++
+		}
+	}
+}
+
+func (s *Stage) bookmaker(name string) *domain.BookmakerOddsStats {
+	st, ok := s.bookmakers[name]
+	if !ok {
+		st = &domain.BookmakerOddsStats{}
+		s.bookmakers[name] = st
+	}
+	return st
+}
+
+// publish сохраняет время исходного события, чтобы потребители считали возраст коэффициентов от него
+func (s *Stage) publish(ctx context.Context, js jetstream.JetStream, id int64, at time.Time, seq uint64,
+	filtered domain.FilteredMonitoring) (err error) {
+	event := domain.NewEvent(domain.EventTypeMatchMonitoring, s.cfg.AppName, id, filtered)
+	event.Header.Timestamp = at.UTC()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := &nats.Msg{Subject: domain.SubjectMatchMonitoringFiltered, Data: data, Header: nats.Header{}}
+	// повторная доставка того же исходного сообщения отбрасывается дедупликацией стрима
+	msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("odds-filter:%d", seq))
+
+	ctx, span := tracing.StartPublish(ctx, msg)
+	defer func() { tracing.End(span, err) }()
+
+	pubCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = js.PublishMsg(pubCtx, msg)
+	return err
+}
@@ -0,0 +1,79 @@
+package oddsfilter_test
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/natstest"
+	"NATS_TIRE_SERVICE/internal/oddsfilter"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go/jetstream"
+	"testing"
+	"time"
+)
+
+func TestStageSuppressesStaleOdds(t *testing.T) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.OddsFilterEnabled = true
+			cfg.OddsTTL = time.Minute
+		}),
+	)
+
+	stage := oddsfilter.NewStage(h.Config, h.Logger, h.Server.ClientConn)
+	if err := stage.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { stage.Stop(context.Background()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		_, err := h.JetStream.Consumer(ctx, domain.StreamEvents, h.Config.OddsFilterDurable)
+		return err == nil
+	}, "odds filter consumer was not created")
+
+	// коэффициенты fonbet из события пятиминутной давности, winline - свежие
+	old := time.Now().Add(-5 * time.Minute).UTC().Format(time.RFC3339Nano)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	h.Publish(domain.SubjectMatchMonitoring, []byte(fmt.Sprintf(`{"payload":{"correlation_id":7,"team_names":["NaVi","G2"],`+
+		`"bets":{"fonbet":[{"bet_market":"match-winner","target_bet":"NaVi","less":2.1,"more":1.7}]},"timestamp":%q}}`, old)))
+	h.Publish(domain.SubjectMatchMonitoring, []byte(fmt.Sprintf(`{"payload":{"correlation_id":7,"team_names":["NaVi","G2"],`+
+		`"bets":{"winline":[{"bet_market":"match-winner","target_bet":"NaVi","less":1.75,"more":2.05}]},"timestamp":%q}}`, now)))
+
+	natstest.Eventually(t, 5*time.Second, func() bool { return stage.Stats().Published == 2 }, "filtered events were not published")
+	stats := stage.Stats()
+	if fonbet := stats.Bookmakers["fonbet"]; fonbet.Checked != 1 || fonbet.Stale != 1 || fonbet.Suppressed != 1 {
+		t.Errorf("fonbet stats = %+v", fonbet)
+	}
+	if winline := stats.Bookmakers["winline"]; winline.Checked != 1 || winline.Suppressed != 0 {
+		t.Errorf("winline stats = %+v", winline)
+	}
+
+	stream, err := h.JetStream.Stream(ctx, domain.StreamEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event struct {
+		Header  domain.EventHeader        `json:"event_header"`
+		Payload domain.FilteredMonitoring `json:"payload"`
+	}
+	// отфильтрованное событие fonbet - первое в events.match.monitoring.filtered
+	raw, err := stream.GetMsg(ctx, 1, jetstream.WithGetMsgSubject(domain.SubjectMatchMonitoringFiltered))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw.Data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Subject != domain.SubjectMatchMonitoringFiltered || event.Header.CorrelationID != 7 ||
+		time.Since(event.Header.Timestamp) < 4*time.Minute {
+		t.Errorf("filtered event %s: %+v", raw.Subject, event.Header)
+	}
+	if bets, ok := event.Payload.Bets["fonbet"]; !ok || len(bets) != 0 ||
+		len(event.Payload.OddsIssues) != 1 || !event.Payload.OddsIssues[0].Suppressed {
+		t.Errorf("filtered payload = %+v", event.Payload)
+	}
+}
@@ -0,0 +1,144 @@
+// Package oddsfilter отслеживает возраст и стабильность коэффициентов букмекеров
+// и отсеивает устаревшие и мигающие коэффициенты из событий мониторинга
+package oddsfilter
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"time"
+)
+
+// oddsKey - коэффициент одного букмекера на рынке матча
+type oddsKey struct {
+	id        int64
+	bookmaker string
+	market    string
+	target    string
+}
+
+type oddsState struct {
+	less    float64
+	more    float64
+	seen    time.Time   // последнее событие, подтвердившее коэффициент
+	changes []time.Time // изменения внутри окна мигания
+}
+
+// Limits - пороги устаревания и мигания
+type Limits struct {
+	TTL               time.Duration
+	FlickerWindow     time.Duration
+	FlickerMaxChanges int
+}
+
+// Tracker хранит историю коэффициентов по correlation id, букмекеру и рынку.
+// Не потокобезопасен, им владеет Stage.
+type Tracker struct {
+	limits Limits
+	odds   map[oddsKey]*oddsState
+}
+
+func NewTracker(limits Limits) *Tracker {
+	return &Tracker{limits: limits, odds: make(map[oddsKey]*oddsState)}
+}
+
+// Check учитывает коэффициенты события со временем at и возвращает ненадежные на момент now.
+// Возраст коэффициента - время с последнего события, которое его подтвердило: повторение
+// того же значения обновляет возраст, а мигание считается только по изменениям.
+// Событие старше уже известного (пришло не по порядку) историю не меняет, а его коэффициенты устаревшие.
+func (t *Tracker) Check(id int64, m domain.MatchMonitoring, at, now time.Time) []domain.OddsIssue {
+	var issues []domain.OddsIssue
+
+	for bookmaker, bets := range m.Bets {
+		for _, bet := range bets {
+			key := oddsKey{id: id, bookmaker: bookmaker, market: bet.BetMarket, target: bet.TargetBet}
+			issue := domain.OddsIssue{Bookmaker: bookmaker, BetMarket: bet.BetMarket, TargetBet: bet.TargetBet}
+
+			s, ok := t.odds[key]
+			switch {
+			case !ok:
+				s = &oddsState{less: bet.Less, more: bet.More, seen: at}
+				t.odds[key] = s
+			case at.Before(s.seen):
+				issue.Reason = domain.OddsStale
+				issue.AgeSeconds = seconds(now.Sub(at))
+				issues = append(issues, issue)
+				continue
+			default:
+				if bet.Less != s.less || bet.More != s.more {
+					s.less, s.more = bet.Less, bet.More
+					s.changes = append(s.changes, at)
+				}
+				s.seen = at
+			}
+			s.prune(at.Add(-t.limits.FlickerWindow))
+
+			age := now.Sub(s.seen)
+			issue.AgeSeconds = seconds(age)
+			issue.Changes = len(s.changes)
+			switch {
+			case age > t.limits.TTL:
+				issue.Reason = domain.OddsStale
+			case len(s.changes) > t.limits.FlickerMaxChanges:
+				issue.Reason = domain.OddsFlicker
+			default:
+				continue
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Expire забывает коэффициенты, которых не было в событиях дольше after
+func (t *Tracker) Expire(now time.Time, after time.Duration) int {
+	removed := 0
+	for key, s := range t.odds {
+		if now.Sub(s.seen) > after {
+			delete(t.odds, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (t *Tracker) Len() int {
+	return len(t.odds)
+}
+
+func (s *oddsState) prune(before time.Time) {
+	i := 0
+	for i < len(s.changes) && s.changes[i].Before(before) {
+		i++
+	}
+	s.changes = s.changes[i:]
+}
+
+// Apply собирает отфильтрованный мониторинг. При suppress ставки с проблемами удаляются,
+// но букмекер остается с пустым списком, чтобы потребители забыли его прежние коэффициенты.
+func Apply(m domain.MatchMonitoring, issues []domain.OddsIssue, suppress bool) domain.FilteredMonitoring {
+	out := domain.FilteredMonitoring{MatchMonitoring: m, OddsIssues: issues}
+	if !suppress || len(issues) == 0 {
+		return out
+	}
+
+	bad := make(map[oddsKey]bool, len(issues))
+	for i := range issues {
+		issues[i].Suppressed = true
+		bad[oddsKey{bookmaker: issues[i].Bookmaker, market: issues[i].BetMarket, target: issues[i].TargetBet}] = true
+	}
+
+	out.Bets = make(map[string][]domain.MonitoringBet, len(m.Bets))
+	for bookmaker, bets := range m.Bets {
+		kept := make([]domain.MonitoringBet, 0, len(bets))
+		for _, bet := range bets {
+			if !bad[oddsKey{bookmaker: bookmaker, market: bet.BetMarket, target: bet.TargetBet}] {
+				kept = append(kept, bet)
+			}
+		}
+		out.Bets[bookmaker] = kept
+	}
+	return out
+}
+
+func seconds(d time.Duration) float64 {
+	return float64(d.Round(time.Millisecond)) / float64(time.Second)
+}
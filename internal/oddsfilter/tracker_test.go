@@ -0,0 +1,100 @@
+package oddsfilter
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"testing"
+	"time"
+)
+
+var limits = Limits{TTL: time.Minute, FlickerWindow: 10 * time.Second, FlickerMaxChanges: 2}
+
+func odds(bookmaker string, less float64) domain.MatchMonitoring {
+	return domain.MatchMonitoring{Bets: map[string][]domain.MonitoringBet{
+		bookmaker: {{BetMarket: "total-maps", TargetBet: "2.5", Less: less, More: 1.8}},
+	}}
+}
+
+func TestTrackerStale(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	tr := NewTracker(limits)
+
+	if issues := tr.Check(1, odds("fonbet", 2.0), start, start); len(issues) != 0 {
+		t.Fatalf("fresh odds: %+v", issues)
+	}
+	// событие пришло с задержкой больше TTL
+	issues := tr.Check(1, odds("fonbet", 2.0), start.Add(time.Second), start.Add(62*time.Second))
+	if len(issues) != 1 || issues[0].Reason != domain.OddsStale || issues[0].AgeSeconds != 61 {
+		t.Fatalf("delayed odds: %+v", issues)
+	}
+	// свежее событие с другим значением
+	if issues := tr.Check(1, odds("fonbet", 2.1), start.Add(62*time.Second), start.Add(62*time.Second)); len(issues) != 0 {
+		t.Errorf("changed odds: %+v", issues)
+	}
+	// событие пришло не по порядку
+	issues = tr.Check(1, odds("fonbet", 1.9), start.Add(30*time.Second), start.Add(63*time.Second))
+	if len(issues) != 1 || issues[0].Reason != domain.OddsStale {
+		t.Errorf("out of order odds: %+v", issues)
+	}
+	// задержанное событие другого букмекера
+	issues = tr.Check(1, odds("winline", 2.0), start, start.Add(2*time.Minute))
+	if len(issues) != 1 || issues[0].Bookmaker != "winline" || issues[0].Reason != domain.OddsStale {
+		t.Errorf("delayed event: %+v", issues)
+	}
+}
+
+func TestTrackerStableOdds(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	tr := NewTracker(limits)
+
+	// букмекер подтверждает одно и то же значение дольше TTL - это не устаревание и не мигание
+	for i := 0; i <= 5; i++ {
+		at := start.Add(time.Duration(i) * 30 * time.Second)
+		if issues := tr.Check(1, odds("fonbet", 2.0), at, at.Add(time.Second)); len(issues) != 0 {
+			t.Fatalf("repeated odds at %s: %+v", at.Sub(start), issues)
+		}
+	}
+}
+
+func TestTrackerFlicker(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	tr := NewTracker(limits)
+
+	var issues []domain.OddsIssue
+	for i, less := range []float64{2.0, 2.1, 2.0, 2.1} {
+		at := start.Add(time.Duration(i) * time.Second)
+		issues = tr.Check(1, odds("fonbet", less), at, at)
+	}
+	if len(issues) != 1 || issues[0].Reason != domain.OddsFlicker || issues[0].Changes != 3 {
+		t.Fatalf("flicker: %+v", issues)
+	}
+
+	// изменения вышли из окна
+	at := start.Add(15 * time.Second)
+	if issues := tr.Check(1, odds("fonbet", 2.1), at, at); len(issues) != 0 {
+		t.Errorf("after window: %+v", issues)
+	}
+
+	if removed := tr.Expire(at.Add(time.Hour), time.Minute); removed != 1 || tr.Len() != 0 {
+		t.Errorf("expire removed %d, left %d", removed, tr.Len())
+	}
+}
+
+func TestApply(t *testing.T) {
+	m := odds("fonbet", 2.0)
+	m.Bets["winline"] = []domain.MonitoringBet{{BetMarket: "total-maps", TargetBet: "2.5", Less: 1.7, More: 2.1}}
+	issues := []domain.OddsIssue{{Bookmaker: "fonbet", BetMarket: "total-maps", TargetBet: "2.5", Reason: domain.OddsStale}}
+
+	annotated := Apply(m, append([]domain.OddsIssue(nil), issues...), false)
+	if len(annotated.Bets["fonbet"]) != 1 || annotated.OddsIssues[0].Suppressed {
+		t.Errorf("annotate = %+v", annotated)
+	}
+
+	suppressed := Apply(m, issues, true)
+	bets, ok := suppressed.Bets["fonbet"]
+	if !ok || len(bets) != 0 || len(suppressed.Bets["winline"]) != 1 || !suppressed.OddsIssues[0].Suppressed {
+		t.Errorf("suppress = %+v", suppressed)
+	}
+	if len(m.Bets["fonbet"]) != 1 {
+		t.Error("source monitoring was modified")
+	}
+}
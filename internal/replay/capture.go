@@ -0,0 +1,74 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"time"
+)
+
+// CaptureOptions - окно записи: по времени, по числу сообщений или до отмены контекста
+type CaptureOptions struct {
+	Filter   string
+	Duration time.Duration
+	Messages int
+}
+
+type CaptureStats struct {
+	Messages int
+	Bytes    int
+	Elapsed  time.Duration
+	Dropped  int
+}
+
+// Capture подписывается на Filter и пишет каждое сообщение с его смещением от начала записи.
+// Подписка обычная (не JetStream), поэтому в запись попадает трафик как он есть, без влияния на consumer'ы.
+func Capture(ctx context.Context, nc *nats.Conn, w *Writer, opts CaptureOptions) (CaptureStats, error) {
+	var stats CaptureStats
+
+	sub, err := nc.SubscribeSync(opts.Filter)
+	if err != nil {
+		return stats, fmt.Errorf("failed to subscribe to %s: %w", opts.Filter, err)
+	}
+	defer sub.Unsubscribe()
+	if err := sub.SetPendingLimits(-1, -1); err != nil {
+		return stats, fmt.Errorf("failed to set pending limits: %w", err)
+	}
+	if err := nc.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to flush subscription: %w", err)
+	}
+
+	started := time.Now()
+	startedUTC := started.UTC()
+	if err := w.Write(Record{Type: recordHeader, Version: FormatVersion, Filter: opts.Filter, Started: &startedUTC}); err != nil {
+		return stats, fmt.Errorf("failed to write recording header: %w", err)
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	for opts.Messages == 0 || stats.Messages < opts.Messages {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return stats, fmt.Errorf("failed to receive message: %w", err)
+		}
+
+		if err := w.Write(messageRecord(time.Since(started), msg)); err != nil {
+			return stats, fmt.Errorf("failed to write message: %w", err)
+		}
+		stats.Messages++
+		stats.Bytes += len(msg.Data)
+	}
+
+	stats.Elapsed = time.Since(started)
+	if dropped, err := sub.Dropped(); err == nil {
+		stats.Dropped = dropped
+	}
+	return stats, nil
+}
@@ -0,0 +1,162 @@
+// Package replay записывает поток событий в файл и воспроизводит его на другом сервере
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// FormatVersion меняется при несовместимых изменениях формата файла
+const FormatVersion = 1
+
+const (
+	recordHeader  = "recording"
+	recordMessage = "msg"
+)
+
+// Record - строка файла записи (JSON lines). Первая строка - описание записи, дальше сообщения
+// в порядке получения. Offset - время от начала записи, по нему восстанавливаются интервалы.
+// JSON-сообщения хранятся как есть в Event, чтобы запись можно было читать и править, остальные - в Data (base64).
+type Record struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version,omitempty"`
+	Filter  string          `json:"filter,omitempty"`
+	Started *time.Time      `json:"started,omitempty"`
+	Offset  time.Duration   `json:"offset_ns,omitempty"`
+	Subject string          `json:"subject,omitempty"`
+	Headers nats.Header     `json:"headers,omitempty"`
+	Event   json.RawMessage `json:"event,omitempty"`
+	Data    []byte          `json:"data,omitempty"`
+}
+
+func messageRecord(offset time.Duration, msg *nats.Msg) Record {
+	rec := Record{Type: recordMessage, Offset: offset, Subject: msg.Subject, Headers: msg.Header}
+	if json.Valid(msg.Data) {
+		rec.Event = msg.Data
+	} else {
+		rec.Data = msg.Data
+	}
+	return rec
+}
+
+// Payload - тело сообщения независимо от того, как оно сохранено
+func (r Record) Payload() []byte {
+	if r.Event != nil {
+		return r.Event
+	}
+	return r.Data
+}
+
+// Writer пишет файл записи, файлы с расширением .gz сжимаются
+type Writer struct {
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	w := &Writer{file: f}
+	var out io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		w.gz = gzip.NewWriter(f)
+		out = w.gz
+	}
+	w.buf = bufio.NewWriter(out)
+	w.enc = json.NewEncoder(w.buf)
+	w.enc.SetEscapeHTML(false)
+	return w, nil
+}
+
+func (w *Writer) Write(r Record) error {
+	return w.enc.Encode(r)
+}
+
+func (w *Writer) Close() error {
+	err := w.buf.Flush()
+	if w.gz != nil {
+		err = errors.Join(err, w.gz.Close())
+	}
+	return errors.Join(err, w.file.Close())
+}
+
+// Reader читает файл записи и проверяет его заголовок
+type Reader struct {
+	file   *os.File
+	gz     *gzip.Reader
+	dec    *json.Decoder
+	Header Record
+}
+
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+
+	r := &Reader{file: f}
+	var in io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		if r.gz, err = gzip.NewReader(in); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		in = r.gz
+	}
+	r.dec = json.NewDecoder(in)
+
+	if err := r.dec.Decode(&r.Header); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+	if r.Header.Type != recordHeader {
+		r.Close()
+		return nil, fmt.Errorf("not a recording file: first record type is %q", r.Header.Type)
+	}
+	if r.Header.Started == nil {
+		r.Close()
+		return nil, errors.New("recording header has no start time")
+	}
+	if r.Header.Version > FormatVersion {
+		r.Close()
+		return nil, fmt.Errorf("unsupported recording version %d", r.Header.Version)
+	}
+	return r, nil
+}
+
+// Next возвращает следующее сообщение или io.EOF в конце файла
+func (r *Reader) Next() (Record, error) {
+	for {
+		var rec Record
+		if err := r.dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return rec, io.EOF
+			}
+			return rec, fmt.Errorf("failed to read record: %w", err)
+		}
+		if rec.Type == recordMessage {
+			return rec, nil
+		}
+	}
+}
+
+func (r *Reader) Close() error {
+	var err error
+	if r.gz != nil {
+		err = r.gz.Close()
+	}
+	return errors.Join(err, r.file.Close())
+}
@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"io"
+	"time"
+)
+
+// Publish отправляет сообщение: в JetStream с ожиданием подтверждения или обычной публикацией
+type Publish func(ctx context.Context, msg *nats.Msg) error
+
+type ReplayOptions struct {
+	Speed        float64 // 1 - исходная скорость, 10 - в десять раз быстрее, 0 - без пауз
+	RewriteIDs   bool    // новый event_id (и Nats-Msg-Id), чтобы дедупликация стрима не отбросила повтор
+	RewriteTimes bool    // сдвинуть время событий на разницу между записью и воспроизведением
+}
+
+type ReplayStats struct {
+	Messages int
+	Failed   int
+	Elapsed  time.Duration
+	// Lag - максимальное отставание от расписания, показывает, успевает ли сервер за выбранной скоростью
+	Lag time.Duration
+	// LastError - последняя ошибка публикации, если были неудачные
+	LastError error
+}
+
+// Replay публикует сообщения файла, сохраняя интервалы между ними с учетом Speed.
+// Ошибки публикации считаются и не прерывают воспроизведение.
+func Replay(ctx context.Context, r *Reader, publish Publish, opts ReplayOptions) (ReplayStats, error) {
+	var stats ReplayStats
+	started := time.Now()
+
+	for ctx.Err() == nil {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		if opts.Speed > 0 {
+			due := started.Add(time.Duration(float64(rec.Offset) / opts.Speed))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					continue
+				case <-time.After(wait):
+				}
+			} else {
+				stats.Lag = max(stats.Lag, -wait)
+			}
+		}
+
+		msg := &nats.Msg{Subject: rec.Subject, Header: rec.Headers, Data: rec.Payload()}
+		if opts.RewriteIDs || opts.RewriteTimes {
+			shift := time.Since(r.Header.Started.Add(rec.Offset))
+			if msg.Data, err = Rewrite(msg, opts, shift); err != nil {
+				// не событие (или не JSON) - публикуем как было записано
+				msg.Data = rec.Payload()
+			}
+		}
+
+		if err := publish(ctx, msg); err != nil {
+			stats.Failed++
+			stats.LastError = err
+			continue
+		}
+		stats.Messages++
+	}
+
+	stats.Elapsed = time.Since(started)
+	return stats, ctx.Err()
+}
+
+// Rewrite меняет event_id и время события, не трогая остальные поля сообщения.
+// Время в event_header и в payload (Timestamp / timestamp) сдвигается на shift.
+func Rewrite(msg *nats.Msg, opts ReplayOptions, shift time.Duration) ([]byte, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := event["event_header"]; ok {
+		var header map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return nil, fmt.Errorf("invalid event_header: %w", err)
+		}
+
+		if opts.RewriteIDs {
+			id := uuid.NewString()
+			header["event_id"], _ = json.Marshal(id)
+			if msg.Header.Get(nats.MsgIdHdr) != "" {
+				msg.Header.Set(nats.MsgIdHdr, id)
+			}
+		}
+		if opts.RewriteTimes {
+			shiftTime(header, "timestamp", shift)
+		}
+
+		var err error
+		if event["event_header"], err = json.Marshal(header); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := event["payload"]; ok && opts.RewriteTimes {
+		var payload map[string]json.RawMessage
+		if json.Unmarshal(raw, &payload) == nil {
+			if shiftTime(payload, "Timestamp", shift) || shiftTime(payload, "timestamp", shift) {
+				var err error
+				if event["payload"], err = json.Marshal(payload); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return json.Marshal(event)
+}
+
+func shiftTime(fields map[string]json.RawMessage, key string, shift time.Duration) bool {
+	raw, ok := fields[key]
+	if !ok {
+		return false
+	}
+	var t time.Time
+	if err := json.Unmarshal(raw, &t); err != nil || t.IsZero() {
+		return false
+	}
+	fields[key], _ = json.Marshal(t.Add(shift))
+	return true
+}
@@ -0,0 +1,89 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nats-io/nats.go"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	for _, name := range []string{"traffic.jsonl", "traffic.jsonl.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		started := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+
+		w, err := Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := []Record{
+			{Type: recordHeader, Version: FormatVersion, Filter: "events.>", Started: &started},
+			messageRecord(10*time.Millisecond, &nats.Msg{Subject: "events.fork.found", Data: []byte(`{"payload":{}}`)}),
+			messageRecord(25*time.Millisecond, &nats.Msg{Subject: "events.raw", Data: []byte{0xff, 0x00}}),
+		}
+		for _, rec := range records {
+			if err := w.Write(rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := Open(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var got []*nats.Msg
+		stats, err := Replay(context.Background(), r, func(_ context.Context, msg *nats.Msg) error {
+			got = append(got, msg)
+			return nil
+		}, ReplayOptions{})
+		r.Close()
+		if err != nil || stats.Messages != 2 {
+			t.Fatalf("%s: replayed %d, err %v", name, stats.Messages, err)
+		}
+		if got[0].Subject != "events.fork.found" || string(got[0].Data) != `{"payload":{}}` || string(got[1].Data) != "\xff\x00" {
+			t.Errorf("%s: replayed %q %q", name, got[0].Data, got[1].Data)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	data := `{"event_header":{"event_id":"old","event_type":"fork.found","timestamp":"2026-05-01T18:00:00Z","correlation_id":7},` +
+		`"payload":{"CorrelationID":7,"Profit":1.5,"Timestamp":"2026-05-01T17:59:59Z"}}`
+	msg := &nats.Msg{Data: []byte(data), Header: nats.Header{nats.MsgIdHdr: []string{"old"}}}
+
+	out, err := Rewrite(msg, ReplayOptions{RewriteIDs: true, RewriteTimes: true}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event struct {
+		Header struct {
+			EventID       string    `json:"event_id"`
+			Timestamp     time.Time `json:"timestamp"`
+			CorrelationID int64     `json:"correlation_id"`
+		} `json:"event_header"`
+		Payload struct {
+			Profit    float64
+			Timestamp time.Time
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(out, &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Header.EventID == "old" || msg.Header.Get(nats.MsgIdHdr) != event.Header.EventID {
+		t.Errorf("event_id %q, Nats-Msg-Id %q", event.Header.EventID, msg.Header.Get(nats.MsgIdHdr))
+	}
+	if !event.Header.Timestamp.Equal(time.Date(2026, 5, 1, 19, 0, 0, 0, time.UTC)) ||
+		!event.Payload.Timestamp.Equal(time.Date(2026, 5, 1, 18, 59, 59, 0, time.UTC)) {
+		t.Errorf("timestamps %s, %s", event.Header.Timestamp, event.Payload.Timestamp)
+	}
+	if event.Header.CorrelationID != 7 || event.Payload.Profit != 1.5 {
+		t.Errorf("other fields changed: %s", out)
+	}
+}
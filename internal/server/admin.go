@@ -0,0 +1,80 @@
+package server
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"crypto/subtle"
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// requireAdmin пропускает запрос только с заголовком Authorization: Bearer <ADMIN_TOKEN>
+func (s *HTTPServer) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminToken := s.cfg.Secret(&s.cfg.AdminToken)
+		if !adminToken.IsSet() {
+			s.sendJSONResponse(w, http.StatusNotFound, domain.ErrorResponse{Error: "admin API is disabled"})
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken.Value())) != 1 {
+			s.logger.Warn("unauthorized admin request",
+				zap.String("path", r.URL.Path),
+				zap.String("client", r.RemoteAddr),
+			)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.sendJSONResponse(w, http.StatusUnauthorized, domain.ErrorResponse{Error: "unauthorized"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *HTTPServer) logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req domain.LogLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: "invalid request body"})
+			return
+		}
+
+		if req.Level != nil {
+			if err := s.logger.SetLevel(*req.Level); err != nil {
+				s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+				return
+			}
+		}
+
+		if req.ServerDebug != nil || req.ServerTrace != nil {
+			debug, trace := s.natsServer.ServerLogging()
+			if req.ServerDebug != nil {
+				debug = *req.ServerDebug
+			}
+			if req.ServerTrace != nil {
+				trace = *req.ServerTrace
+			}
+			s.natsServer.SetServerLogging(debug, trace)
+		}
+
+		s.logger.Notice("log level changed via admin API",
+			zap.String("level", s.logger.Level()),
+			zap.String("client", r.RemoteAddr),
+		)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	debug, trace := s.natsServer.ServerLogging()
+	s.sendJSONResponse(w, http.StatusOK, domain.LogLevelResponse{
+		Level:       s.logger.Level(),
+		ServerDebug: debug,
+		ServerTrace: trace,
+	})
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/normalize"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// SetNormalizer подключает словарь алиасов к /admin/aliases
+func (s *HTTPServer) SetNormalizer(normalizer *normalize.Normalizer) {
+	s.normalizer = normalizer
+}
+
+// aliasesHandler - /admin/aliases: GET - словарь и ненайденные названия, PUT - создать или заменить запись,
+// DELETE ?kind=&canonical= - удалить запись
+func (s *HTTPServer) aliasesHandler(w http.ResponseWriter, r *http.Request) {
+	if s.normalizer == nil {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "normalizer is disabled"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.sendJSONResponse(w, http.StatusOK, s.normalizer.Aliases())
+	case http.MethodPut:
+		var req domain.AliasEntry
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: "invalid request body"})
+			return
+		}
+
+		entry, err := s.normalizer.PutAlias(r.Context(), req)
+		switch {
+		case errors.Is(err, normalize.ErrInvalidEntry):
+			s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, normalize.ErrConflict):
+			s.sendJSONResponse(w, http.StatusConflict, domain.ErrorResponse{Error: err.Error()})
+		case err != nil:
+			s.logger.Error("failed to store alias entry", zap.Error(err))
+			s.sendJSONResponse(w, http.StatusInternalServerError, domain.ErrorResponse{Error: "failed to store alias entry"})
+		default:
+			s.sendJSONResponse(w, http.StatusOK, entry)
+		}
+	case http.MethodDelete:
+		kind, canonical := r.URL.Query().Get("kind"), strings.TrimSpace(r.URL.Query().Get("canonical"))
+		if kind == "" || canonical == "" {
+			s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: "kind and canonical are required"})
+			return
+		}
+
+		err := s.normalizer.DeleteAlias(r.Context(), kind, canonical)
+		switch {
+		case errors.Is(err, normalize.ErrNotFound):
+			s.sendJSONResponse(w, http.StatusNotFound, domain.ErrorResponse{Error: err.Error()})
+		case err != nil:
+			s.logger.Error("failed to delete alias entry", zap.Error(err))
+			s.sendJSONResponse(w, http.StatusInternalServerError, domain.ErrorResponse{Error: "failed to delete alias entry"})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+	}
+}
+
+// resolveAliasHandler - GET /admin/aliases/resolve?kind=team&name=...: проверка совпадения и его уверенности
+func (s *HTTPServer) resolveAliasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if s.normalizer == nil {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "normalizer is disabled"})
+		return
+	}
+
+	kind, name := r.URL.Query().Get("kind"), r.URL.Query().Get("name")
+	if kind != domain.AliasKindTeam && kind != domain.AliasKindMarket {
+		s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: "kind must be team or market"})
+		return
+	}
+	if strings.TrimSpace(name) == "" {
+		s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: "name is required"})
+		return
+	}
+	s.sendJSONResponse(w, http.StatusOK, s.normalizer.Resolve(kind, name))
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"NATS_TIRE_SERVICE/internal/consumers"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"net/http"
+)
+
+// SetConsumerMonitor подключает мониторинг консюмеров к /consumers и /admin/consumers/stale
+func (s *HTTPServer) SetConsumerMonitor(monitor *consumers.Monitor) {
+	s.consumers = monitor
+}
+
+// consumersHandler - GET /consumers: последний снимок durable консюмеров и активные алерты
+func (s *HTTPServer) consumersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if s.consumers == nil {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "consumer monitor is disabled"})
+		return
+	}
+
+	s.sendJSONResponse(w, http.StatusOK, s.consumers.Status())
+}
+
+// staleConsumersHandler - GET /admin/consumers/stale: неактивные durable консюмеры и примененная политика
+func (s *HTTPServer) staleConsumersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if s.consumers == nil || !s.cfg.StaleConsumersEnabled {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "stale consumer detection is disabled"})
+		return
+	}
+
+	s.sendJSONResponse(w, http.StatusOK, s.consumers.Stale())
+}
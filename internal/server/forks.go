@@ -0,0 +1,27 @@
+package server
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/forks"
+	"net/http"
+)
+
+// SetForkDetector подключает встроенный поиск вилок к /forks
+func (s *HTTPServer) SetForkDetector(detector *forks.Detector) {
+	s.forks = detector
+}
+
+// forksHandler - GET /forks: действующие опубликованные вилки
+func (s *HTTPServer) forksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if s.forks == nil {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "fork detector is disabled"})
+		return
+	}
+
+	s.sendJSONResponse(w, http.StatusOK, s.forks.Forks())
+}
@@ -2,13 +2,20 @@ package server
 
 import (
 	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/consumers"
 	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/forks"
+	"NATS_TIRE_SERVICE/internal/journey"
 	"NATS_TIRE_SERVICE/internal/nats"
+	"NATS_TIRE_SERVICE/internal/normalize"
+	"NATS_TIRE_SERVICE/internal/oddsfilter"
+	"NATS_TIRE_SERVICE/internal/tracing"
 	"NATS_TIRE_SERVICE/pkg/utils"
 	"context"
 	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"time"
 )
@@ -20,6 +27,11 @@ type HTTPServer struct {
 	startTime  time.Time
 	natsServer *nats.Server
 	cfg        *config.Config
+	journeys   *journey.Index
+	consumers  *consumers.Monitor
+	forks      *forks.Detector
+	oddsFilter *oddsfilter.Stage
+	normalizer *normalize.Normalizer
 }
 
 func NewHTTPServer(port int, logger *utils.Logger, natsServer *nats.Server, cfg *config.Config) *HTTPServer {
@@ -29,7 +41,7 @@ func NewHTTPServer(port int, logger *utils.Logger, natsServer *nats.Server, cfg
 	server := &HTTPServer{
 		server: &http.Server{
 			Addr:         fmt.Sprintf(":%d", port),
-			Handler:      mux,
+			Handler:      tracing.Middleware(mux),
 			ReadTimeout:  5 * time.Second,
 			WriteTimeout: 10 * time.Second,
 			IdleTimeout:  20 * time.Second,
@@ -43,6 +55,15 @@ func NewHTTPServer(port int, logger *utils.Logger, natsServer *nats.Server, cfg
 	mux.HandleFunc("/ready", server.readyHandler)
 	mux.HandleFunc("/live", server.liveHandler)
 	mux.HandleFunc("/metrics", server.metricsHandler)
+	mux.HandleFunc("/consumers", server.consumersHandler)
+	mux.HandleFunc("/journeys", server.journeysHandler)
+	mux.HandleFunc("/journeys/", server.journeysHandler)
+	mux.HandleFunc("/forks", server.forksHandler)
+	mux.HandleFunc("/odds-filter", server.oddsFilterHandler)
+	mux.HandleFunc("/admin/log-level", server.requireAdmin(server.logLevelHandler))
+	mux.HandleFunc("/admin/consumers/stale", server.requireAdmin(server.staleConsumersHandler))
+	mux.HandleFunc("/admin/aliases", server.requireAdmin(server.aliasesHandler))
+	mux.HandleFunc("/admin/aliases/resolve", server.requireAdmin(server.resolveAliasHandler))
 	mux.HandleFunc("/", server.rootHandler)
 
 	server.natsServer = natsServer
@@ -50,13 +71,21 @@ func NewHTTPServer(port int, logger *utils.Logger, natsServer *nats.Server, cfg
 	return server
 }
 
+// Start занимает порт синхронно, чтобы ошибка бинда вернулась вызывающему, и обслуживает запросы в фоне
 func (s *HTTPServer) Start() error {
 	s.logger.Infof("http server starting on port %d", s.port)
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		s.logger.Errorf("http server error %w", err)
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", s.port, err)
 	}
 
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Errorf("http server error %v", err)
+		}
+	}()
+
 	return nil
 }
 
@@ -67,7 +96,7 @@ func (s *HTTPServer) Stop() error {
 	defer cancel()
 
 	if err := s.server.Shutdown(ctx); err != nil {
-		s.logger.Errorf("http server shutdown error %w", err)
+		return fmt.Errorf("http server shutdown error: %w", err)
 	}
 
 	s.logger.Infof("http server stopped")
@@ -102,18 +131,25 @@ func (s *HTTPServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
-// TODO: доработать проверку готовности сервиса к работе
 func (s *HTTPServer) readyHandler(w http.ResponseWriter, r *http.Request) {
 
+	status, code := "ready", http.StatusOK
+	switch {
+	case s.natsServer == nil || !s.natsServer.IsRunning():
+		status, code = "not_ready", http.StatusServiceUnavailable
+	case s.natsServer.IsDraining():
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+
 	response := domain.HealthResponse{
 		Service:   s.cfg.AppName,
 		Version:   s.cfg.Version,
-		Status:    "ready",
+		Status:    status,
 		Timestamp: time.Now().UTC(),
 		Uptime:    time.Since(s.startTime).String(),
 	}
 
-	s.sendJSONResponse(w, http.StatusOK, response)
+	s.sendJSONResponse(w, code, response)
 }
 
 // TODO: доработать проверку isAlive сервиса
@@ -138,6 +174,16 @@ func (s *HTTPServer) metricsHandler(w http.ResponseWriter, r *http.Request) {
 		"current_time":   time.Now().UTC().Format(time.RFC3339),
 	}
 
+	if s.natsServer != nil {
+		dropped := s.natsServer.LogDropped()
+		var droppedTotal uint64
+		for _, n := range dropped {
+			droppedTotal += n
+		}
+		metrics["nats_log_dropped"] = dropped
+		metrics["nats_log_dropped_total"] = droppedTotal
+	}
+
 	s.sendJSONResponse(w, http.StatusOK, metrics)
 }
 
@@ -151,7 +197,7 @@ func (s *HTTPServer) rootHandler(w http.ResponseWriter, r *http.Request) {
 	info := map[string]interface{}{
 		"service":       s.cfg.AppName,
 		"version":       s.cfg.Version,
-		"endpoints":     []string{"/health", "/ready", "/live", "/metrics"},
+		"endpoints":     []string{"/health", "/ready", "/live", "/metrics", "/consumers", "/journeys/{correlation_id}", "/forks", "/odds-filter"},
 		"documentation": "Health check endpoints for NATS service",
 	}
 
@@ -0,0 +1,418 @@
+package server_test
+
+import (
+	"NATS_TIRE_SERVICE/internal/alerts"
+	"NATS_TIRE_SERVICE/internal/config"
+	"NATS_TIRE_SERVICE/internal/consumers"
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/forks"
+	"NATS_TIRE_SERVICE/internal/journey"
+	"NATS_TIRE_SERVICE/internal/natstest"
+	"NATS_TIRE_SERVICE/internal/normalize"
+	"NATS_TIRE_SERVICE/internal/oddsfilter"
+	"NATS_TIRE_SERVICE/internal/server"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go/jetstream"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const adminToken = "test-admin-token"
+
+type client struct {
+	t    *testing.T
+	base string
+}
+
+// do выполняет запрос и разбирает JSON ответ в out (если out не nil)
+func (c client) do(method, path, token, body string, out interface{}) int {
+	c.t.Helper()
+
+	req, err := http.NewRequest(method, c.base+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			c.t.Fatalf("%s %s: invalid JSON %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c client) get(path string, out interface{}) int {
+	c.t.Helper()
+	return c.do(http.MethodGet, path, "", "", out)
+}
+
+// startService поднимает сервер со всеми компонентами, которые обслуживает HTTP API
+func startService(t *testing.T) (*natstest.Harness, client) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.AdminToken = domain.Secret(adminToken)
+			cfg.LagCheckInterval = 50 * time.Millisecond
+			cfg.StaleConsumerAfter = time.Hour
+			cfg.StaleConsumerDeleteAfter = 2 * time.Hour
+		}),
+	)
+
+	journeys := journey.NewService(h.Config, h.Logger, h.Server.ClientConn)
+	monitor := consumers.NewMonitor(h.Config, h.Logger, h.Server.ClientConn,
+		alerts.NewManager(h.Config.AppName, nil, 0, h.Logger))
+
+	startComponent(t, journeys.Start, journeys.Stop)
+	startComponent(t, monitor.Start, monitor.Stop)
+
+	base := h.StartHTTP(func(s *server.HTTPServer) {
+		s.SetJourneyIndex(journeys.Index())
+		s.SetConsumerMonitor(monitor)
+	})
+	return h, client{t: t, base: base}
+}
+
+// startComponent запускает компонент сервиса и останавливает его в конце теста
+func startComponent(t *testing.T, start, stop func(context.Context) error) {
+	t.Helper()
+	if err := start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := stop(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	h, c := startService(t)
+
+	var health domain.HealthResponse
+	if code := c.get("/health", &health); code != http.StatusOK || health.Status != "healthy" || health.Service != "natstest" {
+		t.Errorf("/health = %d %+v", code, health)
+	}
+
+	var live map[string]interface{}
+	if code := c.get("/live", &live); code != http.StatusOK || live["status"] != "alive" {
+		t.Errorf("/live = %d %v", code, live)
+	}
+
+	var metrics map[string]interface{}
+	if code := c.get("/metrics", &metrics); code != http.StatusOK {
+		t.Errorf("/metrics = %d", code)
+	}
+	for _, key := range []string{"uptime_seconds", "nats_log_dropped", "nats_log_dropped_total"} {
+		if _, ok := metrics[key]; !ok {
+			t.Errorf("/metrics has no %s", key)
+		}
+	}
+
+	var root map[string]interface{}
+	if code := c.get("/", &root); code != http.StatusOK || root["endpoints"] == nil {
+		t.Errorf("/ = %d %v", code, root)
+	}
+	if code := c.get("/unknown", nil); code != http.StatusNotFound {
+		t.Errorf("/unknown = %d, want 404", code)
+	}
+
+	var ready domain.HealthResponse
+	if code := c.get("/ready", &ready); code != http.StatusOK || ready.Status != "ready" {
+		t.Errorf("/ready = %d %+v", code, ready)
+	}
+	if err := h.Server.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if code := c.get("/ready", &ready); code != http.StatusServiceUnavailable || ready.Status != "not_ready" {
+		t.Errorf("/ready after stop = %d %+v", code, ready)
+	}
+}
+
+func TestLogLevelEndpoint(t *testing.T) {
+	_, c := startService(t)
+
+	if code := c.do(http.MethodGet, "/admin/log-level", "", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("without token = %d, want 401", code)
+	}
+	if code := c.do(http.MethodGet, "/admin/log-level", "wrong", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("with wrong token = %d, want 401", code)
+	}
+
+	var level domain.LogLevelResponse
+	if code := c.do(http.MethodGet, "/admin/log-level", adminToken, "", &level); code != http.StatusOK || level.Level != "info" {
+		t.Errorf("GET = %d %+v", code, level)
+	}
+
+	body := `{"level":"debug","server_debug":true}`
+	if code := c.do(http.MethodPut, "/admin/log-level", adminToken, body, &level); code != http.StatusOK ||
+		level.Level != "debug" || !level.ServerDebug {
+		t.Errorf("PUT = %d %+v", code, level)
+	}
+	if code := c.do(http.MethodPut, "/admin/log-level", adminToken, `{"level":"loud"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("PUT invalid level = %d, want 400", code)
+	}
+	if code := c.do(http.MethodDelete, "/admin/log-level", adminToken, "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE = %d, want 405", code)
+	}
+}
+
+func TestJourneyEndpoint(t *testing.T) {
+	h, c := startService(t)
+
+	h.Publish(domain.SubjectBundleMatch, []byte(`{"event_header":{"event_id":"e1","event_type":"match.bundle",`+
+		`"timestamp":"2026-01-01T12:00:00Z","source":"test","correlation_id":0},"payload":{"CorrelationID":42}}`))
+	h.Publish(domain.SubjectMatchMonitoring, []byte(`{"payload":{"correlation_id":42,"timestamp":"2026-01-01T12:00:05Z"}}`))
+
+	var timeline domain.JourneyResponse
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		return c.get("/journeys/42", &timeline) == http.StatusOK && len(timeline.Events) == 2
+	}, "journey 42 was not indexed")
+
+	if timeline.MonitoringUpdates != 1 || timeline.Duration != "5s" || timeline.Events[0].Source != "test" {
+		t.Errorf("timeline = %+v", timeline)
+	}
+
+	var stats domain.JourneyStats
+	if code := c.get("/journeys", &stats); code != http.StatusOK || !stats.Ready || stats.Events != 2 {
+		t.Errorf("/journeys = %d %+v", code, stats)
+	}
+	if code := c.get("/journeys/43", nil); code != http.StatusNotFound {
+		t.Errorf("unknown id = %d, want 404", code)
+	}
+	if code := c.get("/journeys/abc", nil); code != http.StatusBadRequest {
+		t.Errorf("invalid id = %d, want 400", code)
+	}
+}
+
+func TestConsumerEndpoints(t *testing.T) {
+	h, c := startService(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := h.JetStream.CreateOrUpdateConsumer(ctx, domain.StreamEvents, jetstream.ConsumerConfig{
+		Durable:   "test-consumer",
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var status domain.ConsumerStatus
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		return c.get("/consumers", &status) == http.StatusOK && len(status.Consumers) == 1
+	}, "durable consumer was not sampled")
+	if status.Consumers[0].Consumer != "test-consumer" {
+		t.Errorf("/consumers = %+v", status)
+	}
+
+	var stale domain.StaleConsumersResponse
+	if code := c.do(http.MethodGet, "/admin/consumers/stale", adminToken, "", &stale); code != http.StatusOK ||
+		stale.InactiveAfter != "1h0m0s" || len(stale.Consumers) != 0 {
+		t.Errorf("/admin/consumers/stale = %d %+v", code, stale)
+	}
+	if code := c.do(http.MethodPost, "/consumers", "", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /consumers = %d, want 405", code)
+	}
+}
+
+func TestReadyWhileDraining(t *testing.T) {
+	h := natstest.Start(t, natstest.WithConfig(func(cfg *config.Config) {
+		cfg.ShutdownDrainDelay = time.Second
+	}))
+	c := client{t: t, base: h.StartHTTP()}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- h.Server.Stop() }()
+
+	// во время паузы перед остановкой балансировщик уже видит 503, а сервер еще принимает клиентов
+	var ready domain.HealthResponse
+	natstest.Eventually(t, time.Second, func() bool {
+		return c.get("/ready", &ready) == http.StatusServiceUnavailable && ready.Status == "draining"
+	}, "/ready did not report draining: %+v", ready)
+	if !h.Server.IsRunning() {
+		t.Error("server stopped before the drain delay")
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if code := c.get("/ready", &ready); code != http.StatusServiceUnavailable || ready.Status != "not_ready" {
+		t.Errorf("/ready after stop = %d %+v", code, ready)
+	}
+}
+
+func TestForksEndpoint(t *testing.T) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.ForkDetectorEnabled = true
+		}),
+	)
+	detector := forks.NewDetector(h.Config, h.Logger, h.Server.ClientConn)
+	startComponent(t, detector.Start, detector.Stop)
+	c := client{t: t, base: h.StartHTTP(func(s *server.HTTPServer) { s.SetForkDetector(detector) })}
+
+	var resp domain.ForksResponse
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		return c.get("/forks", &resp) == http.StatusOK && resp.Ready
+	}, "fork detector is not ready")
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, bets := range []string{
+		`"fonbet":[{"bet_market":"match-winner","target_bet":"NaVi","less":2.10,"more":1.70}]`,
+		`"winline":[{"bet_market":"match-winner","target_bet":"NaVi","less":1.75,"more":2.05}]`,
+	} {
+		h.Publish(domain.SubjectMatchMonitoring, []byte(`{"payload":{"correlation_id":7,"team_names":["NaVi","G2"],`+
+			`"bets":{`+bets+`},"timestamp":"`+now+`"}}`))
+	}
+
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		return c.get("/forks", &resp) == http.StatusOK && len(resp.Forks) == 1
+	}, "fork is not listed")
+	if f := resp.Forks[0]; resp.Published != 1 || f.CorrelationID != 7 || f.LessBookmaker != "fonbet" || f.MoreBookmaker != "winline" {
+		t.Errorf("/forks = %+v", resp)
+	}
+	if code := c.do(http.MethodPost, "/forks", "", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /forks = %d, want 405", code)
+	}
+}
+
+func TestOddsFilterEndpoint(t *testing.T) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.OddsFilterEnabled = true
+			cfg.OddsTTL = time.Minute
+		}),
+	)
+	stage := oddsfilter.NewStage(h.Config, h.Logger, h.Server.ClientConn)
+	startComponent(t, stage.Start, stage.Stop)
+	c := client{t: t, base: h.StartHTTP(func(s *server.HTTPServer) { s.SetOddsFilter(stage) })}
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		_, err := h.JetStream.Consumer(context.Background(), domain.StreamEvents, h.Config.OddsFilterDurable)
+		return err == nil
+	}, "odds filter consumer was not created")
+
+	// коэффициенты из события пятиминутной давности устарели
+	old := time.Now().Add(-5 * time.Minute).UTC().Format(time.RFC3339Nano)
+	h.Publish(domain.SubjectMatchMonitoring, []byte(`{"payload":{"correlation_id":7,"team_names":["NaVi","G2"],`+
+		`"bets":{"fonbet":[{"bet_market":"match-winner","target_bet":"NaVi","less":2.1,"more":1.7}]},"timestamp":"`+old+`"}}`))
+
+	var resp domain.OddsFilterResponse
+	natstest.Eventually(t, 5*time.Second, func() bool {
+		return c.get("/odds-filter", &resp) == http.StatusOK && resp.Published == 1
+	}, "filtered event was not published")
+	if fonbet := resp.Bookmakers["fonbet"]; resp.Mode != h.Config.OddsFilterMode || fonbet.Checked != 1 || fonbet.Stale != 1 {
+		t.Errorf("/odds-filter = %+v", resp)
+	}
+}
+
+func TestAliasEndpoints(t *testing.T) {
+	h := natstest.Start(t,
+		natstest.WithStreams(natstest.EventsStream()),
+		natstest.WithConfig(func(cfg *config.Config) {
+			cfg.AdminToken = domain.Secret(adminToken)
+			cfg.NormalizeEnabled = true
+		}),
+	)
+	normalizer := normalize.NewNormalizer(h.Config, h.Logger, h.Server.ClientConn)
+	startComponent(t, normalizer.Start, normalizer.Stop)
+	c := client{t: t, base: h.StartHTTP(func(s *server.HTTPServer) { s.SetNormalizer(normalizer) })}
+
+	tests := []struct {
+		name, method, path, token, body string
+		want                            int
+	}{
+		{"put", http.MethodPut, "/admin/aliases", adminToken, `{"kind":"team","canonical":"Natus Vincere","aliases":["NaVi"]}`, http.StatusOK},
+		{"put conflict", http.MethodPut, "/admin/aliases", adminToken, `{"kind":"team","canonical":"NAVI Junior","aliases":["navi"]}`, http.StatusConflict},
+		{"put invalid kind", http.MethodPut, "/admin/aliases", adminToken, `{"kind":"league","canonical":"ESL","aliases":["esl"]}`, http.StatusBadRequest},
+		{"put invalid body", http.MethodPut, "/admin/aliases", adminToken, `{`, http.StatusBadRequest},
+		{"put without token", http.MethodPut, "/admin/aliases", "", `{"kind":"team","canonical":"G2","aliases":["g2"]}`, http.StatusUnauthorized},
+		{"list", http.MethodGet, "/admin/aliases", adminToken, "", http.StatusOK},
+		{"list with wrong token", http.MethodGet, "/admin/aliases", "wrong", "", http.StatusUnauthorized},
+		{"resolve", http.MethodGet, "/admin/aliases/resolve?kind=team&name=navi", adminToken, "", http.StatusOK},
+		{"resolve invalid kind", http.MethodGet, "/admin/aliases/resolve?kind=league&name=navi", adminToken, "", http.StatusBadRequest},
+		{"resolve without name", http.MethodGet, "/admin/aliases/resolve?kind=team", adminToken, "", http.StatusBadRequest},
+		{"resolve without token", http.MethodGet, "/admin/aliases/resolve?kind=team&name=navi", "", "", http.StatusUnauthorized},
+		{"delete without token", http.MethodDelete, "/admin/aliases?kind=team&canonical=Natus%20Vincere", "", "", http.StatusUnauthorized},
+		{"delete without canonical", http.MethodDelete, "/admin/aliases?kind=team", adminToken, "", http.StatusBadRequest},
+		{"delete", http.MethodDelete, "/admin/aliases?kind=team&canonical=Natus%20Vincere", adminToken, "", http.StatusNoContent},
+		{"delete not found", http.MethodDelete, "/admin/aliases?kind=team&canonical=Natus%20Vincere", adminToken, "", http.StatusNotFound},
+		{"post", http.MethodPost, "/admin/aliases", adminToken, "", http.StatusMethodNotAllowed},
+	}
+	// случаи выполняются по порядку: delete удаляет запись, созданную put
+	for _, tt := range tests {
+		if code := c.do(tt.method, tt.path, tt.token, tt.body, nil); code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d", tt.name, tt.method, tt.path, code, tt.want)
+		}
+	}
+
+	var match domain.NameMatch
+	if code := c.do(http.MethodGet, "/admin/aliases/resolve?kind=team&name=navi", adminToken, "", &match); code != http.StatusOK || match.Canonical != "" {
+		t.Errorf("resolve after delete = %d %+v", code, match)
+	}
+	if _, err := normalizer.PutAlias(context.Background(), domain.AliasEntry{
+		Kind: domain.AliasKindTeam, Canonical: "Natus Vincere", Aliases: []string{"NaVi"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if code := c.do(http.MethodGet, "/admin/aliases/resolve?kind=team&name=navi", adminToken, "", &match); code != http.StatusOK ||
+		match.Canonical != "Natus Vincere" || match.Confidence != 1 {
+		t.Errorf("resolve = %d %+v", code, match)
+	}
+	var aliases domain.AliasesResponse
+	if code := c.do(http.MethodGet, "/admin/aliases", adminToken, "", &aliases); code != http.StatusOK ||
+		len(aliases.Entries) != 1 || aliases.Bucket != h.Config.NormalizeBucket {
+		t.Errorf("/admin/aliases = %d %+v", code, aliases)
+	}
+}
+
+func TestEndpointsWithoutNATS(t *testing.T) {
+	port := natstest.FreePort(t)
+	srv := server.NewHTTPServer(port, natstest.NewLogger(t, "error"), nil, &config.Config{})
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Stop() })
+	c := client{t: t, base: fmt.Sprintf("http://127.0.0.1:%d", port)}
+
+	var metrics map[string]interface{}
+	if code := c.get("/metrics", &metrics); code != http.StatusOK || metrics["nats_log_dropped"] != nil {
+		t.Errorf("/metrics = %d %v", code, metrics)
+	}
+	if code := c.get("/ready", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("/ready = %d, want 503", code)
+	}
+}
+
+func TestDisabledEndpoints(t *testing.T) {
+	h := natstest.Start(t)
+	c := client{t: t, base: h.StartHTTP()}
+
+	for _, path := range []string{"/journeys/42", "/consumers", "/forks", "/odds-filter"} {
+		if code := c.get(path, nil); code != http.StatusServiceUnavailable {
+			t.Errorf("%s = %d, want 503", path, code)
+		}
+	}
+	if code := c.do(http.MethodGet, "/admin/log-level", "any", "", nil); code != http.StatusNotFound {
+		t.Errorf("admin without ADMIN_TOKEN = %d, want 404", code)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/journey"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SetJourneyIndex подключает индекс correlation id к /journeys
+func (s *HTTPServer) SetJourneyIndex(index *journey.Index) {
+	s.journeys = index
+}
+
+// journeysHandler - GET /journeys (состояние индекса) и GET /journeys/{correlation_id} (история)
+func (s *HTTPServer) journeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if s.journeys == nil {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "journey index is disabled"})
+		return
+	}
+
+	raw := strings.Trim(strings.TrimPrefix(r.URL.Path, "/journeys"), "/")
+	if raw == "" {
+		s.sendJSONResponse(w, http.StatusOK, s.journeys.Stats())
+		return
+	}
+
+	correlationID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		s.sendJSONResponse(w, http.StatusBadRequest, domain.ErrorResponse{Error: "correlation id must be an integer"})
+		return
+	}
+
+	timeline, ok := s.journeys.Timeline(correlationID)
+	if !ok {
+		s.sendJSONResponse(w, http.StatusNotFound, domain.ErrorResponse{Error: "correlation id not found"})
+		return
+	}
+	s.sendJSONResponse(w, http.StatusOK, timeline)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"NATS_TIRE_SERVICE/internal/oddsfilter"
+	"net/http"
+)
+
+// SetOddsFilter подключает фильтр коэффициентов к /odds-filter
+func (s *HTTPServer) SetOddsFilter(stage *oddsfilter.Stage) {
+	s.oddsFilter = stage
+}
+
+// oddsFilterHandler - GET /odds-filter: счетчики фильтра и отсеянные коэффициенты по букмекерам
+func (s *HTTPServer) oddsFilterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.sendJSONResponse(w, http.StatusMethodNotAllowed, domain.ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	if s.oddsFilter == nil {
+		s.sendJSONResponse(w, http.StatusServiceUnavailable, domain.ErrorResponse{Error: "odds filter is disabled"})
+		return
+	}
+
+	s.sendJSONResponse(w, http.StatusOK, s.oddsFilter.Stats())
+}
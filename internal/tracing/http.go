@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"strings"
+)
+
+// Middleware открывает server спан на каждый HTTP запрос, продолжая трассу из заголовка traceparent.
+// Имя спана - метод и шаблон маршрута ServeMux (например "GET /journeys/"), а не путь с идентификаторами:
+// шаблон известен только после маршрутизации, поэтому имя задается после обработки.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", r.RemoteAddr),
+			),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		req := r.WithContext(ctx)
+		next.ServeHTTP(rec, req)
+
+		// ServeMux записывает шаблон в запрос, который получил; шаблон может уже начинаться с метода
+		if pattern := req.Pattern; pattern != "" {
+			name := pattern
+			if !strings.Contains(pattern, " ") {
+				name = fmt.Sprintf("%s %s", r.Method, pattern)
+			}
+			span.SetName(name)
+			span.SetAttributes(attribute.String("http.route", pattern))
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
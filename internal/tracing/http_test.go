@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareSpanName(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/journeys/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /forks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := Middleware(mux)
+
+	for _, path := range []string{"/journeys/101", "/journeys/202", "/forks/abc", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := []struct{ name, path string }{
+		{"GET /journeys/", "/journeys/101"},
+		{"GET /journeys/", "/journeys/202"},
+		{"GET /forks/{id}", "/forks/abc"},
+		{"GET", "/missing"},
+	}
+	spans := recorder.Ended()
+	if len(spans) != len(want) {
+		t.Fatalf("expected %d spans, got %d", len(want), len(spans))
+	}
+	for i, span := range spans {
+		var path string
+		for _, attr := range span.Attributes() {
+			if attr.Key == "url.path" {
+				path = attr.Value.AsString()
+			}
+		}
+		if span.Name() != want[i].name || path != want[i].path {
+			t.Errorf("span %d = %q with url.path %q, want %q with %q", i, span.Name(), path, want[i].name, want[i].path)
+		}
+	}
+}
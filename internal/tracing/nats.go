@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"context"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier - propagation.TextMapCarrier поверх заголовков NATS сообщения
+type headerCarrier nats.Header
+
+func (c headerCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Inject записывает trace context (traceparent/tracestate) в заголовки сообщения
+func Inject(ctx context.Context, msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(msg.Header))
+}
+
+// Extract восстанавливает trace context из заголовков сообщения
+func Extract(ctx context.Context, header nats.Header) context.Context {
+	if header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier(header))
+}
+
+// StartPublish открывает producer спан для публикации и передает его контекст в заголовках сообщения.
+// Если сообщение уже несет traceparent (например при восстановлении из резервной копии), спан становится его потомком.
+func StartPublish(ctx context.Context, msg *nats.Msg) (context.Context, trace.Span) {
+	ctx = Extract(ctx, msg.Header)
+	ctx, span := Tracer().Start(ctx, msg.Subject+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttributes(msg.Subject, "publish", msg.Data)...),
+	)
+	Inject(ctx, msg)
+	return ctx, span
+}
+
+// StartConsume открывает consumer спан для обработки сообщения, продолжая трассу издателя
+func StartConsume(ctx context.Context, subject string, header nats.Header, data []byte) (context.Context, trace.Span) {
+	ctx = Extract(ctx, header)
+	return Tracer().Start(ctx, subject+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(messagingAttributes(subject, "process", data)...),
+	)
+}
+
+// End завершает спан, отмечая ошибку, если она есть
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func messagingAttributes(subject, operation string, data []byte) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "nats"),
+		attribute.String("messaging.destination.name", subject),
+		attribute.String("messaging.operation.type", operation),
+		attribute.Int("messaging.message.body.size", len(data)),
+	}
+
+	if env, err := domain.ParseEnvelope(data); err == nil {
+		if id, ok := env.CorrelationID(); ok {
+			attrs = append(attrs, AttrCorrelationID.Int64(id))
+		}
+		if env.Header != nil && env.Header.EventType != "" {
+			attrs = append(attrs,
+				attribute.String("event.type", env.Header.EventType),
+				attribute.String("event.id", env.Header.EventID),
+			)
+		}
+	}
+	return attrs
+}
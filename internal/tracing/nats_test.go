@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestPublishConsumePropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	msg := &nats.Msg{
+		Subject: "events.bundle.match",
+		Data:    []byte(`{"event_header":{"event_type":"match.bundle","correlation_id":0},"payload":{"CorrelationID":42}}`),
+	}
+
+	_, pub := StartPublish(context.Background(), msg)
+	pub.End()
+	if msg.Header.Get("traceparent") == "" {
+		t.Fatal("traceparent header was not injected")
+	}
+
+	_, sub := StartConsume(context.Background(), msg.Subject, msg.Header, msg.Data)
+	sub.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != spans[0].SpanContext().SpanID() {
+		t.Fatal("consumer span is not a child of the publish span")
+	}
+
+	var correlation int64
+	for _, attr := range spans[1].Attributes() {
+		if attr.Key == AttrCorrelationID {
+			correlation = attr.Value.AsInt64()
+		}
+	}
+	if correlation != 42 {
+		t.Fatalf("correlation_id = %d, want 42", correlation)
+	}
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"NATS_TIRE_SERVICE/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"path/filepath"
+)
+
+const instrumentationName = "NATS_TIRE_SERVICE"
+
+// AttrCorrelationID - атрибут спана с correlation id события
+const AttrCorrelationID = attribute.Key("correlation_id")
+
+// Setup настраивает глобальный TracerProvider и W3C propagator. При выключенной трассировке
+// спаны не пишутся, но контекст все равно передается дальше в заголовках.
+// Возвращает функцию, которая сбрасывает буфер спанов и закрывает экспортер.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeExporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", cfg.AppName),
+		attribute.String("service.version", cfg.Version),
+		attribute.String("deployment.environment", cfg.Env),
+	)
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeExporter())
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, func() error, error) {
+	noop := func() error { return nil }
+
+	switch cfg.TracingExporter {
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, noop, nil
+
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(cfg.TracingFile), 0755); err != nil {
+			return nil, nil, err
+		}
+		file, err := os.OpenFile(cfg.TracingFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open tracing file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, file.Close, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+}
+
+// Tracer возвращает трейсер сервиса из глобального TracerProvider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
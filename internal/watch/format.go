@@ -0,0 +1,195 @@
+// Package watch выводит события стрима для отладки потребителей
+package watch
+
+import (
+	"NATS_TIRE_SERVICE/internal/domain"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxRaw - сколько байт некорректного сообщения показывать
+const maxRaw = 300
+
+// Event - полученное сообщение. Seq - номер в стриме, 0 для обычной подписки.
+type Event struct {
+	Subject  string
+	Seq      uint64
+	Received time.Time
+	Data     []byte
+}
+
+// decoded - результат разбора: ошибка не мешает вывести то, что удалось прочитать
+type decoded struct {
+	eventType     string
+	correlationID int64
+	payload       interface{}
+	err           error
+}
+
+func decode(ev Event) decoded {
+	var d decoded
+	env, err := domain.ParseEnvelope(ev.Data)
+	if err != nil {
+		d.eventType = eventType(ev.Subject, domain.EventEnvelope{})
+		d.err = fmt.Errorf("not an event envelope: %w", err)
+		return d
+	}
+	d.correlationID, _ = env.CorrelationID()
+
+	d.eventType = eventType(ev.Subject, env)
+	if len(env.Payload) == 0 {
+		d.err = fmt.Errorf("empty payload")
+		return d
+	}
+
+	switch d.eventType {
+	case domain.EventTypeMatchBundle:
+		var p domain.MatchBundle
+		d.err = json.Unmarshal(env.Payload, &p)
+		d.payload = p
+	case domain.EventTypeMatchMonitoring:
+		var p domain.MatchMonitoring
+		d.err = json.Unmarshal(env.Payload, &p)
+		d.payload = p
+	case domain.EventTypeForkFound:
+		var p domain.ForkFound
+		d.err = json.Unmarshal(env.Payload, &p)
+		d.payload = p
+	}
+	if d.err != nil {
+		d.err = fmt.Errorf("invalid %s payload: %w", d.eventType, d.err)
+		d.payload = nil
+	}
+	return d
+}
+
+// eventType берется из заголовка, а у событий без заголовка (старый мониторинг) - по subject
+func eventType(subject string, env domain.EventEnvelope) string {
+	if env.Header != nil && env.Header.EventType != "" {
+		return env.Header.EventType
+	}
+	for _, t := range []string{domain.EventTypeMatchBundle, domain.EventTypeMatchMonitoring, domain.EventTypeForkFound} {
+		if s, _ := domain.SubjectByEventType(t); s == subject {
+			return t
+		}
+	}
+	return ""
+}
+
+// Printer выводит событие и сообщает, было ли оно некорректным
+type Printer func(w io.Writer, ev Event) (malformed bool, err error)
+
+// Pretty печатает событие в читаемом виде, некорректные сообщения - с ошибкой и началом тела
+func Pretty(w io.Writer, ev Event) (bool, error) {
+	d := decode(ev)
+
+	line := fmt.Sprintf("%s %s", ev.Received.Format("15:04:05.000"), ev.Subject)
+	if ev.Seq > 0 {
+		line += fmt.Sprintf(" #%d", ev.Seq)
+	}
+	if d.correlationID != 0 {
+		line += fmt.Sprintf(" correlation_id=%d", d.correlationID)
+	}
+
+	var body []string
+	switch p := d.payload.(type) {
+	case domain.MatchBundle:
+		line += " " + teams(p.TeamNames)
+		for _, bm := range sortedKeys(p.BookmakerBundle) {
+			body = append(body, fmt.Sprintf("%-12s %s", bm, p.BookmakerBundle[bm]))
+		}
+	case domain.MatchMonitoring:
+		line += fmt.Sprintf(" %s [%s]", teams(p.TeamNames), p.SportType)
+		for _, bm := range sortedKeys(p.Bets) {
+			for _, bet := range p.Bets[bm] {
+				body = append(body, fmt.Sprintf("%-12s %-16s %-12s less=%.2f more=%.2f", bm, bet.BetMarket, bet.TargetBet, bet.Less, bet.More))
+			}
+		}
+	case domain.ForkFound:
+		line += fmt.Sprintf(" %s %s profit=%.2f%%", teams(p.TeamNames), p.BetMarket, p.Profit)
+		for _, leg := range p.Legs {
+			body = append(body, fmt.Sprintf("%-12s %-12s odds=%.2f stake=%.3f", leg.Bookmaker, leg.TargetBet, leg.Odds, leg.Stake))
+		}
+	default:
+		if d.err == nil && d.eventType != "" {
+			line += " " + d.eventType
+		}
+	}
+
+	if d.err != nil {
+		body = append(body, "malformed: "+d.err.Error(), "raw: "+raw(ev.Data))
+	}
+
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return false, err
+	}
+	for _, b := range body {
+		if _, err := fmt.Fprintln(w, "    "+b); err != nil {
+			return false, err
+		}
+	}
+	return d.err != nil, nil
+}
+
+// jsonLine - строка вывода -json: событие как есть, если это JSON, иначе строкой
+type jsonLine struct {
+	Received      time.Time       `json:"received"`
+	Subject       string          `json:"subject"`
+	Seq           uint64          `json:"seq,omitempty"`
+	EventType     string          `json:"event_type,omitempty"`
+	CorrelationID int64           `json:"correlation_id,omitempty"`
+	Event         json.RawMessage `json:"event,omitempty"`
+	Raw           string          `json:"raw,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+func JSONLine(w io.Writer, ev Event) (bool, error) {
+	d := decode(ev)
+	line := jsonLine{
+		Received:      ev.Received.UTC(),
+		Subject:       ev.Subject,
+		Seq:           ev.Seq,
+		EventType:     d.eventType,
+		CorrelationID: d.correlationID,
+	}
+	if json.Valid(ev.Data) {
+		line.Event = ev.Data
+	} else {
+		line.Raw = string(ev.Data)
+	}
+	if d.err != nil {
+		line.Error = d.err.Error()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return d.err != nil, enc.Encode(line)
+}
+
+// teams не падает на неполных данных: старый подписчик брал TeamNames[1] без проверки
+func teams(names []string) string {
+	if len(names) == 0 {
+		return "<no teams>"
+	}
+	return strings.Join(names, " vs ")
+}
+
+func raw(data []byte) string {
+	if len(data) > maxRaw {
+		return fmt.Sprintf("%q... (%d bytes)", data[:maxRaw], len(data))
+	}
+	return fmt.Sprintf("%q", data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
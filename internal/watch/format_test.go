@@ -0,0 +1,51 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrettyToleratesBadPayloads(t *testing.T) {
+	cases := []struct {
+		name      string
+		subject   string
+		data      string
+		malformed bool
+		want      string
+	}{
+		{"one team", "events.match.monitoring", `{"payload":{"correlation_id":5,"team_names":["NaVi"],"bets":{}}}`, false, "correlation_id=5 NaVi"},
+		{"no teams", "events.fork.found", `{"event_header":{"event_type":"fork.found"},"payload":{"CorrelationID":7}}`, false, "<no teams>"},
+		{"not json", "events.fork.found", `not json`, true, `raw: "not json"`},
+		{"wrong type", "events.bundle.match", `{"payload":{"TeamNames":"NaVi"}}`, true, "invalid match.bundle payload"},
+		{"no payload", "events.match.monitoring", `{}`, true, "empty payload"},
+	}
+
+	for _, tc := range cases {
+		var out bytes.Buffer
+		malformed, err := Pretty(&out, Event{Subject: tc.subject, Received: time.Now(), Data: []byte(tc.data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if malformed != tc.malformed || !strings.Contains(out.String(), tc.want) {
+			t.Errorf("%s: malformed=%v output:\n%s", tc.name, malformed, out.String())
+		}
+	}
+}
+
+func TestJSONLine(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := JSONLine(&out, Event{Subject: "events.fork.found", Seq: 3, Received: time.Now(), Data: []byte("\x00bad")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var line jsonLine
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", out.String(), err)
+	}
+	if line.Raw != "\x00bad" || len(line.Event) != 0 || line.Error == "" || line.Seq != 3 || line.EventType != "fork.found" {
+		t.Errorf("line = %+v", line)
+	}
+}
@@ -0,0 +1,66 @@
+package watch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type subjectStats struct {
+	count     int
+	malformed int
+	bytes     int
+	first     time.Time
+	last      time.Time
+}
+
+// Stats считает сообщения по subject для итога при выходе
+type Stats struct {
+	started  time.Time
+	subjects map[string]*subjectStats
+}
+
+func NewStats(started time.Time) *Stats {
+	return &Stats{started: started, subjects: make(map[string]*subjectStats)}
+}
+
+func (s *Stats) Add(ev Event, malformed bool) {
+	st, ok := s.subjects[ev.Subject]
+	if !ok {
+		st = &subjectStats{first: ev.Received}
+		s.subjects[ev.Subject] = st
+	}
+	st.count++
+	st.bytes += len(ev.Data)
+	st.last = ev.Received
+	if malformed {
+		st.malformed++
+	}
+}
+
+// Write печатает таблицу: rate - сообщений в секунду за все время подписки
+func (s *Stats) Write(w io.Writer, now time.Time) {
+	elapsed := now.Sub(s.started)
+	subjects := make([]string, 0, len(s.subjects))
+	total := 0
+	for subject, st := range s.subjects {
+		subjects = append(subjects, subject)
+		total += st.count
+	}
+	sort.Strings(subjects)
+
+	fmt.Fprintf(w, "\n%d messages in %s\n", total, elapsed.Round(time.Millisecond))
+	if total == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%-28s %8s %10s %10s %10s\n", "SUBJECT", "COUNT", "MALFORMED", "RATE/S", "BYTES")
+	for _, subject := range subjects {
+		st := s.subjects[subject]
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(st.count) / elapsed.Seconds()
+		}
+		fmt.Fprintf(w, "%-28s %8d %10d %10.2f %10d\n", subject, st.count, st.malformed, rate, st.bytes)
+	}
+}
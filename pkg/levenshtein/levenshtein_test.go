@@ -0,0 +1,24 @@
+package levenshtein
+
+import (
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"LOG_FILE", "LOG_FILE", 0},
+		{"LOG_LIFE", "LOG_FILE", 2},
+		{"", "navi", 4},
+		{"Победитель", "победитель", 1},
+		{"нави", "navi", 4},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
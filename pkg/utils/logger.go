@@ -6,29 +6,25 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
-	"path/filepath"
 	"time"
 )
 
+// Logger - zap.Logger с изменяемым уровнем. Структурные вызовы (Info, Error, ...) идут напрямую в zap,
+// форматированные (Infof, Errorf, ...) принимают printf аргументы и zap поля в одном списке.
 type Logger struct {
 	*zap.Logger
+	level  zap.AtomicLevel
+	file   *RotatingFile
+	helper *zap.Logger
+	notice *zap.Logger
+
+	// base - ядро без фильтра по уровню, из него строятся логгеры с собственным уровнем
+	base    zapcore.Core
+	options []zap.Option
 }
 
 func NewLogger(cfg *config.Config) (*Logger, error) {
-	var level zapcore.Level
-
-	switch cfg.LogLevel {
-	case "debug":
-		level = zapcore.DebugLevel
-	case "info":
-		level = zapcore.InfoLevel
-	case "warn":
-		level = zapcore.WarnLevel
-	case "error":
-		level = zapcore.ErrorLevel
-	default:
-		level = zapcore.InfoLevel
-	}
+	level := zap.NewAtomicLevelAt(parseLevel(cfg.LogLevel))
 
 	// Создаем базовую конфигурацию энкодера
 	encoderConfig := zap.NewProductionEncoderConfig()
@@ -44,6 +40,7 @@ func NewLogger(cfg *config.Config) (*Logger, error) {
 	}
 
 	var cores []zapcore.Core
+	var file *RotatingFile
 
 	stdoutSyncer := zapcore.Lock(os.Stdout)
 	var consoleEncoder zapcore.Encoder
@@ -55,52 +52,147 @@ func NewLogger(cfg *config.Config) (*Logger, error) {
 		consoleEncoder = zapcore.NewConsoleEncoder(encoderConfig)
 	}
 
-	cores = append(cores, zapcore.NewCore(consoleEncoder, stdoutSyncer, level))
+	// ядра пишут все уровни, фильтрует обертка с AtomicLevel - так можно завести логгер со своим уровнем
+	cores = append(cores, zapcore.NewCore(consoleEncoder, stdoutSyncer, zapcore.DebugLevel))
 
 	if cfg.LogFile != "" {
-		fileSyncer, err := getLogFileSyncer(cfg.LogFile)
+		var err error
+		file, err = NewRotatingFile(cfg.LogFile, RotationOptions{
+			MaxSize:    cfg.LogMaxSize.Bytes(),
+			Interval:   cfg.LogRotateInterval,
+			MaxBackups: cfg.LogMaxBackups,
+			MaxAge:     cfg.LogMaxAge,
+			Compress:   cfg.LogCompress,
+		})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to open log file: %w", err)
 		}
 		fileEncoder := zapcore.NewJSONEncoder(encoderConfig)
-		cores = append(cores, zapcore.NewCore(fileEncoder, fileSyncer, level))
+		cores = append(cores, zapcore.NewCore(fileEncoder, file, zapcore.DebugLevel))
 	}
 
-	core := zapcore.NewTee(cores...)
+	base := zapcore.NewTee(cores...).With([]zap.Field{
+		zap.String("service", cfg.AppName),
+		zap.String("version", cfg.Version),
+		zap.String("env", cfg.Env),
+	})
 
-	logger := zap.New(core,
+	options := []zap.Option{
 		zap.AddCaller(),
-		zap.AddCallerSkip(1),
-		zap.AddStacktrace(zap.ErrorLevel))
+		zap.AddStacktrace(zap.ErrorLevel),
+	}
 
-	logger = logger.With(
-		zap.String("service", cfg.AppName),
-		zap.String("version", cfg.Version),
-		zap.String("env", cfg.Env))
+	return newLogger(base, level, file, options), nil
+}
 
-	return &Logger{logger}, nil
+// NewLoggerWithCore строит Logger поверх готового ядра (например для вывода через testing.T).
+// Ядро должно пропускать все уровни: фильтрует level.
+func NewLoggerWithCore(core zapcore.Core, level zap.AtomicLevel) *Logger {
+	return newLogger(core, level, nil, []zap.Option{zap.AddCaller()})
 }
 
-func getLogFileSyncer(logFile string) (zapcore.WriteSyncer, error) {
-	dir := filepath.Dir(logFile)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, err
+func newLogger(base zapcore.Core, level zap.AtomicLevel, file *RotatingFile, options []zap.Option) *Logger {
+	core, err := zapcore.NewIncreaseLevelCore(base, level)
+	if err != nil {
+		// base пишет все уровни, поэтому сюда попасть нельзя
+		panic(err)
 	}
 
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return nil, err
+	logger := zap.New(core, options...)
+	return &Logger{
+		Logger: logger,
+		level:  level,
+		file:   file,
+		// пропускаем logf и Infof/Errorf/..., чтобы caller указывал на место вызова
+		helper:  logger.WithOptions(zap.AddCallerSkip(2)),
+		notice:  zap.New(base, options...).WithOptions(zap.AddCallerSkip(1)),
+		base:    base,
+		options: options,
+	}
+}
+
+// WithLevel возвращает логгер с теми же выводами, но собственным уровнем,
+// который не зависит от LOG_LEVEL (например для логов встроенного сервера)
+func (l *Logger) WithLevel(level zap.AtomicLevel) *Logger {
+	return newLogger(l.base, level, l.file, l.options)
+}
+
+func parseLevel(level string) zapcore.Level {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel
+	case "info":
+		return zapcore.InfoLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// Level возвращает текущий уровень логирования
+func (l *Logger) Level() string {
+	return l.level.Level().String()
+}
+
+// SetLevel меняет уровень логирования во время работы
+func (l *Logger) SetLevel(level string) error {
+	switch level {
+	case "debug", "info", "warn", "error":
+		l.level.SetLevel(parseLevel(level))
+		return nil
+	}
+	return fmt.Errorf("invalid log level %q", level)
+}
+
+// StepLevel делает логирование подробнее (delta < 0) или тише (delta > 0) в пределах debug..error
+func (l *Logger) StepLevel(delta int) string {
+	next := l.level.Level() + zapcore.Level(delta)
+	if next < zapcore.DebugLevel {
+		next = zapcore.DebugLevel
 	}
+	if next > zapcore.ErrorLevel {
+		next = zapcore.ErrorLevel
+	}
+	l.level.SetLevel(next)
+	return next.String()
+}
 
-	return zapcore.AddSync(file), nil
+// ReopenFile заново открывает файл логов (после внешнего logrotate); без LOG_FILE ничего не делает
+func (l *Logger) ReopenFile() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Reopen()
+}
+
+// RotateFile принудительно ротирует файл логов
+func (l *Logger) RotateFile() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Rotate()
 }
 
 func (l *Logger) Sync() error {
 	return l.Logger.Sync()
 }
 
+// Close сбрасывает буферы и закрывает файл логов вместе с его фоновой очисткой
+func (l *Logger) Close() error {
+	err := l.Logger.Sync()
+	if l.file != nil {
+		if closeErr := l.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
 func (l *Logger) WithFields(fields ...zap.Field) *Logger {
-	return &Logger{l.Logger.With(fields...)}
+	return newLogger(l.base.With(fields), l.level, l.file, l.options)
 }
 
 func (l *Logger) LogDuration(start time.Time, operation string) {
@@ -110,27 +202,58 @@ func (l *Logger) LogDuration(start time.Time, operation string) {
 		zap.Duration("duration", duration))
 }
 
+// Notice пишет сообщение уровня info при любом уровне логирования, например о смене самого уровня
+func (l *Logger) Notice(msg string, fields ...zap.Field) {
+	l.notice.Info(msg, fields...)
+}
+
 // Debugf логирует отладочное сообщение с форматированием
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	l.Debug("", zap.String("message", fmt.Sprintf(format, args...)))
+	l.logf(zapcore.DebugLevel, format, args)
 }
 
 // Infof логирует информационное сообщение с форматированием
 func (l *Logger) Infof(format string, args ...interface{}) {
-	l.Info("", zap.String("message", fmt.Sprintf(format, args...)))
+	l.logf(zapcore.InfoLevel, format, args)
 }
 
 // Warnf логирует предупреждение с форматированием
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	l.Warn("", zap.String("message", fmt.Sprintf(format, args...)))
+	l.logf(zapcore.WarnLevel, format, args)
 }
 
 // Errorf логирует ошибку с форматированием
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	l.Error("", zap.String("message", fmt.Sprintf(format, args...)))
+	l.logf(zapcore.ErrorLevel, format, args)
 }
 
-// Fatalf логирует фатальную ошибку с форматированием
+// Fatalf логирует фатальную ошибку с форматированием и завершает процесс
 func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.Fatal("", zap.String("message", fmt.Sprintf(format, args...)))
+	l.logf(zapcore.FatalLevel, format, args)
+}
+
+// logf подставляет обычные аргументы в format, а zap поля прикрепляет к записи.
+// Поля должны идти после аргументов формата - это проверяет TestFormattedCallSites.
+func (l *Logger) logf(level zapcore.Level, format string, args []interface{}) {
+	ce := l.helper.Check(level, "")
+	if ce == nil {
+		return
+	}
+
+	values, fields := splitFields(args)
+	ce.Message = fmt.Sprintf(format, values...)
+	ce.Write(fields...)
+}
+
+func splitFields(args []interface{}) ([]interface{}, []zap.Field) {
+	var values []interface{}
+	var fields []zap.Field
+	for _, arg := range args {
+		if f, ok := arg.(zap.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		values = append(values, arg)
+	}
+	return values, fields
 }
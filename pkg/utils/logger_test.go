@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newObservedLogger(level zapcore.Level) (*Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return NewLoggerWithCore(core, zap.NewAtomicLevelAt(level)), logs
+}
+
+func TestFormattedHelpers(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.DebugLevel)
+
+	logger.Infof("http server starting on port %d", 8080, zap.String("component", "http"))
+	logger.Warnf("100%% done")
+	logger.Debugf("NATS server configuration", zap.Int("port", 4222), zap.Bool("jetstream", true))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		message string
+		level   zapcore.Level
+		fields  map[string]interface{}
+	}{
+		{"http server starting on port 8080", zapcore.InfoLevel, map[string]interface{}{"component": "http"}},
+		{"100% done", zapcore.WarnLevel, map[string]interface{}{}},
+		{"NATS server configuration", zapcore.DebugLevel, map[string]interface{}{"port": int64(4222), "jetstream": true}},
+	}
+
+	for i, tt := range tests {
+		e := entries[i]
+		if e.Message != tt.message {
+			t.Errorf("entry %d: message = %q, want %q", i, e.Message, tt.message)
+		}
+		if e.Level != tt.level {
+			t.Errorf("entry %d: level = %v, want %v", i, e.Level, tt.level)
+		}
+		got := e.ContextMap()
+		if len(got) != len(tt.fields) {
+			t.Errorf("entry %d: fields = %v, want %v", i, got, tt.fields)
+		}
+		for k, v := range tt.fields {
+			if got[k] != v {
+				t.Errorf("entry %d: field %s = %v, want %v", i, k, got[k], v)
+			}
+		}
+		if !strings.HasSuffix(e.Caller.File, "logger_test.go") {
+			t.Errorf("entry %d: caller = %s, want logger_test.go", i, e.Caller.File)
+		}
+	}
+}
+
+func TestFormattedHelpersRespectLevel(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.InfoLevel)
+
+	logger.Debugf("hidden %d", 1)
+	if logs.Len() != 0 {
+		t.Fatalf("debug entry written at info level")
+	}
+
+	if err := logger.SetLevel("debug"); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Level() != "debug" {
+		t.Fatalf("level = %s, want debug", logger.Level())
+	}
+}
+
+func TestWithLevelIsIndependent(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.WarnLevel)
+	server := logger.WithLevel(zap.NewAtomicLevelAt(zapcore.DebugLevel))
+
+	logger.Info("app info")
+	server.Debug("server debug")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 || entries[0].Message != "server debug" {
+		t.Fatalf("entries = %v, want only server debug", entries)
+	}
+
+	if err := logger.SetLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+	server.Debug("still written")
+	if logs.Len() != 2 {
+		t.Fatalf("server logger followed application level change")
+	}
+}
+
+func TestNoticeIgnoresLevel(t *testing.T) {
+	logger, logs := newObservedLogger(zapcore.ErrorLevel)
+	logger.With(zap.String("component", "signals")).Info("hidden")
+	logger.WithFields(zap.String("component", "signals")).Notice("log level changed", zap.String("level", "error"))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 || entries[0].Message != "log level changed" || entries[0].Level != zapcore.InfoLevel {
+		t.Fatalf("entries = %v", entries)
+	}
+	if fields := entries[0].ContextMap(); fields["component"] != "signals" || fields["level"] != "error" {
+		t.Errorf("fields = %v", fields)
+	}
+	if !strings.HasSuffix(entries[0].Caller.File, "logger_test.go") {
+		t.Errorf("caller = %s", entries[0].Caller)
+	}
+}
+
+var formattedHelpers = map[string]bool{
+	"Debugf": true,
+	"Infof":  true,
+	"Warnf":  true,
+	"Errorf": true,
+	"Fatalf": true,
+}
+
+// TestFormattedCallSites - проверка в духе go vet: в вызовах logger.*f число аргументов
+// совпадает с числом директив формата, а zap поля идут только после них
+func TestFormattedCallSites(t *testing.T) {
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "data" || name == "logs") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if problem := checkFormattedCall(call); problem != "" {
+				t.Errorf("%s: %s", fset.Position(call.Pos()), problem)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkFormattedCall(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !formattedHelpers[sel.Sel.Name] || !isLoggerExpr(sel.X) || len(call.Args) == 0 {
+		return ""
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	format, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+
+	values, fieldSeen := 0, false
+	for _, arg := range call.Args[1:] {
+		if isZapField(arg) {
+			fieldSeen = true
+			continue
+		}
+		if fieldSeen {
+			return sel.Sel.Name + " call has format arguments after zap fields"
+		}
+		values++
+	}
+
+	if verbs := countVerbs(format); verbs != values {
+		return sel.Sel.Name + " call has " + strconv.Itoa(verbs) + " formatting directives but " +
+			strconv.Itoa(values) + " arguments"
+	}
+	return ""
+}
+
+// isLoggerExpr распознает logger, s.logger, n.logger и т.п.
+func isLoggerExpr(expr ast.Expr) bool {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return strings.EqualFold(x.Name, "logger")
+	case *ast.SelectorExpr:
+		return strings.EqualFold(x.Sel.Name, "logger")
+	}
+	return false
+}
+
+func isZapField(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "zap"
+}
+
+func countVerbs(format string) int {
+	count := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) && format[i] != '%' {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCheckFormattedCall(t *testing.T) {
+	tests := []struct {
+		src  string
+		fail bool
+	}{
+		{`s.logger.Infof("port %d", port)`, false},
+		{`s.logger.Infof("started", zap.Int("port", port))`, false},
+		{`s.logger.Infof("port %d", port, zap.String("k", v))`, false},
+		{`s.logger.Infof("100%% done")`, false},
+		{`s.logger.Infof("started", port)`, true},
+		{`s.logger.Infof("port %d %s", port)`, true},
+		{`s.logger.Infof("port %d", zap.String("k", v), port)`, true},
+		{`fmt.Errorf("failed: %w", err, other)`, false},
+	}
+
+	for _, tt := range tests {
+		expr, err := parser.ParseExpr(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		problem := checkFormattedCall(expr.(*ast.CallExpr))
+		if (problem != "") != tt.fail {
+			t.Errorf("%s: problem = %q, want fail = %v", tt.src, problem, tt.fail)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// RotationOptions - параметры ротации файла логов. Нулевые значения отключают соответствующее правило.
+type RotationOptions struct {
+	MaxSize    int64
+	Interval   time.Duration
+	MaxBackups int
+	MaxAge     time.Duration
+	Compress   bool
+}
+
+// RotatingFile - zapcore.WriteSyncer, который ротирует файл по размеру и времени
+// и чистит старые копии в фоне
+type RotatingFile struct {
+	path string
+	opts RotationOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	closed      bool
+	cleanupCh   chan struct{}
+	cleanupDone chan struct{}
+	now         func() time.Time
+}
+
+func NewRotatingFile(path string, opts RotationOptions) (*RotatingFile, error) {
+	return newRotatingFile(path, opts, time.Now)
+}
+
+func newRotatingFile(path string, opts RotationOptions, now func() time.Time) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	r := &RotatingFile{
+		path:        path,
+		opts:        opts,
+		cleanupCh:   make(chan struct{}, 1),
+		cleanupDone: make(chan struct{}),
+		now:         now,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	go r.cleanupLoop()
+	r.scheduleCleanup()
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = r.now()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) shouldRotate(incoming int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+incoming > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.Interval > 0 && r.now().Sub(r.openedAt) >= r.opts.Interval
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if err := os.Rename(r.path, r.backupName(r.now())); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.scheduleCleanup()
+	return nil
+}
+
+// Rotate принудительно начинает новый файл
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file == nil {
+		return r.open()
+	}
+	return r.rotate()
+}
+
+// Reopen закрывает и заново открывает файл по тому же пути - для внешнего logrotate (SIGHUP)
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// Close закрывает файл и останавливает фоновую очистку, дожидаясь ее завершения
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.cleanupCh)
+
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.mu.Unlock()
+
+	<-r.cleanupDone
+	return err
+}
+
+// backupName возвращает свободное имя копии. Время пишется в UTC, как его читает backups.
+// Если копия за эту миллисекунду уже есть, к имени добавляется номер: service-<время>-1.log.
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	stamp := t.UTC().Format(backupTimeFormat)
+
+	name := filepath.Join(dir, prefix+stamp+ext)
+	for seq := 1; fileExists(name) || fileExists(name+".gz"); seq++ {
+		name = filepath.Join(dir, fmt.Sprintf("%s%s-%d%s", prefix, stamp, seq, ext))
+	}
+	return name
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func (r *RotatingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(r.path)
+	name := filepath.Base(r.path)
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func (r *RotatingFile) scheduleCleanup() {
+	select {
+	case r.cleanupCh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *RotatingFile) cleanupLoop() {
+	defer close(r.cleanupDone)
+	for range r.cleanupCh {
+		_ = r.cleanup()
+	}
+}
+
+type logBackup struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+func (r *RotatingFile) backups() ([]logBackup, error) {
+	dir, prefix, ext := r.nameParts()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []logBackup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext), prefix)
+		stamp, suffix, hasSeq := strings.Cut(stamp, "-")
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		seq := 0
+		if hasSeq {
+			if seq, err = strconv.Atoi(suffix); err != nil {
+				continue
+			}
+		}
+		result = append(result, logBackup{path: filepath.Join(dir, name), time: t, seq: seq})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].time.Equal(result[j].time) {
+			return result[i].time.After(result[j].time)
+		}
+		return result[i].seq > result[j].seq
+	})
+	return result, nil
+}
+
+// cleanup удаляет копии сверх MaxBackups и старше MaxAge, затем сжимает оставшиеся
+func (r *RotatingFile) cleanup() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	cutoff := r.now().Add(-r.opts.MaxAge)
+	var keep []logBackup
+	for i, b := range backups {
+		expired := r.opts.MaxAge > 0 && b.time.Before(cutoff)
+		overflow := r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups
+		if expired || overflow {
+			os.Remove(b.path)
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	if !r.opts.Compress {
+		return nil
+	}
+	for _, b := range keep {
+		if !strings.HasSuffix(b.path, ".gz") {
+			if err := gzipFile(b.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
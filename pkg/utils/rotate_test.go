@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock - управляемое время для проверки ротации по интервалу и возрасту
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestFile(t *testing.T, opts RotationOptions) (*RotatingFile, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	r, err := newRotatingFile(filepath.Join(t.TempDir(), "service.log"), opts, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, clock
+}
+
+func write(t *testing.T, r *RotatingFile, s string) {
+	t.Helper()
+	if _, err := r.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// logFiles возвращает имена файлов в каталоге лога, кроме текущего
+func logFiles(t *testing.T, r *RotatingFile) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != filepath.Base(r.path) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotateBySize(t *testing.T) {
+	r, clock := newTestFile(t, RotationOptions{MaxSize: 10})
+
+	write(t, r, "first\n")
+	write(t, r, "123\n") // ровно 10 байт - ротации еще нет
+	clock.advance(time.Second)
+	write(t, r, "second\n")
+
+	if got := readFile(t, r.path); got != "second\n" {
+		t.Errorf("current file = %q", got)
+	}
+	backups := logFiles(t, r)
+	if len(backups) != 1 || backups[0] != "service-20260501T120001.000.log" {
+		t.Fatalf("backups = %v", backups)
+	}
+	if got := readFile(t, filepath.Join(filepath.Dir(r.path), backups[0])); got != "first\n123\n" {
+		t.Errorf("backup = %q", got)
+	}
+}
+
+func TestRotateByInterval(t *testing.T) {
+	r, clock := newTestFile(t, RotationOptions{Interval: time.Hour})
+
+	write(t, r, "first\n")
+	clock.advance(59 * time.Minute)
+	write(t, r, "still first\n")
+	if backups := logFiles(t, r); len(backups) != 0 {
+		t.Fatalf("rotated before interval: %v", backups)
+	}
+
+	clock.advance(time.Minute)
+	write(t, r, "second\n")
+	if got := readFile(t, r.path); got != "second\n" {
+		t.Errorf("current file = %q", got)
+	}
+	if backups := logFiles(t, r); len(backups) != 1 {
+		t.Errorf("backups = %v", backups)
+	}
+}
+
+func TestCleanupRetention(t *testing.T) {
+	// cleanup вызывается напрямую, без фоновой горутины
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := &RotatingFile{
+		path: filepath.Join(t.TempDir(), "service.log"),
+		opts: RotationOptions{MaxBackups: 3, MaxAge: 24 * time.Hour},
+		now:  func() time.Time { return now },
+	}
+
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour, 48 * time.Hour} {
+		if err := os.WriteFile(r.backupName(now.Add(-age)), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// чужие файлы в каталоге не трогаются
+	other := filepath.Join(filepath.Dir(r.path), "service-notes.txt")
+	if err := os.WriteFile(other, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"service-20260501T090000.000.log",
+		"service-20260501T100000.000.log",
+		"service-20260501T110000.000.log",
+		"service-notes.txt",
+	}
+	if got := logFiles(t, r); len(got) != len(want) || got[0] != want[0] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	// без MaxBackups остается все, что моложе MaxAge
+	r.opts = RotationOptions{MaxAge: 150 * time.Minute}
+	if err := r.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logFiles(t, r); len(got) != 3 {
+		t.Errorf("files after age cleanup = %v", got)
+	}
+}
+
+func TestCleanupCompress(t *testing.T) {
+	r, _ := newTestFile(t, RotationOptions{Compress: true})
+
+	write(t, r, "rotated line\n")
+	if err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// Close дожидается фоновой очистки, которую запустила ротация
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := logFiles(t, r)
+	if len(backups) != 1 || filepath.Ext(backups[0]) != ".gz" {
+		t.Fatalf("backups = %v", backups)
+	}
+	f, err := os.Open(filepath.Join(filepath.Dir(r.path), backups[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil || string(data) != "rotated line\n" {
+		t.Errorf("decompressed = %q, %v", data, err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	r, _ := newTestFile(t, RotationOptions{})
+
+	write(t, r, "before logrotate\n")
+	moved := r.path + ".1"
+	if err := os.Rename(r.path, moved); err != nil {
+		t.Fatal(err)
+	}
+	// до Reopen запись идет в переименованный файл
+	write(t, r, "still old\n")
+	if err := r.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, r, "after logrotate\n")
+
+	if got := readFile(t, moved); got != "before logrotate\nstill old\n" {
+		t.Errorf("moved file = %q", got)
+	}
+	if got := readFile(t, r.path); got != "after logrotate\n" {
+		t.Errorf("new file = %q", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	r, _ := newTestFile(t, RotationOptions{MaxBackups: 1})
+	write(t, r, "line\n")
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-r.cleanupDone:
+	default:
+		t.Fatal("cleanup goroutine is still running after Close")
+	}
+
+	if _, err := r.Write([]byte("late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write after Close = %v", err)
+	}
+	if err := r.Rotate(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Rotate after Close = %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+func TestRotateSameMillisecond(t *testing.T) {
+	r, _ := newTestFile(t, RotationOptions{})
+
+	for _, line := range []string{"first\n", "second\n"} {
+		write(t, r, line)
+		if err := r.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := r.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || filepath.Base(backups[0].path) != "service-20260501T120000.000-1.log" {
+		t.Fatalf("backups = %+v", backups)
+	}
+	// первая копия не перезаписана второй ротацией
+	if got := readFile(t, backups[1].path); got != "first\n" {
+		t.Errorf("first backup = %q", got)
+	}
+}
+
+func TestBackupNameUTC(t *testing.T) {
+	// время часов в другом поясе, возраст копий считается без сдвига
+	zone := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2026, 5, 1, 15, 0, 0, 0, zone)
+	r := &RotatingFile{
+		path: filepath.Join(t.TempDir(), "service.log"),
+		opts: RotationOptions{MaxAge: 2 * time.Hour},
+		now:  func() time.Time { return now },
+	}
+
+	if got := filepath.Base(r.backupName(now)); got != "service-20260501T120000.000.log" {
+		t.Errorf("backupName = %s", got)
+	}
+	if err := os.WriteFile(r.backupName(now.Add(-time.Hour)), []byte("recent"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logFiles(t, r); len(got) != 1 {
+		t.Errorf("recent backup was removed: %v", got)
+	}
+}